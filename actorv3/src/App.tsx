@@ -1,6 +1,19 @@
+import { useEffect, useState } from 'react'
 import JobManager from './views/JobManager.tsx'
+import Login from './views/Login.tsx'
+import { getSession, setUnauthorizedHandler } from './request/remote'
 
 function App() {
+  // null 表示还在确认登录状态
+  const [loggedIn, setLoggedIn] = useState<boolean | null>(null)
+
+  useEffect(() => {
+    setUnauthorizedHandler(() => setLoggedIn(false))
+    getSession().then(() => setLoggedIn(true), () => setLoggedIn(false))
+  }, [])
+
+  if (loggedIn === null) return null
+  if (!loggedIn) return <Login onLogin={() => setLoggedIn(true)} />
   return (
     <JobManager />
   )
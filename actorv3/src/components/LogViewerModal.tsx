@@ -119,7 +119,21 @@ export default function LogViewerModal({ show, job, logInfo, onClose }: LogViewe
       evt.addEventListener('ping', () => {
          resetWatchdog()
       })
-      
+
+      // 输出方式不写入文件时服务端发送 nolog 后关闭，不再重连
+      evt.addEventListener('nolog', () => {
+        clearTimeout(watchdogTimerRef.current)
+        evt.close()
+        evtRef.current = null
+        setConnectionState('No log file')
+      })
+
+      // 日志文件被截断（copy-truncate），之后的内容从文件开头发送
+      evt.addEventListener('truncated', () => {
+        resetWatchdog()
+        termRef.current?.write('\r\n--- log truncated ---\r\n')
+      })
+
       evt.onmessage = (e) => {
         resetWatchdog()
         if (termRef.current) {
@@ -112,6 +112,20 @@ export default function TaskEditModal({ show, modelValue, onUpdateModelValue, on
               placeholder="留空使用默认路径"
             />
           </div>
+          <div className="flex flex-col gap-1">
+            <label className="text-sm font-medium text-gray-700">Output</label>
+            <select
+              className="w-full px-3 py-1.5 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-blue-500 disabled:bg-gray-100 disabled:text-gray-500"
+              value={localModel.options.outputType || 2}
+              onChange={(e) => handleChange('options.outputType', Number(e.target.value))}
+              disabled={localModel.readonly}
+            >
+              <option value={2}>文件</option>
+              <option value={1}>标准输出</option>
+              <option value={3}>文件 + 标准输出</option>
+              <option value={4}>丢弃</option>
+            </select>
+          </div>
           <div className="flex flex-col gap-1">
             <label className="text-sm font-medium text-gray-700">MaxFailures</label>
             <input
@@ -122,6 +136,15 @@ export default function TaskEditModal({ show, modelValue, onUpdateModelValue, on
               disabled={localModel.readonly}
             />
           </div>
+          <label className="flex items-center gap-2 cursor-pointer text-sm font-medium text-gray-700">
+            <input
+              type="checkbox"
+              checked={!!localModel.options.pty}
+              onChange={(e) => handleChange('options.pty', e.target.checked)}
+              disabled={localModel.readonly}
+            />
+            <span>PTY（可在终端中交互）</span>
+          </label>
         </div>
         <div className="mt-6 flex justify-end gap-2">
           <button
@@ -0,0 +1,129 @@
+import { useEffect, useRef, useState } from 'react'
+import { Terminal } from '@xterm/xterm'
+import { FitAddon } from '@xterm/addon-fit'
+import '@xterm/xterm/css/xterm.css'
+import { jobTerminalUrl } from '../request/remote'
+
+interface TerminalModalProps {
+  job: any
+  onClose: () => void
+}
+
+// TerminalModal 连接到 PTY 任务的终端，可写模式下输入转发给任务，同一时间只有一个可写连接
+export default function TerminalModal({ job, onClose }: TerminalModalProps) {
+  const [write, setWrite] = useState(false)
+  const [connectionState, setConnectionState] = useState('Disconnected')
+  const containerRef = useRef<HTMLDivElement>(null)
+
+  useEffect(() => {
+    if (!job || !containerRef.current) return
+
+    const term = new Terminal({
+      cursorBlink: true,
+      disableStdin: !write,
+      theme: {
+        background: '#1e1e1e',
+      },
+    })
+    const fitAddon = new FitAddon()
+    term.loadAddon(fitAddon)
+    term.open(containerRef.current)
+    fitAddon.fit()
+
+    setConnectionState('Connecting...')
+    const ws = new WebSocket(jobTerminalUrl(job.uuid, write))
+    ws.binaryType = 'arraybuffer'
+
+    const sendResize = () => {
+      if (write && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'resize', cols: term.cols, rows: term.rows }))
+      }
+    }
+
+    ws.onmessage = (e) => {
+      if (e.data instanceof ArrayBuffer) {
+        term.write(new Uint8Array(e.data))
+        return
+      }
+      const msg = JSON.parse(e.data)
+      switch (msg.type) {
+        case 'attached':
+          setConnectionState(msg.mode === 'write' ? 'Connected' : 'Read only')
+          // 只读连接按任务终端的大小显示
+          if (msg.mode === 'write') sendResize()
+          else term.resize(msg.cols, msg.rows)
+          break
+        case 'exit':
+          setConnectionState('Exited')
+          term.write('\r\n--- process exited ---\r\n')
+          break
+        case 'error':
+          term.write(`\r\n--- ${msg.error} ---\r\n`)
+          break
+      }
+    }
+    ws.onclose = () => {
+      setConnectionState((s) => (s === 'Exited' ? s : 'Disconnected'))
+    }
+    // 连接被拒绝时服务端返回 JSON 而不是升级为 WebSocket
+    ws.onerror = () => {
+      setConnectionState('Failed')
+      term.write('--- 无法连接到终端，任务可能未运行或已有其他写入者 ---\r\n')
+    }
+
+    const input = term.onData((data) => {
+      if (ws.readyState === WebSocket.OPEN) {
+        ws.send(new TextEncoder().encode(data))
+      }
+    })
+    const handleResize = () => {
+      fitAddon.fit()
+      sendResize()
+    }
+    window.addEventListener('resize', handleResize)
+
+    return () => {
+      window.removeEventListener('resize', handleResize)
+      input.dispose()
+      ws.close()
+      term.dispose()
+    }
+  }, [job, write])
+
+  if (!job) return null
+
+  return (
+    <div className="fixed inset-0 z-50 flex items-center justify-center bg-black/50">
+      <div className="bg-white rounded-lg p-6 shadow-xl w-11/12 max-w-6xl h-[90vh] flex flex-col relative">
+        <button
+          className="absolute right-4 top-4 text-gray-500 hover:text-gray-700 hover:bg-gray-100 p-2 rounded-full transition-colors"
+          onClick={onClose}
+          title="关闭"
+          aria-label="关闭"
+        >
+          <i className="fa-solid fa-xmark text-lg"></i>
+        </button>
+        <h3 className="font-bold text-lg mb-2">终端: {job.jobName}</h3>
+        <div className="flex items-center gap-4 mb-4">
+          <div className={`px-2.5 py-0.5 rounded-full text-xs font-medium border ${
+            connectionState === 'Connected' ? 'bg-green-50 text-green-700 border-green-200' :
+            connectionState === 'Read only' ? 'bg-blue-50 text-blue-700 border-blue-200' :
+            'bg-gray-50 text-gray-700 border-gray-200'
+          }`}>
+            {connectionState}
+          </div>
+          <label className="flex items-center gap-2 cursor-pointer text-sm font-medium text-gray-700">
+            <span>可写入</span>
+            <input
+              type="checkbox"
+              className="w-9 h-5 bg-gray-200 rounded-full appearance-none relative checked:bg-blue-600 cursor-pointer after:content-[''] after:absolute after:top-[2px] after:left-[2px] after:bg-white after:rounded-full after:h-4 after:w-4 after:transition-all checked:after:translate-x-4"
+              checked={write}
+              onChange={(e) => setWrite(e.target.checked)}
+            />
+          </label>
+        </div>
+        <div ref={containerRef} className="flex-1 bg-[#1e1e1e] rounded overflow-hidden"></div>
+      </div>
+    </div>
+  )
+}
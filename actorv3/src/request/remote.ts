@@ -2,8 +2,60 @@ import axios from 'axios'
 
 const instanceAxios = axios.create({baseURL: '/api/'})
 
-export function getJobList() {
-    return instanceAxios.get('job-list')
+// 登录会话的 CSRF 令牌，修改状态的请求需要携带
+let csrfToken = ''
+
+instanceAxios.interceptors.request.use((config) => {
+    if (csrfToken) {
+        config.headers['X-CSRF-Token'] = csrfToken
+    }
+    return config
+})
+
+// 未登录或会话过期时通知页面显示登录
+let onUnauthorized: () => void = () => {}
+
+export function setUnauthorizedHandler(handler: () => void) {
+    onUnauthorized = handler
+}
+
+instanceAxios.interceptors.response.use(undefined, (error) => {
+    if (error.response?.status === 401) {
+        csrfToken = ''
+        onUnauthorized()
+    }
+    return Promise.reject(error)
+})
+
+export async function login(token: string) {
+    const resp = await instanceAxios.post('login', {token})
+    csrfToken = resp.data?.csrfToken || ''
+    return resp
+}
+
+export async function logout() {
+    const resp = await instanceAxios.post('logout')
+    csrfToken = ''
+    return resp
+}
+
+// getSession 获取当前身份，页面刷新后从这里取回 CSRF 令牌
+export async function getSession() {
+    const resp = await instanceAxios.get('session')
+    csrfToken = resp.data?.csrfToken || ''
+    return resp
+}
+
+export function getJobList(params?: { group?: string, tag?: string[] }) {
+    return instanceAxios.get('job-list', {params, paramsSerializer: {indexes: null}})
+}
+
+export function getJobLabels() {
+    return instanceAxios.get('job-labels')
+}
+
+export function bulkAction(action: string, selector: any) {
+    return instanceAxios.post('bulk-action', {action, selector})
 }
 
 export function runInfo() {
@@ -14,6 +66,46 @@ export function saveTask(data: any) {
     return instanceAxios.post('save-task', data)
 }
 
+export function validateTask(data: any) {
+    return instanceAxios.post('validate-task', data)
+}
+
+export function previewJob(jobId: any) {
+    return instanceAxios.get('job-preview', {params: {jobId}})
+}
+
+export function previewTask(data: any) {
+    return instanceAxios.post('job-preview', data)
+}
+
+export function getJobRuns(jobId: any) {
+    return instanceAxios.get('job-runs', {params: {jobId}})
+}
+
+export function getJobRunLog(jobId: any, runId: string) {
+    return instanceAxios.get('job-run-log', {params: {jobId, runId}, responseType: 'text'})
+}
+
+export function getJobLogFiles(jobId: any) {
+    return instanceAxios.get('job-log-files', {params: {jobId}})
+}
+
+// readJobLog 分页读取日志文件，offset 为负数时从末尾倒数
+export function readJobLog(jobId: any, file: string, offset: number, length?: number) {
+    return instanceAxios.get('job-log-read', {params: {jobId, file, offset, length}})
+}
+
+// jobLogDownloadUrl 日志文件的下载地址，decompress 为 true 时解压 .gz 文件
+export function jobLogDownloadUrl(jobId: string, file: string, decompress?: boolean) {
+    const q = new URLSearchParams({jobId, file})
+    if (decompress) q.set('decompress', 'true')
+    return '/api/job-log-download?' + q.toString()
+}
+
+export function rotateLog(jobId: any) {
+    return instanceAxios.post('rotate-log', {jobId})
+}
+
 export function removeTask(jobId: any) {
     return instanceAxios.post('remove-task', {uuid: jobId})
 }
@@ -38,6 +130,50 @@ export function restartJob(jobId: any) {
     return instanceAxios.post('restart-job-resident-task', {jobId})
 }
 
+export function testNotify(channel: string) {
+    return instanceAxios.post('notify-test', {channel})
+}
+
 export function getHomePath() {
     return instanceAxios.get('home-path')
 }
+
+// eventsUrl 生命周期事件的 SSE 地址，EventSource 断线重连时会自动带上 Last-Event-ID
+export function eventsUrl(params?: { jobId?: string, type?: string[] }) {
+    const q = new URLSearchParams()
+    if (params?.jobId) q.set('jobId', params.jobId)
+    params?.type?.forEach(t => q.append('type', t))
+    const s = q.toString()
+    return '/api/events' + (s ? '?' + s : '')
+}
+
+// logsStreamUrl 合并多个任务实时日志的 SSE 地址，message 事件的数据为 {jobId, jobName, data}
+export function logsStreamUrl(params: { jobId?: string[], group?: string, tag?: string[] }) {
+    const q = new URLSearchParams()
+    params.jobId?.forEach(id => q.append('jobId', id))
+    if (params.group) q.set('group', params.group)
+    params.tag?.forEach(t => q.append('tag', t))
+    return '/api/logs/stream?' + q.toString()
+}
+
+// jobTerminalUrl PTY 任务终端的 WebSocket 地址，write 为 true 时请求写入权限（同一时间只有一个写入者）
+export function jobTerminalUrl(jobId: string, write: boolean) {
+    const proto = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
+    const q = new URLSearchParams({ jobId, mode: write ? 'write' : 'read' })
+    return `${proto}//${window.location.host}/api/job-terminal?${q.toString()}`
+}
+
+// logSearchUrl 日志搜索的 SSE 地址，结果以 match 事件推送，结束时推送 done 事件
+export function logSearchUrl(params: { jobId: string, q: string, regex?: boolean, since?: string, until?: string, limit?: number }) {
+    const q = new URLSearchParams({jobId: params.jobId, q: params.q})
+    if (params.regex) q.set('regex', 'true')
+    if (params.since) q.set('since', params.since)
+    if (params.until) q.set('until', params.until)
+    if (params.limit) q.set('limit', String(params.limit))
+    return '/api/job-log-search?' + q.toString()
+}
+
+// getAudit 查询审计日志，结果从新到旧
+export function getAudit(params: { jobId?: string, actor?: string, action?: string[], since?: string, until?: string, limit?: number }) {
+    return instanceAxios.get('audit', {params, paramsSerializer: {indexes: null}})
+}
@@ -13,6 +13,7 @@ import {
 } from '../request/remote'
 
 import LogViewerModal from '../components/LogViewerModal'
+import TerminalModal from '../components/TerminalModal'
 import TaskEditModal from '../components/TaskEditModal'
 
 export default function JobManager() {
@@ -21,6 +22,7 @@ export default function JobManager() {
   const [scheduled, setScheduled] = useState<any[]>([])
   const [showModal, setShowModal] = useState(false)
   const [showLogModal, setShowLogModal] = useState(false)
+  const [terminalJob, setTerminalJob] = useState<any>(null)
   const [showDeleteModal, setShowDeleteModal] = useState(false)
   const [pendingDeleteUuid, setPendingDeleteUuid] = useState('')
   const [logInfo, setLogInfo] = useState<any>({ realLogPath: '', size: 0, modTime: '', uuid: '' })
@@ -34,7 +36,7 @@ export default function JobManager() {
   const timerRef = useRef<any>(0)
 
   // 监听模态框状态，防止背景滚动穿透
-  useScrollLock(showModal || showLogModal || showDeleteModal)
+  useScrollLock(showModal || showLogModal || showDeleteModal || !!terminalJob)
 
   function showToast(msg: string) {
     setToast({ show: true, message: msg })
@@ -327,6 +329,15 @@ export default function JobManager() {
                           >
                             <i className="fa-regular fa-file-lines text-xs"></i>
                           </button>
+                          {row.options?.pty && (
+                            <button 
+                              className="w-7 h-7 flex items-center justify-center rounded transition-colors text-gray-500 hover:text-gray-900 hover:bg-gray-100"
+                              onClick={() => setTerminalJob(row)}
+                              title="Terminal"
+                            >
+                              <i className="fa-solid fa-terminal text-xs"></i>
+                            </button>
+                          )}
                         </div>
                       </td>
                     </tr>
@@ -415,6 +426,15 @@ export default function JobManager() {
                           >
                             <i className="fa-regular fa-file-lines text-xs"></i>
                           </button>
+                          {row.options?.pty && (
+                            <button 
+                              className="w-7 h-7 flex items-center justify-center rounded transition-colors text-gray-500 hover:text-gray-900 hover:bg-gray-100"
+                              onClick={() => setTerminalJob(row)}
+                              title="Terminal"
+                            >
+                              <i className="fa-solid fa-terminal text-xs"></i>
+                            </button>
+                          )}
                         </div>
                       </td>
                     </tr>
@@ -447,6 +467,11 @@ export default function JobManager() {
         onClose={() => setShowLogModal(false)}
       />
 
+      <TerminalModal
+        job={terminalJob}
+        onClose={() => setTerminalJob(null)}
+      />
+
       {showDeleteModal && (
         <div className="fixed inset-0 z-50 flex items-center justify-center bg-black/50">
           <div className="bg-white rounded-lg p-6 shadow-xl w-11/12 max-w-md">
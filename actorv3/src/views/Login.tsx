@@ -0,0 +1,49 @@
+import { useState } from 'react'
+import { login } from '../request/remote'
+
+interface LoginProps {
+  onLogin: () => void
+}
+
+export default function Login({ onLogin }: LoginProps) {
+  const [token, setToken] = useState('')
+  const [error, setError] = useState('')
+
+  async function submit(e: React.FormEvent) {
+    e.preventDefault()
+    setError('')
+    try {
+      await login(token)
+      onLogin()
+    } catch (err: any) {
+      setError(err.response?.data?.message || '登录失败')
+    }
+  }
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50">
+      <form onSubmit={submit} className="bg-white rounded-lg p-6 shadow-xl w-11/12 max-w-md flex flex-col gap-4">
+        <h3 className="font-bold text-lg">登录 Rooster</h3>
+        <p className="text-sm text-gray-600">
+          管理员令牌保存在配置目录的 <code>auth.json</code> 中，也可以运行 <code>rooster token admin</code> 查看。
+        </p>
+        <input
+          type="password"
+          className="w-full px-3 py-1.5 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-blue-500"
+          placeholder="令牌"
+          value={token}
+          onChange={(e) => setToken(e.target.value)}
+          autoFocus
+        />
+        {error && <div className="text-sm text-red-600">{error}</div>}
+        <button
+          type="submit"
+          className="px-4 py-2 bg-indigo-600 text-white font-medium rounded-md hover:bg-indigo-700 transition-colors disabled:opacity-50"
+          disabled={!token}
+        >
+          登录
+        </button>
+      </form>
+    </div>
+  )
+}
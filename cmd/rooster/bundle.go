@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// stringList 可重复传入的字符串参数
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("o", "", "输出文件，默认输出到标准输出")
+	var jobs stringList
+	fs.Var(&jobs, "job", "要导出的任务 UUID 或任务名，可重复，默认导出全部")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	m, err := jobmanager.OpenUserConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	bundle, err := m.ExportBundle(jobs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data, _ := json.MarshalIndent(bundle, "", "  ")
+	data = append(data, '\n')
+	if *out == "" {
+		_, _ = os.Stdout.Write(data)
+		return 0
+	}
+	if err = os.WriteFile(*out, data, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "已导出 %d 个任务到 %v\n", len(bundle.Jobs), *out)
+	return 0
+}
+
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("f", "", "任务包文件")
+	strategy := fs.String("strategy", jobmanager.ImportSkip, "冲突处理: skip | rename | overwrite")
+	matchBy := fs.String("match", jobmanager.ImportMatchUUID, "冲突判断依据: uuid | name")
+	dryRun := fs.Bool("dry-run", false, "只预览导入结果，不修改配置")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "缺少 -f 任务包文件")
+		return 2
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var bundle jobmanager.JobBundle
+	if err = json.Unmarshal(data, &bundle); err != nil {
+		fmt.Fprintln(os.Stderr, "任务包格式错误:", err)
+		return 1
+	}
+	m, err := jobmanager.OpenUserConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	report, err := m.ImportBundle(bundle, jobmanager.ImportOptions{Strategy: *strategy, MatchBy: *matchBy, DryRun: *dryRun})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tJOB\tUUID\tMESSAGE")
+	failed := 0
+	for _, item := range report.Items {
+		if item.Action == "error" {
+			failed++
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", item.Action, item.JobName, item.UUID, item.Message)
+	}
+	_ = tw.Flush()
+	if *dryRun {
+		fmt.Println("dry-run: 配置未修改")
+	} else {
+		fmt.Println("导入完成，如果 rooster 正在运行请重启使其生效，或改用 dashboard / /api/import 导入")
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// 子命令表，不带子命令时以守护模式运行
+var commands = map[string]func(args []string) int{
+	"convert": runConvert,
+	"ctl":     runCtl,
+	"export":  runExport,
+	"import":  runImport,
+	"token":   runToken,
+}
+
+func runCommand(name string, args []string) int {
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return 0
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知命令: %v\n\n", name)
+		printUsage()
+		return 2
+	}
+	return cmd(args)
+}
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `用法:
+  rooster                 启动任务调度与 dashboard
+  rooster convert         在 json/yaml/toml 配置格式之间转换
+  rooster ctl             操作正在运行的 rooster 中的任务
+  rooster export          导出任务包
+  rooster import          导入任务包
+  rooster token           管理 dashboard 与 API 的令牌
+`)
+}
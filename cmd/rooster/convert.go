@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	to := fs.String("to", "", "目标格式: json | yaml | toml")
+	in := fs.String("in", "", "输入配置文件，默认为当前使用的配置")
+	out := fs.String("out", "", "输出文件，默认与输入同目录的 jobConfig.<格式>")
+	keep := fs.Bool("keep", false, "转换当前配置时保留原文件（默认将原文件重命名为 .bak，使新格式生效）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	format, err := jobmanager.ParseConfigFormat(*to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	active := false
+	inPath := *in
+	if inPath == "" {
+		if inPath, err = jobmanager.ConfigPath(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		active = true
+	}
+	outPath := *out
+	if outPath == "" {
+		outPath = filepath.Join(filepath.Dir(inPath), "jobConfig."+string(format))
+	}
+	if filepath.Clean(outPath) == filepath.Clean(inPath) {
+		fmt.Fprintln(os.Stderr, "输入与输出为同一个文件")
+		return 2
+	}
+	if fileFormat, err := jobmanager.ParseConfigFormat(filepath.Ext(outPath)); err != nil || fileFormat != format {
+		fmt.Fprintf(os.Stderr, "输出文件扩展名与格式 %v 不一致: %v\n", format, outPath)
+		return 2
+	}
+
+	if err = jobmanager.ConvertConfigFile(inPath, outPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("已转换 %v -> %v\n", inPath, outPath)
+
+	// 配置目录中存在多个配置文件时只有第一个生效，转换当前配置时将原文件改名
+	if active && *out == "" && !*keep {
+		backup := inPath + ".bak"
+		if err = os.Rename(inPath, backup); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("原配置已重命名为 %v，重启 rooster 后生效\n", strings.TrimPrefix(backup, filepath.Dir(backup)+string(filepath.Separator)))
+	}
+	return 0
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+const ctlUsage = `用法:
+  rooster ctl [参数] <命令>
+
+命令:
+  list [-group G] [-tag T]      列出任务
+  status JOB...                  查看任务状态
+  start|stop JOB...              启动、停止常驻任务
+  enable|disable JOB...          开启、关闭任务
+  run JOB...                     立即运行一次定时任务
+  logs [-f] [-n N] JOB           查看日志最后 N 行（默认 50），-f 持续输出
+  edit [-f FILE] JOB             用 $EDITOR 编辑任务配置，或以文件中的配置覆盖
+  add -f FILE | -name NAME ...   添加任务，运行 rooster ctl add -h 查看参数
+  rm [-force] JOB...             删除任务，-force 时先关闭开启中的任务
+
+JOB 为任务名或 UUID。参数可以放在命令之前或之后:
+  -url URL       rooster 的地址，默认读取配置目录的 dashboard.json
+  -port PORT     连接本机指定端口的 rooster
+  -token TOKEN   API 令牌，默认读取环境变量 ROOSTER_TOKEN，再使用本机的管理员令牌
+  -o FORMAT      输出格式 table（默认）或 json
+  -insecure      不校验 HTTPS 证书
+
+退出码: 0 成功，1 操作失败，2 参数错误，3 无法连接 rooster，4 认证失败，5 任务不存在，6 任务状态冲突
+`
+
+// runCtl 通过 API 操作正在运行的 rooster
+func runCtl(args []string) int {
+	opts := ctlOptions{output: "table"}
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, ctlUsage) }
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	name, rest := fs.Arg(0), fs.Args()[1:]
+	sub := flag.NewFlagSet("ctl "+name, flag.ContinueOnError)
+	opts.register(sub)
+
+	var run func(c *ctlClient, args []string) int
+	switch name {
+	case "list":
+		group := sub.String("group", "", "只列出该分组的任务")
+		var tags stringList
+		sub.Var(&tags, "tag", "只列出包含该标签的任务，可重复")
+		run = func(c *ctlClient, _ []string) int { return ctlList(c, opts, *group, tags) }
+	case "status":
+		run = func(c *ctlClient, jobs []string) int { return ctlStatus(c, opts, jobs) }
+	case "start", "stop", "enable", "disable", "run":
+		run = func(c *ctlClient, jobs []string) int { return ctlAction(c, opts, name, jobs) }
+	case "logs":
+		followFlag := sub.Bool("f", false, "持续输出新的日志")
+		lines := sub.Int("n", 50, "先输出的日志行数")
+		run = func(c *ctlClient, jobs []string) int {
+			if len(jobs) != 1 {
+				fmt.Fprintln(os.Stderr, "logs 需要且只能指定一个任务")
+				return exitUsage
+			}
+			return ctlLogs(c, jobs[0], *lines, *followFlag)
+		}
+	case "edit":
+		file := sub.String("f", "", "任务配置文件（JSON），- 表示标准输入，不指定时打开编辑器")
+		run = func(c *ctlClient, jobs []string) int {
+			if len(jobs) != 1 {
+				fmt.Fprintln(os.Stderr, "edit 需要且只能指定一个任务")
+				return exitUsage
+			}
+			return ctlEdit(c, opts, jobs[0], *file)
+		}
+	case "add":
+		var spec ctlAddFlags
+		spec.register(sub)
+		run = func(c *ctlClient, _ []string) int { return ctlAdd(c, opts, spec) }
+	case "rm":
+		force := sub.Bool("force", false, "先关闭开启中的任务再删除")
+		run = func(c *ctlClient, jobs []string) int { return ctlRemove(c, opts, jobs, *force) }
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %v\n\n", name)
+		fs.Usage()
+		return exitUsage
+	}
+	if err := sub.Parse(rest); err != nil {
+		return exitUsage
+	}
+	switch name {
+	case "list", "add":
+	default:
+		if sub.NArg() == 0 {
+			fmt.Fprintf(os.Stderr, "%v 需要指定任务\n", name)
+			return exitUsage
+		}
+	}
+	if opts.output != "table" && opts.output != "json" {
+		fmt.Fprintf(os.Stderr, "未知的输出格式: %v\n", opts.output)
+		return exitUsage
+	}
+	c, err := dial(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeOf(err)
+	}
+	return run(c, sub.Args())
+}
+
+func jobPath(job string) string {
+	return "/api/v2/jobs/" + url.PathEscape(job)
+}
+
+// fail 输出错误并返回对应的退出码
+func fail(prefix string, err error) int {
+	if prefix != "" {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", prefix, err)
+	} else {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return exitCodeOf(err)
+}
+
+func printJSON(v any) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
+
+func jobTypeName(t int) string {
+	if jobmanager.JobType(t) == jobmanager.JobTypeResident {
+		return "常驻"
+	}
+	return "定时"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func printJobTable(jobs []jobmanager.JobStatusShow) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tENABLED\tSTATUS\tGROUP\tLAST START\tEXIT\tID")
+	for _, j := range jobs {
+		group := j.Group
+		if group == "" {
+			group = "-"
+		}
+		exit := "-"
+		if !j.LastExit.IsZero() {
+			exit = strconv.Itoa(j.LastExitCode)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", j.JobName, jobTypeName(j.Type), yesNo(j.Run), j.Status,
+			group, formatTime(j.LastStart), exit, j.UUID)
+	}
+	_ = tw.Flush()
+}
+
+func ctlList(c *ctlClient, opts ctlOptions, group string, tags []string) int {
+	q := url.Values{}
+	if group != "" {
+		q.Set("group", group)
+	}
+	for _, tag := range tags {
+		q.Add("tag", tag)
+	}
+	path := "/api/v2/jobs"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var resp struct {
+		Jobs []jobmanager.JobStatusShow `json:"jobs"`
+	}
+	if _, err := c.do(http.MethodGet, path, nil, nil, &resp); err != nil {
+		return fail("", err)
+	}
+	if opts.output == "json" {
+		printJSON(resp.Jobs)
+		return exitOK
+	}
+	printJobTable(resp.Jobs)
+	return exitOK
+}
+
+// getJob 获取任务及其 ETag
+func (c *ctlClient) getJob(job string) (jobmanager.JobStatusShow, string, error) {
+	var j jobmanager.JobStatusShow
+	header, err := c.do(http.MethodGet, jobPath(job), nil, nil, &j)
+	if err != nil {
+		return j, "", err
+	}
+	return j, header.Get("ETag"), nil
+}
+
+func ctlStatus(c *ctlClient, opts ctlOptions, jobs []string) int {
+	code := exitOK
+	var found []jobmanager.JobStatusShow
+	for _, name := range jobs {
+		j, _, err := c.getJob(name)
+		if err != nil {
+			code = fail(name, err)
+			continue
+		}
+		found = append(found, j)
+	}
+	if opts.output == "json" {
+		printJSON(found)
+		return code
+	}
+	for i, j := range found {
+		if i > 0 {
+			fmt.Println()
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		rows := [][2]string{
+			{"Name", j.JobName},
+			{"ID", j.UUID},
+			{"Type", jobTypeName(j.Type)},
+			{"Enabled", yesNo(j.Run)},
+			{"Status", j.Status.String()},
+			{"Command", j.BinPath},
+			{"Dir", j.Dir},
+			{"Spec", j.Spec},
+			{"Group", j.Group},
+			{"Tags", strings.Join(j.Tags, ",")},
+			{"Last start", formatTime(j.LastStart)},
+			{"Last exit", formatTime(j.LastExit)},
+			{"Log", j.RealLogPath},
+		}
+		if !j.LastExit.IsZero() {
+			rows = append(rows, [2]string{"Exit code", strconv.Itoa(j.LastExitCode)}, [2]string{"Duration", j.LastDuration.String()})
+		}
+		for _, row := range rows {
+			if row[1] != "" {
+				fmt.Fprintf(tw, "%v:\t%v\n", row[0], row[1])
+			}
+		}
+		_ = tw.Flush()
+	}
+	return code
+}
+
+// ctlAction 对任务执行 v2 API 的 /jobs/{id}:action 操作
+func ctlAction(c *ctlClient, opts ctlOptions, action string, jobs []string) int {
+	code := exitOK
+	var done []jobmanager.JobStatusShow
+	for _, name := range jobs {
+		var j jobmanager.JobStatusShow
+		if _, err := c.do(http.MethodPost, jobPath(name)+":"+action, nil, nil, &j); err != nil {
+			code = fail(name, err)
+			continue
+		}
+		done = append(done, j)
+		if opts.output == "table" {
+			fmt.Printf("%v: %v ok\n", j.JobName, action)
+		}
+	}
+	if opts.output == "json" {
+		printJSON(done)
+	}
+	return code
+}
+
+// ctlLogs 输出日志的最后 lines 行，follow 时随后持续输出新内容，断线后从原位置继续
+func ctlLogs(c *ctlClient, name string, lines int, follow bool) int {
+	j, _, err := c.getJob(name)
+	if err != nil {
+		return fail(name, err)
+	}
+	if !follow {
+		var resp struct {
+			Chunk jobmanager.LogChunk `json:"chunk"`
+		}
+		q := url.Values{"jobId": {j.UUID}, "offset": {"-1048576"}, "length": {"1048576"}}
+		if err := c.legacy("/api/job-log-read?"+q.Encode(), &resp); err != nil {
+			return fail(name, err)
+		}
+		fmt.Print(lastLines(resp.Chunk.Data, lines))
+		return exitOK
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	path := "/api/job-log-stream?" + url.Values{"jobId": {j.UUID}}.Encode()
+	var lastID string
+	code := -1
+	for ctx.Err() == nil && code < 0 {
+		first := lastID == ""
+		err := c.follow(ctx, path, lastID, func(ev sseEvent) bool {
+			switch ev.event {
+			case "message", "":
+				data := ev.data
+				if first {
+					// 连接时发送的是文件末尾的一段，只保留最后几行
+					data, first = lastLines(data, lines), false
+				}
+				fmt.Print(data)
+				if ev.id != "" {
+					lastID = ev.id
+				}
+			case "truncated":
+				fmt.Fprintln(os.Stderr, "日志文件被截断，从头开始输出")
+			case "nolog":
+				fmt.Fprintln(os.Stderr, ev.data)
+				code = exitFailure
+				return false
+			}
+			return true
+		})
+		if err != nil && ctx.Err() == nil {
+			if exitCodeOf(err) == exitAuth {
+				return fail(name, err)
+			}
+			fmt.Fprintln(os.Stderr, err)
+		}
+		// 日志文件尚未创建或 rooster 重启时稍后重连
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+	if code < 0 {
+		code = exitOK
+	}
+	return code
+}
+
+// lastLines 返回 s 的最后 n 行，n 小于 0 时返回全部
+func lastLines(s string, n int) string {
+	if n < 0 {
+		return s
+	}
+	trimmed := strings.TrimSuffix(s, "\n")
+	if n == 0 || trimmed == "" {
+		return ""
+	}
+	idx := len(trimmed)
+	for i := 0; i < n; i++ {
+		idx = strings.LastIndexByte(trimmed[:idx], '\n')
+		if idx < 0 {
+			return s
+		}
+	}
+	return s[idx+1:]
+}
+
+// editableJob 编辑时展示的任务配置，不包含运行状态
+func editableJob(j jobmanager.JobStatusShow) jobmanager.JobSpec {
+	return jobmanager.JobSpec{
+		UUID:    j.UUID,
+		JobName: j.JobName,
+		Link:    j.Link,
+		Type:    jobmanager.JobType(j.Type),
+		Run:     j.Run,
+		BinPath: j.BinPath,
+		Dir:     j.Dir,
+		Spec:    j.Spec,
+		Options: j.Options,
+		Group:   j.Group,
+		Tags:    j.Tags,
+		Env:     j.Env,
+		Notify:  j.Notify,
+	}
+}
+
+// readJobFile 读取 JSON 格式的任务配置，file 为 - 时读取标准输入
+func readJobFile(file string) (json.RawMessage, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("任务配置不是有效的 JSON")
+	}
+	return data, nil
+}
+
+func ctlEdit(c *ctlClient, opts ctlOptions, name, file string) int {
+	j, etag, err := c.getJob(name)
+	if err != nil {
+		return fail(name, err)
+	}
+	var body json.RawMessage
+	if file != "" {
+		if body, err = readJobFile(file); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	} else {
+		if j.Run {
+			fmt.Fprintf(os.Stderr, "%v: 任务处于开启状态不允许修改，请先执行 rooster ctl disable %v\n", name, name)
+			return exitConflict
+		}
+		original, _ := json.MarshalIndent(editableJob(j), "", "  ")
+		edited, err := editInEditor(original)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+			fmt.Fprintln(os.Stderr, "未修改")
+			return exitOK
+		}
+		if !json.Valid(edited) {
+			fmt.Fprintln(os.Stderr, "任务配置不是有效的 JSON，未保存")
+			return exitFailure
+		}
+		body = edited
+	}
+	var updated jobmanager.JobStatusShow
+	// If-Match 保证不会覆盖编辑期间其他人的修改
+	if _, err := c.do(http.MethodPut, jobPath(j.UUID), body, map[string]string{"If-Match": etag}, &updated); err != nil {
+		return fail(name, err)
+	}
+	if opts.output == "json" {
+		printJSON(updated)
+	} else {
+		fmt.Printf("%v: 已保存\n", updated.JobName)
+	}
+	return exitOK
+}
+
+// editInEditor 用 $VISUAL 或 $EDITOR 编辑 content，返回编辑后的内容
+func editInEditor(content []byte) ([]byte, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	f, err := os.CreateTemp("", "rooster-job-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(append(content, '\n')); err != nil {
+		f.Close()
+		return nil, err
+	}
+	f.Close()
+	// EDITOR 可以带参数，如 "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("编辑器 %v 运行失败: %w", editor, err)
+	}
+	return os.ReadFile(f.Name())
+}
+
+// ctlAddFlags add 命令的参数，-f 与其他参数不能同时使用
+type ctlAddFlags struct {
+	file    string
+	name    string
+	jobType string
+	spec    string
+	command string
+	dir     string
+	group   string
+	tags    stringList
+	enable  bool
+}
+
+func (a *ctlAddFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&a.file, "f", "", "任务配置文件（JSON），- 表示标准输入")
+	fs.StringVar(&a.name, "name", "", "任务名")
+	fs.StringVar(&a.jobType, "type", "", "任务类型 resident | scheduled，默认指定 -spec 时为 scheduled")
+	fs.StringVar(&a.spec, "spec", "", "定时任务的 cron 表达式")
+	fs.StringVar(&a.command, "cmd", "", "执行的命令")
+	fs.StringVar(&a.dir, "dir", "", "工作目录")
+	fs.StringVar(&a.group, "group", "", "分组")
+	fs.Var(&a.tags, "tag", "标签，可重复")
+	fs.BoolVar(&a.enable, "enable", false, "创建后开启任务")
+}
+
+func (a ctlAddFlags) job() (any, error) {
+	if a.file != "" {
+		if a.name != "" || a.command != "" || a.spec != "" {
+			return nil, errors.New("-f 不能与 -name、-cmd、-spec 同时使用")
+		}
+		return readJobFile(a.file)
+	}
+	if a.name == "" || a.command == "" {
+		return nil, errors.New("需要 -f 或 -name 与 -cmd")
+	}
+	jobType := jobmanager.JobTypeResident
+	switch a.jobType {
+	case "scheduled":
+		jobType = jobmanager.JobTypeScheduled
+	case "resident":
+	case "":
+		if a.spec != "" {
+			jobType = jobmanager.JobTypeScheduled
+		}
+	default:
+		return nil, fmt.Errorf("未知的任务类型: %v", a.jobType)
+	}
+	return jobmanager.JobStatusShow{
+		JobName: a.name,
+		Type:    int(jobType),
+		Spec:    a.spec,
+		BinPath: a.command,
+		Dir:     a.dir,
+		Group:   a.group,
+		Tags:    a.tags,
+	}, nil
+}
+
+func ctlAdd(c *ctlClient, opts ctlOptions, a ctlAddFlags) int {
+	body, err := a.job()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	var created jobmanager.JobStatusShow
+	if _, err := c.do(http.MethodPost, "/api/v2/jobs", body, nil, &created); err != nil {
+		return fail("", err)
+	}
+	if a.enable {
+		if _, err := c.do(http.MethodPost, jobPath(created.UUID)+":enable", nil, nil, &created); err != nil {
+			return fail(created.JobName+" 已创建但开启失败", err)
+		}
+	}
+	if opts.output == "json" {
+		printJSON(created)
+	} else {
+		fmt.Printf("%v: 已创建 (%v)\n", created.JobName, created.UUID)
+	}
+	return exitOK
+}
+
+func ctlRemove(c *ctlClient, opts ctlOptions, jobs []string, force bool) int {
+	code := exitOK
+	var removed []string
+	for _, name := range jobs {
+		j, etag, err := c.getJob(name)
+		if err != nil {
+			code = fail(name, err)
+			continue
+		}
+		if j.Run && force {
+			if _, err := c.do(http.MethodPost, jobPath(j.UUID)+":disable", nil, nil, &j); err != nil {
+				code = fail(name, err)
+				continue
+			}
+			etag = ""
+		}
+		header := map[string]string{}
+		if etag != "" {
+			header["If-Match"] = etag
+		}
+		if _, err := c.do(http.MethodDelete, jobPath(j.UUID), nil, header, nil); err != nil {
+			code = fail(name, err)
+			continue
+		}
+		removed = append(removed, j.UUID)
+		if opts.output == "table" {
+			fmt.Printf("%v: 已删除\n", j.JobName)
+		}
+	}
+	if opts.output == "json" {
+		printJSON(removed)
+	}
+	return code
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+	"github.com/leancodebox/rooster/internal/server"
+)
+
+// rooster ctl 的退出码，脚本可以据此区分失败的原因
+const (
+	exitOK          = 0
+	exitFailure     = 1 // 操作失败，如校验不通过
+	exitUsage       = 2 // 参数错误
+	exitUnavailable = 3 // 找不到或无法连接正在运行的 rooster
+	exitAuth        = 4 // 令牌无效或权限不足
+	exitNotFound    = 5 // 任务不存在
+	exitConflict    = 6 // 任务状态不允许该操作，或已被其他人修改
+)
+
+// ctlOptions 所有 ctl 子命令共用的参数
+type ctlOptions struct {
+	url      string
+	port     int
+	token    string
+	output   string
+	insecure bool
+}
+
+func (o *ctlOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.url, "url", o.url, "rooster 的地址，如 https://127.0.0.1:9090，默认读取配置目录的 dashboard.json")
+	fs.IntVar(&o.port, "port", o.port, "连接本机指定端口的 rooster")
+	fs.StringVar(&o.token, "token", o.token, "API 令牌，默认读取环境变量 ROOSTER_TOKEN，再使用本机的管理员令牌")
+	fs.StringVar(&o.output, "o", o.output, "输出格式: table | json")
+	fs.BoolVar(&o.insecure, "insecure", o.insecure, "不校验 HTTPS 证书")
+}
+
+// ctlClient 通过 v2 API 访问正在运行的 rooster
+type ctlClient struct {
+	base   string
+	token  string
+	http   *http.Client
+	stream *http.Client // 不设超时，用于日志流
+}
+
+// ctlError 请求失败。status 为 0 时表示无法连接，code 为 v2 API 的错误码
+type ctlError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *ctlError) Error() string {
+	return e.message
+}
+
+// exitCodeOf 按失败原因选择退出码
+func exitCodeOf(err error) int {
+	var ce *ctlError
+	if !errors.As(err, &ce) {
+		return exitFailure
+	}
+	switch {
+	case ce.status == 0:
+		return exitUnavailable
+	case ce.status == http.StatusUnauthorized || ce.status == http.StatusForbidden:
+		return exitAuth
+	case ce.code == "job_not_found":
+		return exitNotFound
+	case ce.status == http.StatusConflict || ce.status == http.StatusPreconditionFailed:
+		return exitConflict
+	}
+	return exitFailure
+}
+
+// dial 按参数、dashboard.json 的顺序找到正在运行的 rooster，并选择令牌
+func dial(o ctlOptions) (*ctlClient, error) {
+	var configDir string
+	if m, err := jobmanager.OpenUserConfig(); err == nil {
+		configDir = m.ConfigDir()
+	} else if o.url == "" && o.port == 0 {
+		return nil, &ctlError{message: "读取配置失败，请通过 -url 或 -port 指定 rooster 的地址: " + err.Error()}
+	}
+	var d server.Discovery
+	var discovered bool
+	if configDir != "" {
+		var err error
+		d, err = server.ReadDiscovery(configDir)
+		discovered = err == nil
+	}
+
+	base := strings.TrimSuffix(o.url, "/")
+	switch {
+	case base != "":
+	case o.port > 0:
+		base = "http://127.0.0.1:" + strconv.Itoa(o.port)
+		if discovered && d.Port == o.port && d.TLS {
+			base = "https://127.0.0.1:" + strconv.Itoa(o.port)
+		}
+	case discovered:
+		base = d.URL
+	default:
+		return nil, &ctlError{message: "rooster 未运行（配置目录中没有 dashboard.json），也可以通过 -url 或 -port 指定地址"}
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: o.insecure}
+	if discovered && d.CertFile != "" && strings.HasPrefix(base, "https://") {
+		// 自签名证书不在系统信任的证书中
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if pem, err := os.ReadFile(d.CertFile); err == nil {
+			pool.AppendCertsFromPEM(pem)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	token := o.token
+	if token == "" {
+		token = os.Getenv("ROOSTER_TOKEN")
+	}
+	if token == "" && configDir != "" {
+		if auth, err := server.LoadAuth(configDir); err == nil {
+			token = auth.AdminToken()
+		}
+	}
+	return &ctlClient{
+		base:   base,
+		token:  token,
+		http:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		stream: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (c *ctlClient) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do 请求 v2 API，把成功的响应解析到 out（可以为 nil），返回响应头
+func (c *ctlClient) do(method, path string, body any, header map[string]string, out any) (http.Header, error) {
+	req, err := c.newRequest(context.Background(), method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &ctlError{message: "无法连接 " + c.base + ": " + err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &ctlError{message: "读取响应失败: " + err.Error()}
+	}
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Error server.V2Error `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error.Code == "" {
+			// 旧版本 rooster 没有 v2 API
+			return nil, &ctlError{status: resp.StatusCode, message: fmt.Sprintf("请求失败: %v %v", resp.Status, strings.TrimSpace(string(data)))}
+		}
+		msg := e.Error.Message
+		if e.Error.Code == "validation_failed" {
+			msg = validationMessage(e.Error.Details, msg)
+		}
+		return nil, &ctlError{status: resp.StatusCode, code: e.Error.Code, message: msg}
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("响应格式错误: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// validationMessage 把校验失败的字段错误逐行列出
+func validationMessage(details any, fallback string) string {
+	data, _ := json.Marshal(details)
+	var result jobmanager.ValidationResult
+	if json.Unmarshal(data, &result) != nil || len(result.Errors) == 0 {
+		return fallback
+	}
+	lines := []string{"任务配置校验失败:"}
+	for _, issue := range result.Errors {
+		lines = append(lines, "  "+issue.Field+": "+issue.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// legacy 请求返回 {"message": ...} 的旧接口，message 不为 success 时作为错误
+func (c *ctlClient) legacy(path string, out any) error {
+	req, err := c.newRequest(context.Background(), http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &ctlError{message: "无法连接 " + c.base + ": " + err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var msg struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(data, &msg)
+	if resp.StatusCode >= 400 || msg.Message != "success" {
+		return &ctlError{status: resp.StatusCode, message: msg.Message}
+	}
+	return json.Unmarshal(data, out)
+}
+
+// sseEvent 日志流中的一个事件
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// follow 读取 SSE 流，每个事件调用一次 fn，fn 返回 false 时结束。连接结束时返回 nil
+func (c *ctlClient) follow(ctx context.Context, path, lastEventID string, fn func(sseEvent) bool) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return &ctlError{message: "无法连接 " + c.base + ": " + err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return &ctlError{status: resp.StatusCode, message: "请求失败: " + resp.Status}
+	}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var ev sseEvent
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if data != nil {
+				ev.data = strings.Join(data, "\n")
+				if !fn(ev) {
+					return nil
+				}
+			}
+			ev, data = sseEvent{}, nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return nil
+}
@@ -15,13 +15,20 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
 	err := jobmanager.RegByUserConfig()
 	if err != nil {
 		slog.Error(err.Error())
 		return
 	}
-	server.ServeRun()
-	quit := make(chan os.Signal)
+	if server.ServeRun() == nil && server.ServeError() != nil {
+		// 例如 strictPort 模式下端口被占用
+		jobmanager.StopAll()
+		os.Exit(1)
+	}
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	server.ServeStop()
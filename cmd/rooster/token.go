@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+	"github.com/leancodebox/rooster/internal/server"
+)
+
+// runToken 管理 dashboard 与 API 的令牌，正在运行的 rooster 会自动读取修改后的令牌
+func runToken(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, `用法:
+  rooster token list                                列出令牌
+  rooster token create -name NAME [-scope SCOPE]    创建令牌，SCOPE 为 read（默认）或 operator
+  rooster token revoke -name NAME                   删除令牌
+  rooster token admin                               显示管理员令牌
+`)
+		return 2
+	}
+	fs := flag.NewFlagSet("token "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "令牌名称")
+	scope := fs.String("scope", string(server.ScopeRead), "令牌权限: read | operator")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	m, err := jobmanager.OpenUserConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	auth, err := server.LoadAuth(m.ConfigDir())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSCOPE\tCREATED")
+		for _, t := range auth.Tokens() {
+			fmt.Fprintf(tw, "%v\t%v\t%v\n", t.Name, t.Scope, t.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		_ = tw.Flush()
+	case "create":
+		token, err := auth.CreateToken(*name, server.Scope(*scope))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(token)
+		fmt.Fprintln(os.Stderr, "令牌只显示这一次，请妥善保存")
+	case "revoke":
+		if err := auth.RevokeToken(*name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "admin":
+		fmt.Println(auth.AdminToken())
+	default:
+		fmt.Fprintf(os.Stderr, "未知操作: %v\n", args[0])
+		return 2
+	}
+	return 0
+}
@@ -24,6 +24,8 @@ const (
 
 func main() {
 	setupLogger()
+	// 托盘程序没有控制台，任务输出不转发到标准输出
+	jobmanager.SetHeadless(true)
 
 	a := app.NewWithID(appID)
 	a.SetIcon(assets.GetAppIcon())
@@ -96,7 +98,7 @@ func setupTray(a fyne.App) *fyne.Menu {
 func runServer(a fyne.App, menu *fyne.Menu) {
 	serverErr := startRoosterServer()
 	port := server.GetPort()
-	url := fmt.Sprintf("http://localhost:%d/actor/", port)
+	url := server.GetURL() + "/actor/"
 
 	// Rebuild the menu items from scratch
 	var newMenuItems []*fyne.MenuItem
@@ -149,7 +151,7 @@ func startRoosterServer() error {
 		return err
 	}
 	server.ServeRun()
-	return nil
+	return server.ServeError()
 }
 
 func openURL(url string) error {
@@ -0,0 +1,283 @@
+// Package audit 记录修改状态的操作：谁在什么时候对哪个任务做了什么，以及任务配置的前后变化
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 每天一个日志文件，只追加写入；保存在内存中时最多保留的条数
+const (
+	fileDateLayout   = "2006-01-02"
+	fileSuffix       = ".jsonl"
+	memoryMaxEntries = 1000
+	defaultLimit     = 100
+	maxLimit         = 1000
+)
+
+// Change 一个配置字段的变化，字段以点分隔，如 options.outputType
+type Change struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Entry 一条审计记录
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Client  string    `json:"client"`            // 客户端地址
+	Actor   string    `json:"actor"`             // 令牌名，管理员令牌为 admin，未认证时为空
+	Action  string    `json:"action"`            // 如 job.save、job.disable
+	JobID   string    `json:"jobId,omitempty"`   // 目标任务
+	JobName string    `json:"jobName,omitempty"` // 目标任务的名称
+	Detail  string    `json:"detail,omitempty"`  // 操作的补充信息，如批量操作的类型
+	Result  string    `json:"result"`            // success 或错误信息
+	Changes []Change  `json:"changes,omitempty"` // 任务配置的变化
+}
+
+// Filter 查询条件，零值字段不参与过滤
+type Filter struct {
+	JobID   string
+	Actor   string
+	Actions []string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // 默认 100，最多 1000
+}
+
+func (f Filter) match(e Entry) bool {
+	if f.JobID != "" && e.JobID != f.JobID {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if len(f.Actions) > 0 && !slices.Contains(f.Actions, e.Action) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return defaultLimit
+	}
+	return min(f.Limit, maxLimit)
+}
+
+// Log 审计日志，保存在目录中的每日文件里，超过保留天数的文件被删除
+type Log struct {
+	dir       string // 为空时只保存在内存中
+	retention time.Duration
+	lock      sync.Mutex
+	memory    []Entry
+	pruned    string // 最后一次清理的日期
+}
+
+// Open 打开 dir 中的审计日志，dir 为空时只在内存中保留最近的记录。retentionDays 小于 1 时不删除
+func Open(dir string, retentionDays int) (*Log, error) {
+	l := &Log{dir: dir, retention: time.Duration(retentionDays) * 24 * time.Hour}
+	if dir == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Dir 审计日志目录，只保存在内存中时为空
+func (l *Log) Dir() string {
+	return l.dir
+}
+
+// Append 追加一条记录，Time 为空时使用当前时间
+func (l *Log) Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.dir == "" {
+		l.memory = append(l.memory, e)
+		if over := len(l.memory) - memoryMaxEntries; over > 0 {
+			l.memory = append(l.memory[:0], l.memory[over:]...)
+		}
+		return nil
+	}
+	l.prune(e.Time)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, e.Time.Format(fileDateLayout)+fileSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// prune 每天最多一次删除超过保留天数的文件，需持有锁
+func (l *Log) prune(now time.Time) {
+	today := now.Format(fileDateLayout)
+	if l.retention <= 0 || l.pruned == today {
+		return
+	}
+	l.pruned = today
+	cutoff := now.Add(-l.retention).Format(fileDateLayout)
+	for _, day := range l.days() {
+		if day < cutoff {
+			_ = os.Remove(filepath.Join(l.dir, day+fileSuffix))
+		}
+	}
+}
+
+// days 目录中日志文件的日期，从早到晚
+func (l *Log) days() []string {
+	entries, _ := os.ReadDir(l.dir)
+	var days []string
+	for _, e := range entries {
+		day, ok := strings.CutSuffix(e.Name(), fileSuffix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(fileDateLayout, day); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+// Query 按条件查询，结果从新到旧
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	limit := f.limit()
+	var result []Entry
+	if l.dir == "" {
+		for i := len(l.memory) - 1; i >= 0 && len(result) < limit; i-- {
+			if f.match(l.memory[i]) {
+				result = append(result, l.memory[i])
+			}
+		}
+		return result, nil
+	}
+	days := l.days()
+	for i := len(days) - 1; i >= 0 && len(result) < limit; i-- {
+		// 文件按本地日期命名，多留一天的余量
+		if !f.Since.IsZero() && days[i] < f.Since.Add(-24*time.Hour).Format(fileDateLayout) {
+			break
+		}
+		if !f.Until.IsZero() && days[i] > f.Until.Add(24*time.Hour).Format(fileDateLayout) {
+			continue
+		}
+		entries, err := readEntries(filepath.Join(l.dir, days[i]+fileSuffix))
+		if err != nil {
+			return nil, err
+		}
+		for j := len(entries) - 1; j >= 0 && len(result) < limit; j-- {
+			if f.match(entries[j]) {
+				result = append(result, entries[j])
+			}
+		}
+	}
+	return result, nil
+}
+
+// readEntries 读取一个日志文件，无法解析的行被忽略
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// Diff 比较两个值的 JSON 形式，返回变化的字段。对象按字段递归比较，数组作为整体比较；
+// before 或 after 为 nil 时表示创建或删除
+func Diff(before, after any) []Change {
+	b, a := map[string]any{}, map[string]any{}
+	flatten("", toJSONValue(before), b)
+	flatten("", toJSONValue(after), a)
+	var changes []Change
+	for k, bv := range b {
+		av, ok := a[k]
+		if !ok {
+			changes = append(changes, Change{Field: k, Before: bv})
+		} else if !reflect.DeepEqual(bv, av) {
+			changes = append(changes, Change{Field: k, Before: bv, After: av})
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changes = append(changes, Change{Field: k, After: av})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func toJSONValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+// flatten 展开对象，空值（null、空字符串、空数组、空对象）不记录
+func flatten(prefix string, v any, out map[string]any) {
+	switch t := v.(type) {
+	case nil:
+	case map[string]any:
+		for k, child := range t {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, child, out)
+		}
+	case string:
+		if t != "" {
+			out[prefix] = t
+		}
+	case []any:
+		if len(t) > 0 {
+			out[prefix] = t
+		}
+	default:
+		out[prefix] = t
+	}
+}
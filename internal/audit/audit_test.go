@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	type options struct {
+		OutputType int `json:"outputType"`
+	}
+	type spec struct {
+		Name    string   `json:"name"`
+		Run     bool     `json:"run"`
+		Tags    []string `json:"tags"`
+		Options options  `json:"options"`
+	}
+	before := &spec{Name: "api", Run: true, Options: options{OutputType: 2}}
+	after := &spec{Name: "api", Run: false, Tags: []string{"web"}, Options: options{OutputType: 4}}
+	changes := Diff(before, after)
+	if len(changes) != 3 || changes[0].Field != "options.outputType" || changes[1].Field != "run" || changes[2].Field != "tags" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	if changes[1].Before != true || changes[1].After != false || changes[2].Before != nil {
+		t.Fatalf("unexpected values %+v", changes)
+	}
+	if created := Diff(nil, after); len(created) != 4 {
+		t.Fatalf("creation should list every set field: %+v", created)
+	}
+	var none *spec
+	if removed := Diff(before, none); len(removed) != 3 || removed[0].After != nil {
+		t.Fatalf("removal should list every set field: %+v", removed)
+	}
+}
+
+func TestLogQueryAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().AddDate(0, 0, -10)
+	_ = os.WriteFile(filepath.Join(dir, old.Format(fileDateLayout)+fileSuffix), []byte(`{"action":"job.remove"}`+"\n"), 0o600)
+
+	l, err := Open(dir, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	_ = l.Append(Entry{Time: now.Add(-time.Minute), Actor: "admin", Action: "job.update", JobID: "a"})
+	_ = l.Append(Entry{Time: now, Actor: "ci", Action: "job.disable", JobID: "b"})
+	_ = l.Append(Entry{Time: now, Actor: "ci", Action: "job.update", JobID: "b"})
+	if _, err := os.Stat(filepath.Join(dir, old.Format(fileDateLayout)+fileSuffix)); !os.IsNotExist(err) {
+		t.Fatalf("files older than the retention should be removed")
+	}
+
+	entries, err := l.Query(Filter{})
+	if err != nil || len(entries) != 3 || entries[0].Action != "job.update" || entries[0].JobID != "b" {
+		t.Fatalf("entries should be newest first: %+v %v", entries, err)
+	}
+	entries, _ = l.Query(Filter{JobID: "b", Actions: []string{"job.disable"}})
+	if len(entries) != 1 || entries[0].Actor != "ci" {
+		t.Fatalf("unexpected filtered entries %+v", entries)
+	}
+	entries, _ = l.Query(Filter{Since: now.Add(-time.Second), Limit: 1})
+	if len(entries) != 1 || entries[0].JobID != "b" {
+		t.Fatalf("unexpected limited entries %+v", entries)
+	}
+}
@@ -0,0 +1,297 @@
+package jobmanager
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 任务包格式版本
+const bundleVersion = 1
+
+// JobBundle 可在机器之间迁移的任务包，不包含运行状态，家目录下的路径以 ~/ 表示
+type JobBundle struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Jobs       []JobSpec `json:"jobs"`
+}
+
+// 导入冲突处理策略
+const (
+	ImportSkip      = "skip"      // 保留已有任务
+	ImportRename    = "rename"    // 以新名称和新 UUID 导入
+	ImportOverwrite = "overwrite" // 覆盖已有任务（需已关闭）
+)
+
+// 导入时判断冲突的依据
+const (
+	ImportMatchUUID = "uuid"
+	ImportMatchName = "name"
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Strategy string `json:"strategy"` // skip / rename / overwrite，默认 skip
+	MatchBy  string `json:"matchBy"`  // uuid / name，默认 uuid
+	DryRun   bool   `json:"dryRun"`   // 只预览不写入
+}
+
+// ImportItem 单个任务的导入结果
+type ImportItem struct {
+	UUID    string            `json:"uuid"`
+	JobName string            `json:"jobName"`
+	Action  string            `json:"action"` // create / skip / rename / overwrite / error
+	Target  string            `json:"target"` // 被覆盖或重命名后的任务 UUID
+	Message string            `json:"message"`
+	Issues  []ValidationIssue `json:"issues,omitempty"`
+}
+
+// ImportReport 导入结果汇总
+type ImportReport struct {
+	DryRun bool         `json:"dryRun"`
+	Items  []ImportItem `json:"items"`
+}
+
+// bundlePath 将家目录下的绝对路径转为 ~/ 形式
+func bundlePath(p, home string) string {
+	if p == "" || home == "" {
+		return p
+	}
+	rel, err := filepath.Rel(home, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return p
+	}
+	if rel == "." {
+		return "~"
+	}
+	return "~/" + filepath.ToSlash(rel)
+}
+
+// localPath 将 ~/ 形式的路径展开为本机家目录
+func localPath(p, home string) string {
+	if home == "" {
+		return p
+	}
+	if p == "~" {
+		return home
+	}
+	if strings.HasPrefix(p, "~/") {
+		return filepath.Join(home, filepath.FromSlash(p[2:]))
+	}
+	return p
+}
+
+func matchJob(job *Job, selector string) bool {
+	return job.UUID == selector || job.JobName == selector
+}
+
+// ExportBundle 导出指定任务（UUID 或任务名），未指定时导出全部
+func (m *Manager) ExportBundle(selectors []string) (JobBundle, error) {
+	home, _ := userHomeDirFn()
+	defaultLogDir, _ := m.defaultLogDir()
+	bundle := JobBundle{Version: bundleVersion, ExportedAt: m.now(), Jobs: []JobSpec{}}
+	for _, job := range m.config.TaskList {
+		if len(selectors) > 0 {
+			found := false
+			for _, s := range selectors {
+				if matchJob(job, s) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		spec := job.JobSpec
+		spec.Run = false
+		spec.Dir = bundlePath(spec.Dir, home)
+		spec.BinPath = bundlePath(spec.BinPath, home)
+		// 默认日志目录是机器相关的，导入时使用目标机器的默认值
+		if spec.Options.OutputPath == defaultLogDir {
+			spec.Options.OutputPath = ""
+		}
+		spec.Options.OutputPath = bundlePath(spec.Options.OutputPath, home)
+		bundle.Jobs = append(bundle.Jobs, spec)
+	}
+	for _, s := range selectors {
+		if m.config.GetJob(s) == nil && m.getJobByName(s) == nil {
+			return bundle, fmt.Errorf("任务不存在: %v", s)
+		}
+	}
+	return bundle, nil
+}
+
+func (m *Manager) getJobByName(name string) *Job {
+	for _, job := range m.config.TaskList {
+		if job.JobName == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// ImportBundle 导入任务包。导入的任务均为关闭状态，DryRun 时只返回预览结果
+func (m *Manager) ImportBundle(bundle JobBundle, opts ImportOptions) (ImportReport, error) {
+	if bundle.Version != bundleVersion {
+		return ImportReport{}, fmt.Errorf("不支持的任务包版本: %v", bundle.Version)
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = ImportSkip
+	}
+	if opts.MatchBy == "" {
+		opts.MatchBy = ImportMatchUUID
+	}
+	switch opts.Strategy {
+	case ImportSkip, ImportRename, ImportOverwrite:
+	default:
+		return ImportReport{}, fmt.Errorf("未知的冲突处理策略: %v", opts.Strategy)
+	}
+	if opts.MatchBy != ImportMatchUUID && opts.MatchBy != ImportMatchName {
+		return ImportReport{}, fmt.Errorf("未知的冲突判断依据: %v", opts.MatchBy)
+	}
+
+	m.taskStatusLock.Lock()
+	defer m.taskStatusLock.Unlock()
+
+	home, _ := userHomeDirFn()
+	report := ImportReport{DryRun: opts.DryRun, Items: []ImportItem{}}
+
+	// 在副本上依次模拟导入，使包内任务之间的重名也能被检查到
+	working := make([]*Job, 0, len(m.config.TaskList)+len(bundle.Jobs))
+	for _, job := range m.config.TaskList {
+		working = append(working, &Job{JobSpec: job.JobSpec})
+	}
+	type change struct {
+		spec   JobSpec
+		target *Job // 覆盖的目标任务，为空表示新增
+	}
+	var changes []change
+
+	for _, spec := range bundle.Jobs {
+		item := ImportItem{UUID: spec.UUID, JobName: spec.JobName}
+		spec.Run = false
+		spec.Dir = localPath(spec.Dir, home)
+		spec.BinPath = localPath(spec.BinPath, home)
+		spec.Options.OutputPath = localPath(spec.Options.OutputPath, home)
+
+		var existing *Job
+		for _, job := range working {
+			if (opts.MatchBy == ImportMatchUUID && spec.UUID != "" && job.UUID == spec.UUID) ||
+				(opts.MatchBy == ImportMatchName && job.JobName == spec.JobName) {
+				existing = job
+				break
+			}
+		}
+
+		var target *Job
+		switch {
+		case existing == nil:
+			item.Action = "create"
+		case opts.Strategy == ImportSkip:
+			item.Action = "skip"
+			item.Target = existing.UUID
+			item.Message = "已存在同名或同 UUID 的任务"
+			report.Items = append(report.Items, item)
+			continue
+		case opts.Strategy == ImportRename:
+			item.Action = "rename"
+			spec.UUID = ""
+			spec.JobName = uniqueJobName(spec.JobName, working)
+		default:
+			item.Action = "overwrite"
+			item.Target = existing.UUID
+			if live := m.config.GetJob(existing.UUID); live != nil && live.Run {
+				item.Action = "error"
+				item.Message = "任务处于开启状态不允许修改,如需修改请先关闭"
+				report.Items = append(report.Items, item)
+				continue
+			}
+			if existing.Type != spec.Type {
+				item.Action = "error"
+				item.Message = "任务类型不允许修改"
+				report.Items = append(report.Items, item)
+				continue
+			}
+			spec.UUID = existing.UUID
+			target = existing
+		}
+
+		// UUID 与其他任务冲突时重新生成
+		if target == nil && spec.UUID != "" {
+			for _, job := range working {
+				if job.UUID == spec.UUID {
+					spec.UUID = ""
+					break
+				}
+			}
+		}
+		if target == nil && spec.UUID == "" {
+			spec.UUID = generateUUID()
+		}
+		if item.Action == "rename" {
+			item.Target = spec.UUID
+			item.Message = "导入为 " + spec.JobName
+		}
+
+		if r := validateJobSpec(spec, working, m.config.Config, m.configDir()); !r.Valid() {
+			item.Action = "error"
+			item.Message = r.Err().Error()
+			item.Issues = r.Errors
+			report.Items = append(report.Items, item)
+			continue
+		}
+
+		if target != nil {
+			target.JobSpec = spec
+		} else {
+			working = append(working, &Job{JobSpec: spec})
+		}
+		changes = append(changes, change{spec: spec, target: target})
+		report.Items = append(report.Items, item)
+	}
+
+	if opts.DryRun || len(changes) == 0 {
+		return report, nil
+	}
+	for _, ch := range changes {
+		if ch.target != nil {
+			job := m.config.GetJob(ch.spec.UUID)
+			if job == nil {
+				continue
+			}
+			// 在副本上补全默认值，避免替换正在使用的锁
+			updated := &Job{JobSpec: ch.spec}
+			m.ConfigInit(updated)
+			job.confLock.Lock()
+			job.JobSpec = updated.JobSpec
+			job.runtimeLogPath = updated.runtimeLogPath
+			job.confLock.Unlock()
+			continue
+		}
+		newJob := &Job{JobSpec: ch.spec}
+		m.ConfigInit(newJob)
+		m.config.AddJob(newJob)
+	}
+	m.flushConfig()
+	return report, nil
+}
+
+func uniqueJobName(name string, jobs []*Job) string {
+	taken := map[string]bool{}
+	for _, job := range jobs {
+		taken[job.JobName] = true
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%v-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// OpenUserConfig 读取用户配置但不启动任何任务，供命令行离线操作配置使用
+func OpenUserConfig() (*Manager, error) {
+	return New(Options{})
+}
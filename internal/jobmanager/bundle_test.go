@@ -0,0 +1,101 @@
+package jobmanager
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBundlePathRoundTrip(t *testing.T) {
+	home := filepath.FromSlash("/home/alice")
+	cases := map[string]string{
+		filepath.FromSlash("/home/alice/work/api"): "~/work/api",
+		home:                               "~",
+		filepath.FromSlash("/opt/app"):     filepath.FromSlash("/opt/app"),
+		filepath.FromSlash("/home/alicex"): filepath.FromSlash("/home/alicex"),
+		"":                                 "",
+	}
+	for in, want := range cases {
+		got := bundlePath(in, home)
+		if got != want {
+			t.Errorf("bundlePath(%q) = %q, want %q", in, got, want)
+		}
+		if back := localPath(got, home); back != in {
+			t.Errorf("localPath(%q) = %q, want %q", got, back, in)
+		}
+	}
+}
+
+func TestExportImportBundle(t *testing.T) {
+	home, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	src := createTestManager()
+	api := &Job{JobSpec: JobSpec{UUID: "u-api", JobName: "api", Type: JobTypeResident, Run: true, BinPath: filepath.Join(home, "bin", "api") + " --port 80", Dir: home}}
+	tick := &Job{JobSpec: JobSpec{UUID: "u-tick", JobName: "tick", Type: JobTypeScheduled, Spec: "* * * * *", BinPath: "echo tick"}}
+	src.ConfigInit(api)
+	src.ConfigInit(tick)
+	src.config.AddJob(api)
+	src.config.AddJob(tick)
+
+	bundle, err := src.ExportBundle([]string{"api"})
+	if err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+	if len(bundle.Jobs) != 1 || bundle.Jobs[0].Dir != "~" || bundle.Jobs[0].Run ||
+		bundle.Jobs[0].BinPath != "~/bin/api --port 80" {
+		t.Fatalf("unexpected bundle: %+v", bundle.Jobs)
+	}
+	if _, err := src.ExportBundle([]string{"missing"}); err == nil {
+		t.Fatalf("expected error for unknown job")
+	}
+
+	dst := createTestManager()
+	existing := &Job{JobSpec: JobSpec{UUID: "u-api", JobName: "api", Type: JobTypeResident, BinPath: "echo old"}}
+	dst.ConfigInit(existing)
+	dst.config.AddJob(existing)
+
+	// skip：已存在同 UUID 的任务
+	report, err := dst.ImportBundle(bundle, ImportOptions{})
+	if err != nil || report.Items[0].Action != "skip" {
+		t.Fatalf("expected skip, got %+v err %v", report, err)
+	}
+
+	// rename dry-run 不修改配置
+	report, err = dst.ImportBundle(bundle, ImportOptions{Strategy: ImportRename, DryRun: true})
+	if err != nil || report.Items[0].Action != "rename" || len(dst.config.TaskList) != 1 {
+		t.Fatalf("dry-run rename failed: %+v err %v", report, err)
+	}
+
+	report, err = dst.ImportBundle(bundle, ImportOptions{Strategy: ImportRename})
+	if err != nil || len(dst.config.TaskList) != 2 {
+		t.Fatalf("rename failed: %+v err %v", report, err)
+	}
+	renamed := dst.config.TaskList[1]
+	if renamed.JobName != "api-2" || renamed.UUID == "u-api" || renamed.Dir != home || renamed.Run {
+		t.Fatalf("unexpected renamed job: %+v", renamed.JobSpec)
+	}
+
+	// overwrite：按名称匹配覆盖已有任务
+	lock := existing.confLock
+	report, err = dst.ImportBundle(bundle, ImportOptions{Strategy: ImportOverwrite, MatchBy: ImportMatchName})
+	if err != nil || report.Items[0].Action != "overwrite" {
+		t.Fatalf("overwrite failed: %+v err %v", report, err)
+	}
+	if existing.BinPath != api.BinPath || existing.UUID != "u-api" {
+		t.Fatalf("job not overwritten: %+v", existing.JobSpec)
+	}
+	// 覆盖后与新增一样补全默认值，并保留原有的锁
+	if existing.Options.OutputType != OutputTypeFile || existing.Options.OutputPath == "" || existing.getRuntimeLogPath() == "" {
+		t.Fatalf("overwritten job not initialized: %+v", existing.Options)
+	}
+	if existing.confLock != lock {
+		t.Fatalf("overwrite should keep the job's lock")
+	}
+
+	// 开启中的任务不允许覆盖
+	existing.Run = true
+	report, _ = dst.ImportBundle(bundle, ImportOptions{Strategy: ImportOverwrite})
+	if report.Items[0].Action != "error" {
+		t.Fatalf("expected error for running job, got %+v", report.Items[0])
+	}
+}
@@ -13,39 +13,40 @@ import (
 	"strings"
 )
 
-func buildCmd(job *Job) *exec.Cmd {
-	shell := resolveShell(job)
-	fullCommand := job.BinPath
-	args := []string{"-lc", fullCommand}
+// commandLine 返回执行任务使用的 shell 与参数
+func commandLine(spec JobSpec) (string, []string) {
+	return resolveShell(spec), []string{"-lc", spec.BinPath}
+}
+
+func buildCmd(spec JobSpec) *exec.Cmd {
+	shell, args := commandLine(spec)
 	slog.Info(shell)
 	slog.Info(strings.Join(args, " "))
 	cmd := exec.Command(shell, args...)
 	HideWindows(cmd)
-	cmd.Env = loadUnixEnv(shell)
-	cmd.Dir = job.Dir
+	cmd.Env = append(loadUnixEnv(shell), sortedEnv(spec.Env)...)
+	cmd.Dir = spec.Dir
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd
 }
 
-func buildCmdWithCtx(ctx context.Context, job *Job) *exec.Cmd {
-	shell := resolveShell(job)
-	fullCommand := job.BinPath
-	args := []string{"-lc", fullCommand}
-	slog.Info("command", "bin", shell, "args", strings.Join(args, " "))
+// buildCmdWithCtx 构建任务命令，spec 需已完成变量展开。子进程不继承 rooster 的标准输入，
+// 需要交互输入的任务使用 PTY 模式
+func buildCmdWithCtx(ctx context.Context, spec JobSpec) *exec.Cmd {
+	shell, args := commandLine(spec)
 	cmd := exec.CommandContext(ctx, shell, args...)
 	HideWindows(cmd)
-	cmd.Env = loadUnixEnv(shell)
-	cmd.Dir = job.Dir
-	cmd.Stdin = os.Stdin
+	cmd.Env = append(loadUnixEnv(shell), sortedEnv(spec.Env)...)
+	cmd.Dir = spec.Dir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd
 }
 
-func resolveShell(job *Job) string {
-	shell := job.Options.ShellPath
+func resolveShell(spec JobSpec) string {
+	shell := spec.Options.ShellPath
 	if shell == "" {
 		shell = os.Getenv("SHELL")
 	}
@@ -10,27 +10,31 @@ import (
 	"strings"
 )
 
-func buildCmd(job *Job) *exec.Cmd {
-	shell := "cmd.exe"
-	args := []string{"/C", job.BinPath}
+// commandLine 返回执行任务使用的 shell 与参数
+func commandLine(spec JobSpec) (string, []string) {
+	return "cmd.exe", []string{"/C", spec.BinPath}
+}
+
+func buildCmd(spec JobSpec) *exec.Cmd {
+	shell, args := commandLine(spec)
 	cmd := exec.Command(shell, args...)
 	HideWindows(cmd)
-	cmd.Env = enrichWinEnv()
-	cmd.Dir = job.Dir
+	cmd.Env = append(enrichWinEnv(), sortedEnv(spec.Env)...)
+	cmd.Dir = spec.Dir
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd
 }
 
-func buildCmdWithCtx(ctx context.Context, job *Job) *exec.Cmd {
-	shell := "cmd.exe"
-	args := []string{"/C", job.BinPath}
+// buildCmdWithCtx 构建任务命令，spec 需已完成变量展开。子进程不继承 rooster 的标准输入，
+// 需要交互输入的任务使用 PTY 模式
+func buildCmdWithCtx(ctx context.Context, spec JobSpec) *exec.Cmd {
+	shell, args := commandLine(spec)
 	cmd := exec.CommandContext(ctx, shell, args...)
 	HideWindows(cmd)
-	cmd.Env = enrichWinEnv()
-	cmd.Dir = job.Dir
-	cmd.Stdin = os.Stdin
+	cmd.Env = append(enrichWinEnv(), sortedEnv(spec.Env)...)
+	cmd.Dir = spec.Dir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd
@@ -0,0 +1,254 @@
+package jobmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat 配置文件格式
+type ConfigFormat string
+
+const (
+	ConfigFormatJSON ConfigFormat = "json"
+	ConfigFormatYAML ConfigFormat = "yaml"
+	ConfigFormatTOML ConfigFormat = "toml"
+)
+
+// 配置文件候选名，按优先级排列
+var configFileNames = []string{"jobConfig.json", "jobConfig.yaml", "jobConfig.yml", "jobConfig.toml"}
+
+// ParseConfigFormat 解析格式名或文件扩展名
+func ParseConfigFormat(name string) (ConfigFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "json":
+		return ConfigFormatJSON, nil
+	case "yaml", "yml":
+		return ConfigFormatYAML, nil
+	case "toml":
+		return ConfigFormatTOML, nil
+	}
+	return "", fmt.Errorf("不支持的配置格式: %v", name)
+}
+
+// configFormatOf 根据文件扩展名判断配置格式，无法识别时按 JSON 处理
+func configFormatOf(path string) ConfigFormat {
+	if f, err := ParseConfigFormat(filepath.Ext(path)); err == nil {
+		return f
+	}
+	return ConfigFormatJSON
+}
+
+// decodeConfig 按格式解析配置。YAML/TOML 先转为 JSON 再解析，字段名与 JSON 保持一致
+func decodeConfig(data []byte, format ConfigFormat) (JobConfig, error) {
+	var config JobConfig
+	if len(bytes.TrimSpace(data)) == 0 {
+		return config, errors.New("配置内容为空")
+	}
+	jsonData, err := toJSON(data, format)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(jsonData, &config)
+	return config, err
+}
+
+// encodeConfig 按格式序列化配置
+func encodeConfig(config JobConfig, format ConfigFormat) ([]byte, error) {
+	jsonData, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return fromJSON(jsonData, format)
+}
+
+func toJSON(data []byte, format ConfigFormat) ([]byte, error) {
+	var v any
+	switch format {
+	case ConfigFormatJSON:
+		return data, nil
+	case ConfigFormatYAML:
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+	case ConfigFormatTOML:
+		if err := toml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的配置格式: %v", format)
+	}
+	return json.Marshal(v)
+}
+
+func fromJSON(jsonData []byte, format ConfigFormat) ([]byte, error) {
+	switch format {
+	case ConfigFormatJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, jsonData, "", "  "); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ConfigFormatYAML:
+		// 通过 yaml.Node 构造以保留字段顺序
+		node, err := jsonToYAMLNode(json.NewDecoder(bytes.NewReader(jsonData)))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return nil, err
+		}
+		_ = enc.Close()
+		return buf.Bytes(), nil
+	case ConfigFormatTOML:
+		dec := json.NewDecoder(bytes.NewReader(jsonData))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(normalizeForTOML(v)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("不支持的配置格式: %v", format)
+}
+
+// normalizeForTOML 去掉 TOML 无法表示的 null，并把数字还原为整数或浮点数
+func normalizeForTOML(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(vv))
+		for k, item := range vv {
+			if item == nil {
+				continue
+			}
+			out[k] = normalizeForTOML(item)
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(vv))
+		for _, item := range vv {
+			if item == nil {
+				continue
+			}
+			out = append(out, normalizeForTOML(item))
+		}
+		return out
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		f, _ := vv.Float64()
+		return f
+	}
+	return v
+}
+
+func jsonToYAMLNode(dec *json.Decoder) (*yaml.Node, error) {
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := jsonToYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keyTok.(string)}, value)
+			}
+			_, err = dec.Token()
+			return node, err
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				value, err := jsonToYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, value)
+			}
+			_, err = dec.Token()
+			return node, err
+		}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(t)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected json token: %v", tok)
+}
+
+// sameConfig 判断两份配置序列化后是否一致，用于避免重写手工编辑（含注释）的配置文件
+func sameConfig(a, b JobConfig) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// ConfigPath 返回当前使用的配置文件路径
+func ConfigPath() (string, error) {
+	return getConfigPath()
+}
+
+// ConvertConfigFile 将配置文件转换为另一种格式，格式由文件扩展名决定
+func ConvertConfigFile(inPath, outPath string) error {
+	if _, err := ParseConfigFormat(filepath.Ext(outPath)); err != nil {
+		return err
+	}
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	config, err := decodeConfig(data, configFormatOf(inPath))
+	if err != nil {
+		return fmt.Errorf("解析 %v 失败: %w", inPath, err)
+	}
+	out, err := encodeConfig(config, configFormatOf(outPath))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(outPath, out, 0644)
+}
@@ -0,0 +1,89 @@
+package jobmanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeConfig_RoundTrip(t *testing.T) {
+	def := generateDefaultJobConfig()
+	for _, format := range []ConfigFormat{ConfigFormatJSON, ConfigFormatYAML, ConfigFormatTOML} {
+		data, err := encodeConfig(def, format)
+		if err != nil {
+			t.Fatalf("%v encode err: %v", format, err)
+		}
+		got, err := decodeConfig(data, format)
+		if err != nil {
+			t.Fatalf("%v decode err: %v\n%s", format, err, data)
+		}
+		if !sameConfig(def, got) {
+			t.Fatalf("%v round trip mismatch:\n%s", format, data)
+		}
+	}
+}
+
+func TestEncodeConfig_YAMLKeepsFieldOrder(t *testing.T) {
+	data, err := encodeConfig(generateDefaultJobConfig(), ConfigFormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	if strings.Index(s, "jobName:") > strings.Index(s, "binPath:") {
+		t.Fatalf("field order not preserved:\n%s", s)
+	}
+	if strings.Contains(s, "maxFailures: 5.0") {
+		t.Fatalf("integer encoded as float:\n%s", s)
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	if got := findConfigFile(dir); filepath.Base(got) != "jobConfig.json" {
+		t.Fatalf("default should be json, got %v", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "jobConfig.yml"), []byte("taskList: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findConfigFile(dir); filepath.Base(got) != "jobConfig.yml" {
+		t.Fatalf("expected yml, got %v", got)
+	}
+}
+
+func TestConvertConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "jobConfig.yaml")
+	yamlData := `# 团队共享的任务
+taskList:
+  - jobName: api
+    type: 1
+    binPath: ./api --port 8080 # 注释
+    options:
+      maxFailures: 3
+config:
+  dashboard:
+    port: 9090
+`
+	if err := os.WriteFile(in, []byte(yamlData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "jobConfig.toml")
+	if err := ConvertConfigFile(in, out); err != nil {
+		t.Fatalf("convert err: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	config, err := decodeConfig(data, ConfigFormatTOML)
+	if err != nil {
+		t.Fatalf("decode toml err: %v\n%s", err, data)
+	}
+	if len(config.TaskList) != 1 || config.TaskList[0].BinPath != "./api --port 8080" || config.TaskList[0].Options.MaxFailures != 3 {
+		t.Fatalf("unexpected converted config: %s", data)
+	}
+	if config.Config.Dashboard.Port != 9090 {
+		t.Fatalf("dashboard port lost: %s", data)
+	}
+	if err := ConvertConfigFile(in, filepath.Join(dir, "jobConfig.ini")); err == nil {
+		t.Fatalf("expected error for unsupported format")
+	}
+}
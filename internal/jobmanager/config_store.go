@@ -0,0 +1,304 @@
+package jobmanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 配置历史版本保留数量
+const maxConfigVersions = 20
+
+// 历史版本文件名中的时间格式
+const configVersionLayout = "20060102-150405.000"
+
+// ConfigVersion 描述一个已保存的配置历史版本
+type ConfigVersion struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Size    int64     `json:"size"`
+	Current bool      `json:"current"` // 是否与当前配置内容一致
+}
+
+func configHistoryDir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "history")
+}
+
+func configVersionFile(configPath, id string) string {
+	base := filepath.Base(configPath)
+	ext := filepath.Ext(base)
+	return filepath.Join(configHistoryDir(configPath), strings.TrimSuffix(base, ext)+"."+id+ext)
+}
+
+// writeFileAtomic 先写临时文件再 rename，避免写入中途崩溃导致配置被截断
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpName)
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	err = os.Rename(tmpName, filename)
+	return err
+}
+
+// saveConfigVersion 将配置内容存为一个历史版本并清理超出数量的旧版本
+func saveConfigVersion(configPath string, data []byte) error {
+	dir := configHistoryDir(configPath)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	now := time.Now()
+	id := now.Format(configVersionLayout)
+	// 同一毫秒内多次写入时顺延，保证版本号唯一
+	for fileExists(configVersionFile(configPath, id)) {
+		now = now.Add(time.Millisecond)
+		id = now.Format(configVersionLayout)
+	}
+	if err := writeFileAtomic(configVersionFile(configPath, id), data, 0644); err != nil {
+		return err
+	}
+	return pruneConfigVersions(configPath, maxConfigVersions)
+}
+
+func pruneConfigVersions(configPath string, keep int) error {
+	ids, err := listConfigVersionIds(configPath)
+	if err != nil {
+		return err
+	}
+	if len(ids) <= keep {
+		return nil
+	}
+	for _, id := range ids[keep:] {
+		if err := os.Remove(configVersionFile(configPath, id)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listConfigVersionIds 返回历史版本号，按时间倒序
+func listConfigVersionIds(configPath string) ([]string, error) {
+	entries, err := os.ReadDir(configHistoryDir(configPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	base := filepath.Base(configPath)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "."
+	var ids []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.ParseInLocation(configVersionLayout, id, time.Local); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+func readConfigVersion(configPath, id string) ([]byte, error) {
+	if _, err := time.ParseInLocation(configVersionLayout, id, time.Local); err != nil {
+		return nil, errors.New("版本号格式错误")
+	}
+	data, err := os.ReadFile(configVersionFile(configPath, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("版本不存在")
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// loadLatestValidVersion 返回最近一个可解析的历史版本
+func loadLatestValidVersion(configPath string) (string, []byte, error) {
+	ids, err := listConfigVersionIds(configPath)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, id := range ids {
+		data, err := os.ReadFile(configVersionFile(configPath, id))
+		if err != nil {
+			continue
+		}
+		if _, err := decodeConfig(data, configFormatOf(configPath)); err == nil {
+			return id, data, nil
+		}
+	}
+	return "", nil, errors.New("没有可用的历史版本")
+}
+
+// writeConfigFile 原子写入配置文件，内容有变化时记录历史版本
+func writeConfigFile(configPath string, data []byte, logger *slog.Logger) error {
+	if old, err := os.ReadFile(configPath); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
+		return err
+	}
+	if err := saveConfigVersion(configPath, data); err != nil {
+		logger.Error("保存配置历史版本失败", "err", err)
+	}
+	return nil
+}
+
+// recoverBrokenConfig 备份无法解析的配置文件，并用最近的有效历史版本恢复
+func recoverBrokenConfig(configPath string, broken []byte, logger *slog.Logger) ([]byte, error) {
+	id, data, err := loadLatestValidVersion(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件 %v 无法解析且%w，请手动修复", configPath, err)
+	}
+	backup := fmt.Sprintf("%v.broken-%v", configPath, time.Now().Format(configVersionLayout))
+	if err := os.WriteFile(backup, broken, 0644); err != nil {
+		return nil, fmt.Errorf("备份损坏的配置文件失败: %w", err)
+	}
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
+		return nil, err
+	}
+	logger.Warn("配置文件无法解析，已从历史版本恢复", "version", id, "backup", backup)
+	return data, nil
+}
+
+// ListConfigVersions 列出配置历史版本，按时间倒序
+func (m *Manager) ListConfigVersions() ([]ConfigVersion, error) {
+	configPath, err := m.configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := listConfigVersionIds(configPath)
+	if err != nil {
+		return nil, err
+	}
+	current, _ := os.ReadFile(configPath)
+	versions := make([]ConfigVersion, 0, len(ids))
+	for _, id := range ids {
+		fp := configVersionFile(configPath, id)
+		st, err := os.Stat(fp)
+		if err != nil {
+			continue
+		}
+		t, _ := time.ParseInLocation(configVersionLayout, id, time.Local)
+		v := ConfigVersion{ID: id, Time: t, Size: st.Size()}
+		if data, err := os.ReadFile(fp); err == nil && bytes.Equal(data, current) {
+			v.Current = true
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// DiffConfigVersion 比较历史版本与另一个版本（为空时与当前配置比较），返回 unified 格式的差异
+func (m *Manager) DiffConfigVersion(id string, against string) (string, error) {
+	configPath, err := m.configFilePath()
+	if err != nil {
+		return "", err
+	}
+	from, err := readConfigVersion(configPath, id)
+	if err != nil {
+		return "", err
+	}
+	var to []byte
+	toName := "current"
+	if against == "" {
+		to, err = os.ReadFile(configPath)
+	} else {
+		to, err = readConfigVersion(configPath, against)
+		toName = against
+	}
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(id, toName, string(from), string(to)), nil
+}
+
+// RollbackConfig 回滚到指定的历史版本，并按新配置重新加载任务
+func (m *Manager) RollbackConfig(id string) error {
+	configPath, err := m.configFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := readConfigVersion(configPath, id)
+	if err != nil {
+		return err
+	}
+	config, err := decodeConfig(data, configFormatOf(configPath))
+	if err != nil {
+		return fmt.Errorf("历史版本无法解析: %w", err)
+	}
+	// 与加载配置一致，校验错误只记录警告，未通过校验的任务需要修正后才能保存
+	m.reload(config)
+	return nil
+}
+
+// reload 停止当前所有任务，替换为新的配置后重新启动
+func (m *Manager) reload(config JobConfig) {
+	m.taskStatusLock.Lock()
+	defer m.taskStatusLock.Unlock()
+
+	for _, job := range m.config.TaskList {
+		if job.confLock == nil {
+			continue
+		}
+		if job.entityId != 0 {
+			m.cron.Remove(job.entityId)
+			job.entityId = 0
+		}
+		m.StopJob(job)
+	}
+	for _, job := range m.config.GetResidentTask() {
+		if job.confLock != nil {
+			waitLoopExit(job)
+		}
+	}
+
+	m.configLock.Lock()
+	m.config = config
+	m.configLock.Unlock()
+
+	m.Start()
+	m.flushConfig()
+}
+
+// waitLoopExit 等待常驻任务的守护循环退出，最多等待 5 秒
+func waitLoopExit(job *Job) {
+	for i := 0; i < 50; i++ {
+		if !job.IsRunningLoop() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,128 @@
+package jobmanager
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfigFile_VersionsAndPrune(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "jobConfig.json")
+
+	for i := 0; i < maxConfigVersions+5; i++ {
+		cfg := JobConfig{TaskList: []*Job{{JobSpec: JobSpec{JobName: "job", Spec: strings.Repeat("*", i+1)}}}}
+		b, _ := json.MarshalIndent(cfg, "", "  ")
+		if err := writeConfigFile(configPath, b, slog.Default()); err != nil {
+			t.Fatalf("writeConfigFile err: %v", err)
+		}
+		// 内容不变时不应产生新版本
+		if err := writeConfigFile(configPath, b, slog.Default()); err != nil {
+			t.Fatalf("writeConfigFile err: %v", err)
+		}
+	}
+
+	ids, err := listConfigVersionIds(configPath)
+	if err != nil {
+		t.Fatalf("list err: %v", err)
+	}
+	if len(ids) != maxConfigVersions {
+		t.Fatalf("expected %d versions, got %d", maxConfigVersions, len(ids))
+	}
+	latest, err := readConfigVersion(configPath, ids[0])
+	if err != nil {
+		t.Fatalf("read err: %v", err)
+	}
+	current, _ := os.ReadFile(configPath)
+	if string(latest) != string(current) {
+		t.Fatalf("latest version does not match current config")
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(configPath))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Fatalf("temp file left behind: %v", e.Name())
+		}
+	}
+}
+
+func TestRecoverBrokenConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "jobConfig.json")
+
+	if _, err := recoverBrokenConfig(configPath, []byte("{"), slog.Default()); err == nil {
+		t.Fatalf("expected error without history")
+	}
+
+	good := []byte(`{"taskList":[{"jobName":"keep-me"}]}`)
+	if err := writeConfigFile(configPath, good, slog.Default()); err != nil {
+		t.Fatalf("writeConfigFile err: %v", err)
+	}
+	// 模拟写入中途崩溃导致文件被截断
+	if err := os.WriteFile(configPath, []byte(`{"taskList":[{"jobNa`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := recoverBrokenConfig(configPath, []byte(`{"taskList":[{"jobNa`), slog.Default())
+	if err != nil {
+		t.Fatalf("recover err: %v", err)
+	}
+	if string(data) != string(good) {
+		t.Fatalf("unexpected recovered data: %s", data)
+	}
+	onDisk, _ := os.ReadFile(configPath)
+	if string(onDisk) != string(good) {
+		t.Fatalf("config not restored on disk: %s", onDisk)
+	}
+	matches, _ := filepath.Glob(configPath + ".broken-*")
+	if len(matches) != 1 {
+		t.Fatalf("broken config should be backed up, got %v", matches)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if d := unifiedDiff("a", "b", "x\ny\n", "x\ny\n"); d != "" {
+		t.Fatalf("expected empty diff, got %q", d)
+	}
+	d := unifiedDiff("a", "b", "1\n2\n3\n4\n", "1\n2\nthree\n4\n5\n")
+	for _, want := range []string{"--- a", "+++ b", "-3", "+three", "+5", " 2"} {
+		if !strings.Contains(d, want) {
+			t.Fatalf("diff missing %q:\n%s", want, d)
+		}
+	}
+}
+
+func TestRollbackConfig_WarnsOnInvalidVersion(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "jobConfig.json")
+	// 历史版本中任务的工作目录已不存在，与加载配置一样只记录警告
+	invalid := []byte(`{"taskList":[{"uuid":"u-legacy","jobName":"legacy","type":2,"spec":"@daily","binPath":"echo legacy","dir":"` +
+		filepath.ToSlash(filepath.Join(dir, "gone")) + `"}]}`)
+	if err := writeConfigFile(configPath, invalid, slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(Options{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer m.StopAll()
+	ids, _ := listConfigVersionIds(configPath)
+	if len(ids) != 1 {
+		t.Fatalf("expected one version, got %v", ids)
+	}
+	if err := m.SaveTask(JobStatusShow{JobName: "fresh", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo fresh"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := m.RollbackConfig(ids[0]); err != nil {
+		t.Fatalf("rollback to a version with validation errors should succeed: %v", err)
+	}
+	list := m.JobList()
+	if len(list) != 1 || list[0].UUID != "u-legacy" {
+		t.Fatalf("unexpected jobs after rollback: %+v", list)
+	}
+	// 未通过校验的任务需要修正后才能保存
+	if err := m.SaveTask(list[0]); err == nil {
+		t.Fatalf("expected validation error when saving the invalid job")
+	}
+}
@@ -0,0 +1,31 @@
+package jobmanager
+
+import "errors"
+
+// 任务操作失败的类别，可通过 errors.Is 判断，供 API 映射为状态码与错误码。
+// 错误信息保持各操作原有的描述
+var (
+	ErrJobNotFound      = errors.New("任务不存在")
+	ErrJobEnabled       = errors.New("任务处于开启状态")
+	ErrJobTypeImmutable = errors.New("任务类型不允许修改")
+	ErrJobBusy          = errors.New("任务正在运行")
+	ErrUnsupported      = errors.New("任务不支持该操作")
+)
+
+// jobError 属于某个类别的错误
+type jobError struct {
+	kind error
+	msg  string
+}
+
+func (e *jobError) Error() string {
+	return e.msg
+}
+
+func (e *jobError) Is(target error) bool {
+	return target == e.kind
+}
+
+func newJobError(kind error, msg string) error {
+	return &jobError{kind: kind, msg: msg}
+}
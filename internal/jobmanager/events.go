@@ -0,0 +1,183 @@
+package jobmanager
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType 生命周期事件类型
+type EventType string
+
+const (
+	EventJobStarted    EventType = "job.started"    // 进程已启动
+	EventJobExited     EventType = "job.exited"     // 进程已退出，带退出码与原因
+	EventJobRestarting EventType = "job.restarting" // 常驻任务将在 Delay 后重启
+	EventJobGaveUp     EventType = "job.gave_up"    // 常驻任务连续失败次数过多，停止重启
+	EventJobEnabled    EventType = "job.enabled"    // 任务被开启
+	EventJobDisabled   EventType = "job.disabled"   // 任务被关闭
+	EventConfigChanged EventType = "config.changed" // 配置内容发生变化
+	EventCronFired     EventType = "cron.fired"     // 定时任务被触发
+	EventCronSkipped   EventType = "cron.skipped"   // 定时任务的触发被跳过
+)
+
+// 退出原因
+const (
+	ExitReasonExited      = "exited"       // 正常退出，退出码为 0
+	ExitReasonFailed      = "failed"       // 退出码非 0
+	ExitReasonStopped     = "stopped"      // 被手动停止或随程序关闭
+	ExitReasonStartFailed = "start_failed" // 进程未能启动
+)
+
+// Event 生命周期事件，ID 在同一个 Manager 内单调递增
+type Event struct {
+	ID       uint64        `json:"id"`
+	Type     EventType     `json:"type"`
+	Time     time.Time     `json:"time"`
+	UUID     string        `json:"uuid,omitempty"`
+	JobName  string        `json:"jobName,omitempty"`
+	RunID    string        `json:"runId,omitempty"`
+	Pid      int           `json:"pid,omitempty"`
+	ExitCode *int          `json:"exitCode,omitempty"`
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"` // 本次运行时长
+	Delay    time.Duration `json:"delay,omitempty"`    // 重启前的等待时间
+	Message  string        `json:"message,omitempty"`
+}
+
+func jobEvent(t EventType, job *Job) Event {
+	return Event{Type: t, UUID: job.UUID, JobName: job.JobName}
+}
+
+// 默认保留的最近事件数量，用于断线重连后补发
+const defaultEventBufferSize = 1000
+
+// EventBus 发布生命周期事件，并保留最近的事件供订阅者补发
+type EventBus struct {
+	lock   sync.Mutex
+	lastID uint64
+	buffer []Event // 环形缓冲区
+	start  int     // 最早事件在 buffer 中的位置
+	count  int
+	subs   map[chan Event]struct{}
+	clock  func() time.Time
+}
+
+// NewEventBus 创建保留最近 size 个事件的事件总线
+func NewEventBus(size int) *EventBus {
+	if size <= 0 {
+		size = defaultEventBufferSize
+	}
+	return &EventBus{
+		buffer: make([]Event, size),
+		subs:   map[chan Event]struct{}{},
+		clock:  time.Now,
+	}
+}
+
+// Publish 分配 ID 与时间后发布事件。订阅者处理不过来时其通道会被关闭，
+// 由订阅者按最后收到的 ID 重新订阅补发，发布方不会被阻塞
+func (b *EventBus) Publish(e Event) Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time.IsZero() {
+		e.Time = b.clock()
+	}
+	if b.count < len(b.buffer) {
+		b.buffer[(b.start+b.count)%len(b.buffer)] = e
+		b.count++
+	} else {
+		b.buffer[b.start] = e
+		b.start = (b.start + 1) % len(b.buffer)
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// since 返回 ID 大于 afterID 的缓冲事件，调用方需持有锁
+func (b *EventBus) since(afterID uint64) []Event {
+	// afterID 比最新的还大，说明来自重启之前，补发全部缓冲事件
+	if afterID > b.lastID {
+		afterID = 0
+	}
+	var events []Event
+	for i := 0; i < b.count; i++ {
+		e := b.buffer[(b.start+i)%len(b.buffer)]
+		if e.ID > afterID {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Since 返回缓冲区中 ID 大于 afterID 的事件
+func (b *EventBus) Since(afterID uint64) []Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.since(afterID)
+}
+
+// Subscribe 订阅之后发布的事件，buffer 为通道容量。调用返回的函数取消订阅
+func (b *EventBus) Subscribe(buffer int) (<-chan Event, func()) {
+	_, ch, cancel := b.SubscribeFrom(b.LastID(), buffer)
+	return ch, cancel
+}
+
+// SubscribeFrom 原子地返回 ID 大于 afterID 的缓冲事件并订阅之后的事件，用于断线重连
+func (b *EventBus) SubscribeFrom(afterID uint64, buffer int) ([]Event, <-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan Event, buffer)
+	b.lock.Lock()
+	replay := b.since(afterID)
+	b.subs[ch] = struct{}{}
+	b.lock.Unlock()
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.lock.Lock()
+			defer b.lock.Unlock()
+			if _, ok := b.subs[ch]; ok {
+				delete(b.subs, ch)
+				close(ch)
+			}
+		})
+	}
+	return replay, ch, cancel
+}
+
+// LastID 最近一个事件的 ID
+func (b *EventBus) LastID() uint64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.lastID
+}
+
+// Events 返回管理器的事件总线
+func (m *Manager) Events() *EventBus {
+	m.eventsOnce.Do(func() {
+		if m.events == nil {
+			m.events = NewEventBus(defaultEventBufferSize)
+			m.events.clock = m.now
+		}
+	})
+	return m.events
+}
+
+func (m *Manager) emit(e Event) {
+	m.Events().Publish(e)
+}
+
+// Subscribe 订阅管理器的生命周期事件，调用返回的函数取消订阅
+func (m *Manager) Subscribe(buffer int) (<-chan Event, func()) {
+	return m.Events().Subscribe(buffer)
+}
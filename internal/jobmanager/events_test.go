@@ -0,0 +1,115 @@
+package jobmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func eventIDs(events []Event) []uint64 {
+	var ids []uint64
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestEventBus_BufferAndResume(t *testing.T) {
+	bus := NewEventBus(3)
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: EventConfigChanged})
+	}
+	if got := eventIDs(bus.Since(0)); len(got) != 3 || got[0] != 3 || got[2] != 5 {
+		t.Fatalf("ring buffer should keep the last 3 events, got %v", got)
+	}
+	if got := eventIDs(bus.Since(4)); len(got) != 1 || got[0] != 5 {
+		t.Fatalf("unexpected events after 4: %v", got)
+	}
+	// 来自重启之前的 ID 补发全部缓冲事件
+	if got := bus.Since(100); len(got) != 3 {
+		t.Fatalf("unknown id should replay the buffer, got %v", eventIDs(got))
+	}
+
+	replay, ch, cancel := bus.SubscribeFrom(4, 1)
+	defer cancel()
+	if len(replay) != 1 || replay[0].ID != 5 {
+		t.Fatalf("unexpected replay: %v", eventIDs(replay))
+	}
+	bus.Publish(Event{Type: EventJobStarted})
+	if e := <-ch; e.ID != 6 || e.Type != EventJobStarted || e.Time.IsZero() {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	// 处理不过来的订阅者被关闭，不阻塞发布方
+	bus.Publish(Event{Type: EventJobExited})
+	bus.Publish(Event{Type: EventJobExited})
+	<-ch
+	if _, ok := <-ch; ok {
+		t.Fatalf("slow subscriber should be closed")
+	}
+	cancel()
+}
+
+func collect(ch <-chan Event, n int, t *testing.T) []Event {
+	var events []Event
+	timeout := time.After(5 * time.Second)
+	for len(events) < n {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		case <-timeout:
+			t.Fatalf("timed out, got %+v", events)
+		}
+	}
+	return events
+}
+
+func TestManagerEmitsLifecycleEvents(t *testing.T) {
+	m := createTestManager()
+	tmpDir, cleanup := mockHomeDir(t)
+	defer cleanup()
+	m.store = NewMemoryStore(JobConfig{})
+	ch, cancel := m.Subscribe(32)
+	defer cancel()
+
+	job := &Job{JobSpec: JobSpec{UUID: "u1", JobName: "fail", Type: JobTypeScheduled, Spec: "* * * * *", BinPath: "exit 3", Dir: tmpDir}}
+	m.ConfigInit(job)
+	m.config.AddJob(job)
+
+	m.cronFunc(job)()
+	events := collect(ch, 3, t)
+	if events[0].Type != EventCronFired || events[1].Type != EventJobStarted || events[2].Type != EventJobExited {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	exited := events[2]
+	if exited.ExitCode == nil || *exited.ExitCode != 3 || exited.Reason != ExitReasonFailed || exited.RunID == "" || exited.RunID != events[1].RunID {
+		t.Fatalf("unexpected exit event: %+v", exited)
+	}
+
+	// 默认照常运行，与上次运行重叠
+	job.runOnceLock.Lock()
+	m.cronFunc(job)()
+	job.runOnceLock.Unlock()
+	if e := collect(ch, 3, t)[0]; e.Type != EventCronFired {
+		t.Fatalf("overlapping run should fire by default, got %+v", e)
+	}
+
+	// 并发策略为 forbid 时，上次运行尚未结束则跳过
+	job.Options.Concurrency = ConcurrencyForbid
+	job.runOnceLock.Lock()
+	m.cronFunc(job)()
+	job.runOnceLock.Unlock()
+	if e := collect(ch, 1, t)[0]; e.Type != EventCronSkipped || e.UUID != "u1" {
+		t.Fatalf("expected skip event, got %+v", e)
+	}
+
+	if err := m.OpenCloseTask("u1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.OpenCloseTask("u1", true); err != nil {
+		t.Fatal(err)
+	}
+	events = collect(ch, 3, t)
+	if events[0].Type != EventJobDisabled || events[1].Type != EventConfigChanged || events[2].Type != EventJobEnabled {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
@@ -2,12 +2,15 @@ package jobmanager
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"strings"
 	"time"
 )
 
 // ExecutionResult 保存单次任务执行的结果
 type ExecutionResult struct {
+	RunID     string
 	StartTime time.Time
 	EndTime   time.Time
 	Duration  time.Duration
@@ -18,35 +21,105 @@ type ExecutionResult struct {
 // JobExecutor 处理任务的执行逻辑
 type JobExecutor struct {
 	logManager *LogManager
+	base       BaseConfig // 全局配置，提供自定义变量
+	configDir  string     // ${CONFIG_DIR} 的值
+	clock      func() time.Time
+	logger     *slog.Logger
+	emit       func(Event)              // 发布生命周期事件，可为空
+	sinks      func(JobSpec) []lineSink // 返回任务输出需要转发到的外部日志系统，可为空
 }
 
 // NewJobExecutor 创建一个新的执行器实例
 func NewJobExecutor() *JobExecutor {
 	return &JobExecutor{
 		logManager: NewLogManager(),
+		clock:      time.Now,
+		logger:     slog.Default(),
 	}
 }
 
-// Execute 处理单次运行的完整生命周期
-func (e *JobExecutor) Execute(ctx context.Context, job *Job, onStart func(int)) ExecutionResult {
-	result := ExecutionResult{
-		StartTime: time.Now(),
+// newExecutor 创建使用管理器配置、时钟与日志的执行器
+func (m *Manager) newExecutor() *JobExecutor {
+	e := NewJobExecutor()
+	e.logManager.logDir = m.logDir
+	e.logManager.clock = m.now
+	if m.headless {
+		e.logManager.stdout = nil
 	}
+	e.base = m.config.Config
+	e.configDir = m.configDir()
+	e.clock = m.now
+	e.logger = m.log()
+	e.emit = m.emit
+	e.sinks = m.jobSinks
+	return e
+}
 
-	// 1. 设置日志
-	writer, fullLogPath, err := e.logManager.SetupLogger(job.JobName, job.Options)
+// pruneRunLogs 按任务的保留设置清理运行日志
+func (e *JobExecutor) pruneRunLogs(spec JobSpec) {
+	dir, err := runLogDir(e.logManager.logDir, spec.JobName, spec.Options)
+	if err == nil {
+		err = pruneRunLogs(dir, spec.Options.KeepRuns, spec.Options.KeepDays, e.clock())
+	}
 	if err != nil {
-		slog.Error("SetupLogger failed", "err", err)
+		e.logger.Warn("清理运行日志失败", "jobName", spec.JobName, "err", err)
+	}
+}
+
+func (e *JobExecutor) publish(ev Event) {
+	if e.emit != nil {
+		e.emit(ev)
+	}
+}
+
+// Execute 以启动时的配置副本 spec 处理 job 单次运行的完整生命周期，
+// run 中未指定的运行 ID 与触发时间会自动生成
+func (e *JobExecutor) Execute(ctx context.Context, job *Job, spec JobSpec, run RunInfo, onStart func(int)) ExecutionResult {
+	result := ExecutionResult{
+		StartTime: e.clock(),
+	}
+	if run.FireTime.IsZero() {
+		run.FireTime = result.StartTime
+	}
+	if run.RunID == "" {
+		run.RunID = newRunID(run.FireTime)
+	}
+	result.RunID = run.RunID
+
+	// 展开 BinPath、Dir、OutputPath 与 Env 中的变量
+	spec = newVarContext(e.base, e.configDir, spec, run).expandSpec(spec)
+
+	// 1. 设置日志，开启 perRunLog 时每次运行写入单独的文件
+	var writer io.WriteCloser
+	var fullLogPath string
+	var err error
+	if spec.Options.PerRunLog {
+		// 先注册清理，保证在日志文件关闭之后执行
+		defer e.pruneRunLogs(spec)
+		writer, fullLogPath, err = e.logManager.SetupRunLogger(spec.JobName, run.RunID, spec.Options)
 	} else {
-		job.runtimeLogPath = fullLogPath
+		writer, fullLogPath, err = e.logManager.SetupLogger(spec.JobName, spec.Options)
+	}
+	if err != nil {
+		e.logger.Error("SetupLogger failed", "err", err)
+	} else if writer != nil {
+		if fullLogPath != "" {
+			job.setRuntimeLogPath(fullLogPath)
+		}
 		defer func() {
 			_ = writer.Close()
 		}()
+		// 运行期间允许立即轮转，在关闭文件之前撤下
+		if r, ok := writer.(logRotator); ok && fullLogPath != "" && !spec.Options.PerRunLog {
+			job.setLogWriter(r)
+			defer job.setLogWriter(nil)
+		}
 	}
 
 	// 2. 构建命令
 	// buildCmdWithCtx 定义在 cmd_build_*.go
-	cmd := buildCmdWithCtx(ctx, job)
+	cmd := buildCmdWithCtx(ctx, spec)
+	e.logger.Info("command", "jobName", spec.JobName, "bin", cmd.Path, "args", strings.Join(cmd.Args[1:], " "))
 
 	// 配置优雅退出 (Go 1.20+)
 	// 当上下文被取消时，尝试先优雅终止进程组。
@@ -56,23 +129,54 @@ func (e *JobExecutor) Execute(ctx context.Context, job *Job, onStart func(int))
 	}
 	cmd.WaitDelay = 1 * time.Second
 
-	if writer != nil {
-		cmd.Stdout = writer
-		cmd.Stderr = writer
+	// 按日志格式分帧，raw 格式下标准输出与标准错误直接写入同一个文件，同时按行转发到 sinks
+	var sinks []lineSink
+	if e.sinks != nil {
+		sinks = e.sinks(spec)
+	}
+	var framer *logFramer
+	var meta, ptyOut io.Writer
+	if writer != nil || len(sinks) > 0 {
+		var w io.Writer
+		if writer != nil {
+			w = writer
+		}
+		framer = newLogFramer(w, spec.Options.LogFormat, run.RunID, e.clock)
+		framer.sinks = sinks
+		cmd.Stdout = framer.Stream(LogStreamOut)
+		cmd.Stderr = framer.Stream(LogStreamErr)
+		ptyOut = cmd.Stdout
+		if spec.Options.PerRunLog && fullLogPath != "" {
+			meta = framer.Stream(LogStreamMeta)
+			writeRunLogHeader(meta, spec.JobName, run, result.StartTime, cmd.Args, cmd.Dir)
+		}
 	}
 
 	// 3. 更新状态（开始）
 	job.SetStartInfo(result.StartTime)
 
-	// 4. 运行
-	if err := cmd.Start(); err != nil {
+	// 4. 运行，PTY 模式下标准输出与标准错误合并为终端输出
+	var term *Terminal
+	if spec.Options.PTY {
+		term = newTerminal()
+		err = term.start(cmd, ptyOut)
+	} else {
+		err = cmd.Start()
+	}
+	if err != nil {
 		// 启动失败
-		result.EndTime = time.Now()
+		result.EndTime = e.clock()
 		result.Duration = result.EndTime.Sub(result.StartTime)
 		result.Error = err
 		result.ExitCode = -1 // 无法获取具体退出码
 
 		job.SetExitInfo(result.EndTime, result.Duration, result.ExitCode)
+		if meta != nil {
+			writeRunLogFooter(meta, result, ExitReasonStartFailed)
+		}
+		ev := jobEvent(EventJobExited, job)
+		ev.RunID, ev.ExitCode, ev.Reason, ev.Message = run.RunID, &result.ExitCode, ExitReasonStartFailed, err.Error()
+		e.publish(ev)
 		return result
 	}
 
@@ -80,12 +184,27 @@ func (e *JobExecutor) Execute(ctx context.Context, job *Job, onStart func(int))
 	if onStart != nil && cmd.Process != nil {
 		onStart(cmd.Process.Pid)
 	}
+	started := jobEvent(EventJobStarted, job)
+	started.RunID, started.Pid = run.RunID, cmd.Process.Pid
+	e.publish(started)
+
+	if term != nil {
+		job.setTerminal(term)
+	}
 
 	// 等待结束
 	err = cmd.Wait()
+	if term != nil {
+		// 读完终端中剩余的输出，后台的后代进程仍占用终端时不再等待
+		term.wait(time.Second)
+		job.setTerminal(nil)
+	}
+	if framer != nil {
+		framer.Flush()
+	}
 
 	// 5. 更新状态（结束）
-	result.EndTime = time.Now()
+	result.EndTime = e.clock()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 	result.Error = err
 
@@ -97,5 +216,24 @@ func (e *JobExecutor) Execute(ctx context.Context, job *Job, onStart func(int))
 
 	job.SetExitInfo(result.EndTime, result.Duration, result.ExitCode)
 
+	exited := jobEvent(EventJobExited, job)
+	exited.RunID, exited.Pid, exited.Duration = run.RunID, started.Pid, result.Duration
+	exited.ExitCode = &result.ExitCode
+	switch {
+	case ctx.Err() != nil:
+		exited.Reason = ExitReasonStopped
+	case err == nil && result.ExitCode == 0:
+		exited.Reason = ExitReasonExited
+	default:
+		exited.Reason = ExitReasonFailed
+	}
+	if err != nil {
+		exited.Message = err.Error()
+	}
+	if meta != nil {
+		writeRunLogFooter(meta, result, exited.Reason)
+	}
+	e.publish(exited)
+
 	return result
 }
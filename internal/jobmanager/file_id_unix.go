@@ -0,0 +1,23 @@
+//go:build !windows
+
+package jobmanager
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// fileID 返回文件的标识，重命名后不变，新建的同名文件不同
+func fileID(f *os.File) (string, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	sys, ok := st.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", errors.New("无法获取文件标识")
+	}
+	return strconv.FormatUint(uint64(sys.Ino), 36), nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package jobmanager
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// fileID 返回文件的标识，重命名后不变，新建的同名文件不同
+func fileID(f *os.File) (string, error) {
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &info); err != nil {
+		return "", err
+	}
+	index := uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow)
+	return strconv.FormatUint(index, 36), nil
+}
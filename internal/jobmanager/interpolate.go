@@ -0,0 +1,247 @@
+package jobmanager
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// 内置变量名
+const (
+	VarHome      = "HOME"
+	VarJobName   = "JOB_NAME"
+	VarJobUUID   = "JOB_UUID"
+	VarConfigDir = "CONFIG_DIR"
+	VarRunID     = "RUN_ID"
+	VarDate      = "DATE" // 触发时间，格式 2006-01-02
+	VarTime      = "TIME" // 触发时间，默认格式 20060102-150405，可写作 ${TIME:<Go 时间格式>}
+)
+
+const defaultTimeLayout = "20060102-150405"
+
+// RunInfo 单次运行的信息，用于变量展开与日志
+type RunInfo struct {
+	RunID    string    `json:"runId"`
+	FireTime time.Time `json:"fireTime"` // 定时任务的触发时间，常驻任务为本次启动时间
+}
+
+// newRunID 生成按时间排序的运行 ID
+func newRunID(t time.Time) string {
+	id := generateUUID()
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return t.Format("20060102-150405") + "-" + id
+}
+
+// varContext 变量展开的上下文。未定义的变量原样保留，交给 shell 处理
+type varContext struct {
+	vars     map[string]string
+	fireTime time.Time
+}
+
+// newVarContext configDir 为空时不定义 ${CONFIG_DIR}
+func newVarContext(base BaseConfig, configDir string, spec JobSpec, run RunInfo) varContext {
+	if run.FireTime.IsZero() {
+		run.FireTime = time.Now()
+	}
+	builtin := map[string]string{
+		VarJobName: spec.JobName,
+		VarJobUUID: spec.UUID,
+		VarRunID:   run.RunID,
+		VarDate:    run.FireTime.Format("2006-01-02"),
+		VarTime:    run.FireTime.Format(defaultTimeLayout),
+	}
+	if home, err := userHomeDirFn(); err == nil {
+		builtin[VarHome] = home
+	}
+	if configDir != "" {
+		builtin[VarConfigDir] = configDir
+	}
+	vc := varContext{vars: builtin, fireTime: run.FireTime}
+	// 自定义变量的值可以引用内置变量，内置变量不可被覆盖
+	all := make(map[string]string, len(builtin)+len(base.Vars))
+	for k, v := range base.Vars {
+		all[k] = vc.expand(v)
+	}
+	for k, v := range builtin {
+		all[k] = v
+	}
+	vc.vars = all
+	return vc
+}
+
+// expand 展开 ${NAME} 与 ${TIME:layout}，$${ 转义为字面量 ${。变量值原样替换，不做 shell 转义
+func (vc varContext) expand(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			sb.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i+2:], '}')
+		if end < 0 {
+			sb.WriteString(s[i:])
+			break
+		}
+		token := s[i : i+2+end+1]
+		if v, ok := vc.lookup(s[i+2 : i+2+end]); ok {
+			sb.WriteString(v)
+		} else {
+			sb.WriteString(token)
+		}
+		i += len(token)
+	}
+	return sb.String()
+}
+
+func (vc varContext) lookup(name string) (string, bool) {
+	if layout, ok := strings.CutPrefix(name, VarTime+":"); ok && layout != "" {
+		return vc.fireTime.Format(layout), true
+	}
+	v, ok := vc.vars[name]
+	return v, ok
+}
+
+// unresolved 返回字符串中未定义的变量名
+func (vc varContext) unresolved(s string) []string {
+	var names []string
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i+2:], '}')
+		if end < 0 {
+			break
+		}
+		name := s[i+2 : i+2+end]
+		if _, ok := vc.lookup(name); !ok {
+			names = append(names, name)
+		}
+		i += 2 + end + 1
+	}
+	return names
+}
+
+// expandSpec 展开任务中支持变量的字段：BinPath、Dir、Options.OutputPath 与 Env 的值
+func (vc varContext) expandSpec(spec JobSpec) JobSpec {
+	spec.BinPath = vc.expand(spec.BinPath)
+	spec.Dir = vc.expand(spec.Dir)
+	spec.Options.OutputPath = vc.expand(spec.Options.OutputPath)
+	if len(spec.Env) > 0 {
+		env := make(map[string]string, len(spec.Env))
+		for k, v := range spec.Env {
+			env[k] = vc.expand(v)
+		}
+		spec.Env = env
+	}
+	return spec
+}
+
+// varContext 使用管理器的全局变量与时钟创建变量上下文
+func (m *Manager) varContext(spec JobSpec, run RunInfo) varContext {
+	if run.FireTime.IsZero() {
+		run.FireTime = m.now()
+	}
+	return newVarContext(m.config.Config, m.configDir(), spec, run)
+}
+
+// expandJobSpec 使用管理器的全局变量展开任务
+func (m *Manager) expandJobSpec(spec JobSpec, run RunInfo) JobSpec {
+	return m.varContext(spec, run).expandSpec(spec)
+}
+
+// sortedEnv 以 KEY=VALUE 形式返回任务环境变量，按键排序
+func sortedEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, k+"="+env[k])
+	}
+	return out
+}
+
+// JobPreview 展开变量后实际执行的命令
+type JobPreview struct {
+	RunInfo
+	Shell      string            `json:"shell"`
+	Args       []string          `json:"args"`
+	BinPath    string            `json:"binPath"`
+	Dir        string            `json:"dir"`
+	OutputPath string            `json:"outputPath"`
+	LogPath    string            `json:"logPath"`
+	Env        map[string]string `json:"env"`
+	Vars       map[string]string `json:"vars"`       // 可用的变量
+	Unresolved []string          `json:"unresolved"` // 未定义、将原样交给 shell 的变量
+}
+
+// PreviewJobSpec 预览任务展开后的命令，fireTime 为空时使用当前时间
+func (m *Manager) PreviewJobSpec(spec JobSpec, fireTime time.Time) JobPreview {
+	return m.previewJobSpec(spec, fireTime, false)
+}
+
+// previewJobSpec redact 为 true 时，自定义变量与环境变量的值以 RedactedValue 展开与展示
+func (m *Manager) previewJobSpec(spec JobSpec, fireTime time.Time, redact bool) JobPreview {
+	if fireTime.IsZero() {
+		fireTime = m.now()
+	}
+	run := RunInfo{RunID: newRunID(fireTime), FireTime: fireTime}
+	base := m.config.Config
+	if redact {
+		base.Vars = redactEnv(base.Vars)
+		spec.Env = redactEnv(spec.Env)
+	}
+	vc := newVarContext(base, m.configDir(), spec, run)
+	expanded := vc.expandSpec(spec)
+	shell, args := commandLine(expanded)
+	p := JobPreview{
+		RunInfo:    run,
+		Shell:      shell,
+		Args:       args,
+		BinPath:    expanded.BinPath,
+		Dir:        expanded.Dir,
+		OutputPath: expanded.Options.OutputPath,
+		Env:        expanded.Env,
+		Vars:       vc.vars,
+		Unresolved: []string{},
+	}
+	if logPath, err := resolveLogPath(m.logDir, expanded.JobName, expanded.Options); err == nil {
+		p.LogPath = logPath
+	}
+	for _, s := range []string{spec.BinPath, spec.Dir, spec.Options.OutputPath} {
+		p.Unresolved = append(p.Unresolved, vc.unresolved(s)...)
+	}
+	return p
+}
+
+// PreviewJob 预览已保存任务展开后的命令，自定义变量与环境变量的值被隐藏
+func (m *Manager) PreviewJob(jobId string) (JobPreview, error) {
+	job := m.getJobByJobId(jobId)
+	if job == nil {
+		return JobPreview{}, newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	return m.previewJobSpec(job.specSnapshot(), time.Time{}, true), nil
+}
+
+// PreviewTask 预览一个待保存任务展开后的命令，自定义变量与环境变量的值被隐藏
+func (m *Manager) PreviewTask(job JobStatusShow) JobPreview {
+	return m.previewJobSpec(job.toJobSpec(), time.Time{}, true)
+}
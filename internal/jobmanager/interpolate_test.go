@@ -0,0 +1,99 @@
+package jobmanager
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVarContextExpand(t *testing.T) {
+	home, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	fire := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	base := BaseConfig{Vars: map[string]string{
+		"APP":      "${HOME}/app",
+		"JOB_NAME": "overridden",
+	}}
+	vc := newVarContext(base, "/etc/rooster", JobSpec{JobName: "api", UUID: "u1"}, RunInfo{RunID: "r1", FireTime: fire})
+
+	cases := map[string]string{
+		"${APP}/bin":            home + "/app/bin",
+		"${JOB_NAME}-${RUN_ID}": "api-r1",
+		"${DATE}":               "2024-01-02",
+		"${TIME}":               "20240102-030405",
+		"${TIME:2006/01}":       "2024/01",
+		"echo $${HOME} ${HOME}": "echo ${HOME} " + home,
+		"${UNKNOWN} $HOME":      "${UNKNOWN} $HOME",
+		"broken ${HOME":         "broken ${HOME",
+		"no variables":          "no variables",
+	}
+	for in, want := range cases {
+		if got := vc.expand(in); got != want {
+			t.Errorf("expand(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := vc.unresolved("${A} $${B} ${HOME} ${C}"); !reflect.DeepEqual(got, []string{"A", "C"}) {
+		t.Errorf("unresolved = %v", got)
+	}
+}
+
+func TestExpandSpecAndValidate(t *testing.T) {
+	home, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	base := BaseConfig{Vars: map[string]string{"WORK": "${HOME}"}}
+	spec := JobSpec{
+		JobName: "api",
+		Type:    JobTypeResident,
+		BinPath: "echo ${JOB_NAME}",
+		Dir:     "${WORK}",
+		Env:     map[string]string{"TARGET": "${HOME}/out"},
+	}
+	expanded := newVarContext(base, "", spec, RunInfo{}).expandSpec(spec)
+	if expanded.Dir != home || expanded.BinPath != "echo api" || expanded.Env["TARGET"] != home+"/out" {
+		t.Fatalf("unexpected expanded spec: %+v", expanded)
+	}
+	if spec.Env["TARGET"] != "${HOME}/out" {
+		t.Fatalf("expandSpec should not modify the original env")
+	}
+	if r := ValidateJobSpec(spec, nil, base); !r.Valid() {
+		t.Fatalf("unexpected errors: %+v", r.Errors)
+	}
+
+	spec.Dir = "${MISSING}/x"
+	if r := ValidateJobSpec(spec, nil, base); !issueFields(r.Errors)["dir"] {
+		t.Fatalf("expected dir error for unresolved variable: %+v", r.Errors)
+	}
+}
+
+func TestExpandSpec_RawSubstitution(t *testing.T) {
+	// 变量值原样替换进命令，不做 shell 转义；需要作为单个参数时应通过 env 传入并引用 "$MSG"
+	base := BaseConfig{Vars: map[string]string{"MSG": "a b; echo $(id) 'x'"}}
+	spec := JobSpec{JobName: "api", BinPath: "echo ${MSG}", Env: map[string]string{"MSG": "${MSG}"}}
+	expanded := newVarContext(base, "", spec, RunInfo{}).expandSpec(spec)
+	if expanded.BinPath != "echo a b; echo $(id) 'x'" {
+		t.Fatalf("unexpected binPath: %q", expanded.BinPath)
+	}
+	if expanded.Env["MSG"] != "a b; echo $(id) 'x'" {
+		t.Fatalf("unexpected env: %q", expanded.Env["MSG"])
+	}
+}
+
+func TestPreviewJobSpec(t *testing.T) {
+	_, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	m := createTestManager()
+	fire := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	p := m.PreviewJobSpec(JobSpec{JobName: "tick", BinPath: "echo ${DATE} ${NOPE}"}, fire)
+	if p.BinPath != "echo 2024-01-02 ${NOPE}" || p.RunID == "" || !p.FireTime.Equal(fire) {
+		t.Fatalf("unexpected preview: %+v", p)
+	}
+	if !reflect.DeepEqual(p.Unresolved, []string{"NOPE"}) {
+		t.Fatalf("unexpected unresolved: %v", p.Unresolved)
+	}
+	if len(p.Args) == 0 || p.Args[len(p.Args)-1] != p.BinPath {
+		t.Fatalf("unexpected args: %v", p.Args)
+	}
+}
@@ -2,7 +2,6 @@ package jobmanager
 
 import (
 	"errors"
-	"log/slog"
 	"os"
 	"sync"
 	"time"
@@ -10,15 +9,19 @@ import (
 
 // JobStatusShow 对外展示的任务状态结构
 type JobStatusShow struct {
-	UUID    string     `json:"uuid"`
-	JobName string     `json:"jobName"`
-	Type    int        `json:"type"` // 运行模式 1 常驻 / 2 定时
-	Run     bool       `json:"run"`
-	BinPath string     `json:"binPath"`
-	Dir     string     `json:"dir"`
-	Spec    string     `json:"spec"`
-	Options RunOptions `json:"options"` // 运行选项
-	Link    string     `json:"link"`    // 快速跳转链接
+	UUID    string            `json:"uuid"`
+	JobName string            `json:"jobName"`
+	Type    int               `json:"type"` // 运行模式 1 常驻 / 2 定时
+	Run     bool              `json:"run"`
+	BinPath string            `json:"binPath"`
+	Dir     string            `json:"dir"`
+	Spec    string            `json:"spec"`
+	Options RunOptions        `json:"options"` // 运行选项
+	Link    string            `json:"link"`    // 快速跳转链接
+	Group   string            `json:"group"`
+	Tags    []string          `json:"tags"`
+	Env     map[string]string `json:"env"`
+	Notify  *NotifyRule       `json:"notify"`
 
 	Status       RunStatus     `json:"status"`
 	LastStart    time.Time     `json:"lastStart"`
@@ -47,6 +50,10 @@ func (job *Job) ToStatusShow() JobStatusShow {
 		Dir:          job.Dir,
 		Spec:         job.Spec,
 		Options:      job.Options,
+		Group:        job.Group,
+		Tags:         append([]string{}, job.Tags...),
+		Env:          job.Env,
+		Notify:       job.Notify,
 		Status:       job.status,
 		LastStart:    job.LastStart,
 		LastExit:     job.LastExit,
@@ -70,6 +77,25 @@ func (job *Job) ToStatusShow() JobStatusShow {
 	return js
 }
 
+// toJobSpec 转换为任务的静态配置
+func (js JobStatusShow) toJobSpec() JobSpec {
+	return JobSpec{
+		UUID:    js.UUID,
+		JobName: js.JobName,
+		Link:    js.Link,
+		Type:    JobType(js.Type),
+		Run:     js.Run,
+		BinPath: js.BinPath,
+		Dir:     js.Dir,
+		Spec:    js.Spec,
+		Options: js.Options,
+		Group:   js.Group,
+		Tags:    js.Tags,
+		Env:     js.Env,
+		Notify:  js.Notify,
+	}
+}
+
 func (m *Manager) JobList() []JobStatusShow {
 	var jobNameList []JobStatusShow
 	for _, job := range m.config.TaskList {
@@ -78,6 +104,15 @@ func (m *Manager) JobList() []JobStatusShow {
 	return jobNameList
 }
 
+// JobSpecs 返回所有任务的配置
+func (m *Manager) JobSpecs() []JobSpec {
+	var specs []JobSpec
+	for _, job := range m.JobList() {
+		specs = append(specs, job.toJobSpec())
+	}
+	return specs
+}
+
 func JobList() []JobStatusShow {
 	if DefaultManager != nil {
 		return DefaultManager.JobList()
@@ -93,9 +128,13 @@ func (m *Manager) JobRunResidentTask(jobId string) error {
 	defer m.flushConfig()
 	jh := m.getJobByJobId(jobId)
 	if jh == nil {
-		return errors.New("jobId不存在")
+		return newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	if err := m.ForceRunJob(jh); err != nil {
+		return err
 	}
-	return m.ForceRunJob(jh)
+	m.emit(jobEvent(EventJobEnabled, jh))
+	return nil
 }
 
 func JobRunResidentTask(jobId string) error {
@@ -108,10 +147,11 @@ func JobRunResidentTask(jobId string) error {
 func (m *Manager) JobStopResidentTask(jobId string) error {
 	jh := m.getJobByJobId(jobId)
 	if jh == nil {
-		return errors.New("jobId不存在")
+		return newJobError(ErrJobNotFound, "jobId不存在")
 	}
 	defer m.flushConfig()
 	m.StopJob(jh)
+	m.emit(jobEvent(EventJobDisabled, jh))
 	return nil
 }
 
@@ -122,28 +162,32 @@ func JobStopResidentTask(jobId string) error {
 	return errors.New("manager not initialized")
 }
 
+// StopAll 停止定时器与所有常驻任务，等待其退出
 func (m *Manager) StopAll() {
 	m.StartClose()
+	// 等待正在运行的定时任务结束，最多等待 5 秒，之后再关闭日志转发
+	select {
+	case <-m.cron.Stop().Done():
+	case <-time.After(5 * time.Second):
+		m.log().Warn("定时任务未在 5 秒内结束，继续退出")
+	}
+	m.stopNotifier()
 	wg := sync.WaitGroup{}
 	for _, item := range m.config.GetResidentTask() {
-		slog.Info(item.JobName + "准备退出")
+		m.log().Info(item.JobName + "准备退出")
 		wg.Go(func(job *Job) func() {
 			return func() {
 				m.StopJob(job)
 
 				// 等待任务彻底退出，最多等待 5 秒
-				for i := 0; i < 50; i++ {
-					if !job.IsRunningLoop() {
-						break
-					}
-					time.Sleep(100 * time.Millisecond)
-				}
-
-				slog.Info(job.JobName + "退出")
+				waitLoopExit(job)
+
+				m.log().Info(job.JobName + "退出")
 			}
 		}(item))
 	}
 	wg.Wait()
+	m.closeSinks()
 }
 
 func StopAll() {
@@ -173,31 +217,28 @@ func (m *Manager) OpenCloseTask(taskId string, run bool) error {
 
 	job := m.getTaskByTaskId(taskId)
 	if job == nil {
-		return errors.New("taskId不存在")
+		return newJobError(ErrJobNotFound, "taskId不存在")
 	}
 
 	defer m.flushConfig()
-	job.Run = run
+	job.setRun(run)
 
-	if job.Run {
+	if run {
 		if job.entityId != 0 {
-			return errors.New("任务已注册")
+			return newJobError(ErrJobEnabled, "任务已注册")
 		}
-		entityId, err := m.cron.AddFunc(job.Spec, func(job *Job) func() {
-			return func() {
-				m.execAction(job)
-			}
-		}(job))
+		entityId, err := m.cron.AddFunc(job.Spec, m.cronFunc(job))
 		if err != nil {
 			return err
 		}
 		job.entityId = entityId
+		m.emit(jobEvent(EventJobEnabled, job))
 	} else {
-		if job.entityId == 0 {
-			return nil
+		if job.entityId != 0 {
+			m.cron.Remove(job.entityId)
+			job.entityId = 0
 		}
-		m.cron.Remove(job.entityId)
-		job.entityId = 0
+		m.emit(jobEvent(EventJobDisabled, job))
 	}
 	return nil
 }
@@ -212,7 +253,7 @@ func OpenCloseTask(taskId string, run bool) error {
 func (m *Manager) RunTask(taskId string) error {
 	task := m.getTaskByTaskId(taskId)
 	if task == nil {
-		return errors.New("taskId不存在")
+		return newJobError(ErrJobNotFound, "taskId不存在")
 	}
 	return m.RunScheduledJob(task)
 }
@@ -224,49 +265,84 @@ func RunTask(taskId string) error {
 	return errors.New("manager not initialized")
 }
 
+// RotateLog 立即轮转任务的日志文件，正在运行的任务之后的输出写入新文件
+func (m *Manager) RotateLog(jobId string) error {
+	job := m.getJobByJobId(jobId)
+	if job == nil {
+		return newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	expanded := m.expandJobSpec(job.JobSpec, RunInfo{})
+	if !expanded.Options.OutputType.WritesFile() {
+		return newJobError(ErrUnsupported, "该任务的输出方式不写入日志文件")
+	}
+	if expanded.Options.PerRunLog {
+		return newJobError(ErrUnsupported, "单次运行日志模式下每次运行都是单独的文件，无需轮转")
+	}
+	if w := job.getLogWriter(); w != nil {
+		return w.Rotate()
+	}
+	lm := &LogManager{logDir: m.logDir, clock: m.now}
+	if err := lm.RotateLog(expanded.JobName, expanded.Options); err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("日志文件不存在")
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateTask 新增任务并返回为其分配的 UUID，job 中的 UUID 被忽略
+func (m *Manager) CreateTask(job JobStatusShow) (string, error) {
+	job.UUID = ""
+	if err := m.ValidateTask(job).Err(); err != nil {
+		return "", err
+	}
+	job.UUID = generateUUID()
+	newJob := Job{
+		JobSpec: job.toJobSpec(),
+	}
+	m.ConfigInit(&newJob)
+	m.config.AddJob(&newJob)
+	m.flushConfig()
+	return job.UUID, nil
+}
+
 func (m *Manager) SaveTask(job JobStatusShow) error {
+	if job.UUID == "" {
+		_, err := m.CreateTask(job)
+		return err
+	}
 	needFlush := false
 	defer func() {
 		if needFlush {
 			m.flushConfig()
 		}
 	}()
-	if job.UUID == "" {
-		job.UUID = generateUUID()
-		newJob := Job{
-			JobSpec: JobSpec{
-				UUID:    job.UUID,
-				JobName: job.JobName,
-				Link:    job.Link,
-				Type:    JobType(job.Type),
-				Run:     job.Run,
-				BinPath: job.BinPath,
-				Dir:     job.Dir,
-				Spec:    job.Spec,
-				Options: job.Options,
-			},
+	if err := m.ValidateTask(job).Err(); err != nil {
+		return err
+	}
+	jobItem := m.config.GetJob(job.UUID)
+	if jobItem != nil {
+		if jobItem.Run {
+			return newJobError(ErrJobEnabled, "任务处于开启状态不允许修改,如需修改请先关闭")
 		}
-		m.ConfigInit(&newJob)
-		m.config.AddJob(&newJob)
-		needFlush = true
-	} else {
-		jobItem := m.config.GetJob(job.UUID)
-		if jobItem != nil {
-			if jobItem.Run {
-				return errors.New("任务处于开启状态不允许修改,如需修改请先关闭")
-			}
-			if jobItem.Type != JobType(job.Type) {
-				return errors.New("任务类型不允许修改")
-			}
-			jobItem.JobName = job.JobName
-			jobItem.Run = job.Run
-			jobItem.BinPath = job.BinPath
-			jobItem.Dir = job.Dir
-			jobItem.Spec = job.Spec
-			jobItem.Options = job.Options
-			jobItem.Link = job.Link
-			needFlush = true
+		if jobItem.Type != JobType(job.Type) {
+			return newJobError(ErrJobTypeImmutable, "任务类型不允许修改")
 		}
+		jobItem.confLock.Lock()
+		jobItem.JobName = job.JobName
+		jobItem.Run = job.Run
+		jobItem.BinPath = job.BinPath
+		jobItem.Dir = job.Dir
+		jobItem.Spec = job.Spec
+		jobItem.Options = restoreSecrets(job.Options, jobItem.Options)
+		jobItem.Link = job.Link
+		jobItem.Group = job.Group
+		jobItem.Tags = job.Tags
+		jobItem.Env = restoreEnv(job.Env, jobItem.Env)
+		jobItem.Notify = job.Notify
+		jobItem.confLock.Unlock()
+		needFlush = true
 	}
 	return nil
 }
@@ -283,11 +359,11 @@ func (m *Manager) RemoveTask(job JobStatusShow) error {
 
 	jobItem := m.config.GetJob(job.UUID)
 	if jobItem == nil {
-		return errors.New("任务不存在或已删除")
+		return newJobError(ErrJobNotFound, "任务不存在或已删除")
 	}
 
 	if jobItem.Run {
-		return errors.New("任务处于开启状态不允许修改,如需修改请先关闭")
+		return newJobError(ErrJobEnabled, "任务处于开启状态不允许修改,如需修改请先关闭")
 	}
 
 	if m.config.RemoveJob(job.UUID) {
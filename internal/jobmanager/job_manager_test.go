@@ -48,7 +48,7 @@ func TestExecActionSetsStatusAndObservables(t *testing.T) {
 		},
 	}
 	m.ConfigInit(&job)
-	m.execAction(&job)
+	m.execAction(&job, job.JobSpec, time.Now())
 	if job.status != Stop {
 		t.Fatalf("status not Stop: %v", job.status)
 	}
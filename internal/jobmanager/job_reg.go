@@ -1,6 +1,7 @@
 package jobmanager
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,10 +10,12 @@ import (
 	"math/rand"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
 
+	"github.com/leancodebox/rooster/internal/logsink"
 	"github.com/robfig/cron/v3"
 )
 
@@ -51,6 +54,33 @@ type Manager struct {
 	configLock     sync.Mutex
 	taskStatusLock sync.Mutex
 	startTime      time.Time
+
+	store  ConfigStore
+	logDir string           // 为空时使用 ~/.roosterTaskConfig/log
+	clock  func() time.Time // 为空时使用 time.Now
+	logger *slog.Logger     // 为空时使用 slog.Default()
+
+	events         *EventBus
+	eventsOnce     sync.Once
+	configSnapshot []byte // 最近一次保存的配置，用于判断配置是否变化
+
+	notifier     *notifier
+	notifierLock sync.Mutex
+
+	headless bool // 没有控制台，任务输出不转发到标准输出
+
+	syslogWriters map[string]*logsink.SyslogWriter // 按网络类型与地址共用的 syslog 连接
+	lokiClients   map[string]*logsink.LokiClient   // 按地址、租户与请求头共用的 Loki 客户端
+	sinkLock      sync.Mutex
+}
+
+// 后续通过 RegByUserConfig 等创建的默认管理器是否为无控制台模式
+var defaultHeadless bool
+
+// SetHeadless 设置无控制台模式（例如托盘程序），任务的输出不再转发到标准输出，
+// 输出方式为标准输出的任务其输出被丢弃。需在 RegByUserConfig 之前调用
+func SetHeadless(headless bool) {
+	defaultHeadless = headless
 }
 
 func NewManager(fileData []byte) (*Manager, error) {
@@ -59,16 +89,23 @@ func NewManager(fileData []byte) (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newManagerWithConfig(config), nil
+}
 
+func newManagerWithConfig(config JobConfig) *Manager {
 	m := &Manager{
 		config:    config,
 		cron:      cron.New(),
 		startTime: time.Now(),
+		headless:  defaultHeadless,
 	}
-	return m, nil
+	m.configSnapshot, _ = json.Marshal(config)
+	return m
 }
 
 func (m *Manager) Start() {
+	m.startNotifier()
+	m.validateJobs()
 	for _, job := range m.config.GetResidentTask() {
 		m.ConfigInit(job)
 
@@ -76,10 +113,29 @@ func (m *Manager) Start() {
 			m.StartResidentJob(job)
 		}
 
-		slog.Info(fmt.Sprintf("%v 加入常驻任务", job.UUID+job.JobName))
+		m.log().Info(fmt.Sprintf("%v 加入常驻任务", job.UUID+job.JobName))
 	}
 
-	go m.scheduleV2(m.config.GetScheduledTask())
+	m.scheduleV2(m.config.GetScheduledTask())
+}
+
+// validateJobs 校验已保存的任务并记录问题。加载时只作为警告，任务照常启动，
+// 保存时才作为错误拒绝，避免升级后已有的任务因新的校验规则停止运行
+func (m *Manager) validateJobs() {
+	base := newValidationResult()
+	validateNotifyConfig(&base, m.config.Config.Notify)
+	for _, e := range base.Errors {
+		m.log().Warn("通知配置错误", "field", e.Field, "msg", e.Message)
+	}
+	for i, job := range m.config.TaskList {
+		r := validateJobSpec(job.JobSpec, m.config.TaskList[:i], m.config.Config, m.configDir())
+		for _, w := range r.Warnings {
+			m.log().Warn("任务配置警告", "jobName", job.JobName, "field", w.Field, "msg", w.Message)
+		}
+		if !r.Valid() {
+			m.log().Warn("任务配置未通过校验，修改时需要先修正", "jobName", job.JobName, "err", r.Err())
+		}
+	}
 }
 
 func RegV2(fileData []byte) {
@@ -92,22 +148,23 @@ func RegV2(fileData []byte) {
 	DefaultManager.Start()
 }
 
+func regConfig(config JobConfig) {
+	DefaultManager = newManagerWithConfig(config)
+	DefaultManager.Start()
+}
+
 func (m *Manager) scheduleV2(jobList []*Job) {
 	for _, job := range jobList {
 		m.ConfigInit(job)
 		if !job.Run {
 			continue
 		}
-		entityId, err := m.cron.AddFunc(job.Spec, func(job *Job) func() {
-			return func() {
-				m.execAction(job)
-			}
-		}(job))
+		entityId, err := m.cron.AddFunc(job.Spec, m.cronFunc(job))
 		if err != nil {
-			slog.Error(err.Error())
+			m.log().Error(err.Error())
 		} else {
 			job.entityId = entityId
-			slog.Info(fmt.Sprintf("%v 加入任务", job.GetJobName()))
+			m.log().Info(fmt.Sprintf("%v 加入任务", job.GetJobName()))
 		}
 	}
 	m.cron.Start()
@@ -137,7 +194,7 @@ func (m *Manager) ConfigInit(itself *Job) {
 	if itself.Options.OutputPath == "" {
 		if def.OutputPath != "" {
 			itself.Options.OutputPath = def.OutputPath
-		} else if ld, err := getLogDir(); err == nil {
+		} else if ld, err := m.defaultLogDir(); err == nil {
 			itself.Options.OutputPath = ld
 		}
 	}
@@ -150,9 +207,41 @@ func (m *Manager) ConfigInit(itself *Job) {
 	if itself.Options.ShellPath == "" {
 		itself.Options.ShellPath = def.ShellPath
 	}
+	if itself.Options.Rotation == "" {
+		itself.Options.Rotation = def.Rotation
+	}
+	if itself.Options.MaxSizeMB == 0 {
+		itself.Options.MaxSizeMB = def.MaxSizeMB
+	}
+	if itself.Options.MaxBackups == 0 {
+		itself.Options.MaxBackups = def.MaxBackups
+	}
+	if itself.Options.MaxAgeDays == 0 {
+		itself.Options.MaxAgeDays = def.MaxAgeDays
+	}
+	if itself.Options.LogFormat == "" {
+		itself.Options.LogFormat = def.LogFormat
+	}
+	if itself.Options.Concurrency == "" {
+		itself.Options.Concurrency = def.Concurrency
+	}
+	if itself.Options.Compress == nil && def.Compress != nil {
+		compress := *def.Compress
+		itself.Options.Compress = &compress
+	}
 
 	// Initialize runtime log path
-	if path, err := ResolveLogPath(itself.JobName, itself.Options); err == nil {
+	expanded := m.expandJobSpec(itself.JobSpec, RunInfo{})
+	if !expanded.Options.OutputType.WritesFile() {
+		itself.runtimeLogPath = ""
+	} else if expanded.Options.PerRunLog {
+		// 单次运行日志模式下指向最近一次运行的日志
+		if dir, err := runLogDir(m.logDir, expanded.JobName, expanded.Options); err == nil {
+			if logs, err := listRunLogs(dir); err == nil && len(logs) > 0 {
+				itself.runtimeLogPath = logs[0].Path
+			}
+		}
+	} else if path, err := resolveLogPath(m.logDir, expanded.JobName, expanded.Options); err == nil {
 		itself.runtimeLogPath = path
 	}
 }
@@ -165,20 +254,21 @@ func (itself *Job) GetJobName() string {
 func (m *Manager) runResidentJobLoop(job *Job) {
 	defer func() {
 		if err := recover(); err != nil {
-			slog.Error("jobGuard", "err", err)
+			m.log().Error("jobGuard", "err", err)
 		}
 	}()
 
-	executor := NewJobExecutor()
+	executor := m.newExecutor()
 
 	counter := 1
 	consecutiveFailures := 0
 	for {
-		if !job.Run {
-			slog.Info(fmt.Sprintf("%v : no Run ", job.JobName))
+		spec := job.specSnapshot()
+		if !spec.Run {
+			m.log().Info(fmt.Sprintf("%v : no Run ", spec.JobName))
 			return
 		}
-		unitStartTime := time.Now()
+		unitStartTime := m.now()
 		counter += 1
 
 		// 准备取消上下文 (Phase 3 迁移)
@@ -187,7 +277,7 @@ func (m *Manager) runResidentJobLoop(job *Job) {
 		job.SetCancel(cancel)
 
 		// 执行任务
-		result := executor.Execute(ctx, job, func(pid int) {
+		result := executor.Execute(ctx, job, spec, RunInfo{FireTime: unitStartTime}, func(pid int) {
 			job.SetPid(pid)
 			m.flushConfig()
 		})
@@ -199,9 +289,9 @@ func (m *Manager) runResidentJobLoop(job *Job) {
 		// 基于结果的重试/退避逻辑
 		cmdErr := result.Error
 
-		executionTime := time.Since(unitStartTime)
+		executionTime := m.now().Sub(unitStartTime)
 		if cmdErr != nil {
-			slog.Info(cmdErr.Error(), "jobName", job.JobName)
+			m.log().Info(cmdErr.Error(), "jobName", job.JobName)
 		}
 		threshold := maxExecutionTime
 		if job.Options.MinRunSeconds > 0 {
@@ -215,7 +305,7 @@ func (m *Manager) runResidentJobLoop(job *Job) {
 
 		if !job.Run || m.Closed() {
 			msg := job.JobName + " 溜了溜了"
-			slog.Info(msg)
+			m.log().Info(msg)
 			break
 		}
 
@@ -225,13 +315,16 @@ func (m *Manager) runResidentJobLoop(job *Job) {
 		}
 		if consecutiveFailures >= failLimit {
 			msg := job.JobName + "程序连续3次启动失败，停止重启"
-			slog.Info(msg)
+			m.log().Info(msg)
 			job.Run = false
+			e := jobEvent(EventJobGaveUp, job)
+			e.Message = fmt.Sprintf("连续 %v 次运行不足 %v", consecutiveFailures, threshold)
+			m.emit(e)
 			m.flushConfig()
 			break
 		} else {
 			msg := job.JobName + "程序终止尝试重新运行"
-			slog.Info(msg)
+			m.log().Info(msg)
 			var maxDelay = 16
 			calculatedDelay := 1 << uint(consecutiveFailures)
 			if calculatedDelay > maxDelay {
@@ -242,7 +335,10 @@ func (m *Manager) runResidentJobLoop(job *Job) {
 			if actualDelay > maxDelay {
 				actualDelay = maxDelay
 			}
-			sleepFn(time.Duration(actualDelay) * time.Second)
+			e := jobEvent(EventJobRestarting, job)
+			e.Delay = time.Duration(actualDelay) * time.Second
+			m.emit(e)
+			sleepFn(e.Delay)
 		}
 	}
 }
@@ -280,7 +376,7 @@ func StopJob(job *Job) {
 }
 
 func (m *Manager) GetRunTime() time.Duration {
-	return time.Since(m.startTime)
+	return m.now().Sub(m.startTime)
 }
 
 func GetRunTime() time.Duration {
@@ -304,6 +400,15 @@ func GetStartTime() time.Time {
 var userHomeDirFn = os.UserHomeDir
 
 func getConfigPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return findConfigFile(configDir), nil
+}
+
+// getConfigDir 返回配置目录 ~/.roosterTaskConfig，不存在时创建
+func getConfigDir() (string, error) {
 	homeDir, err := userHomeDirFn()
 	if err != nil {
 		slog.Error("获取家目录失败", "err", err)
@@ -313,15 +418,30 @@ func getConfigPath() (string, error) {
 		homeDir = devPath
 	}
 	configDir := path.Join(homeDir, ".roosterTaskConfig")
-	slog.Info("当前目录", "homeDir", homeDir)
 	if _, err = os.Stat(configDir); os.IsNotExist(err) {
 		err = os.MkdirAll(configDir, os.ModePerm)
 		if err != nil {
 			return "", err
 		}
 	}
-	jobConfigPath := path.Join(configDir, "jobConfig.json")
-	return jobConfigPath, nil
+	return configDir, nil
+}
+
+// findConfigFile 在配置目录中按优先级查找已存在的配置文件，都不存在时使用 jobConfig.json
+func findConfigFile(configDir string) string {
+	var found []string
+	for _, name := range configFileNames {
+		if fileExists(path.Join(configDir, name)) {
+			found = append(found, path.Join(configDir, name))
+		}
+	}
+	if len(found) == 0 {
+		return path.Join(configDir, configFileNames[0])
+	}
+	if len(found) > 1 {
+		slog.Warn("存在多个配置文件，仅使用第一个", "use", found[0], "ignored", found[1:])
+	}
+	return found[0]
 }
 
 func GetLogDir() (string, error) {
@@ -329,15 +449,10 @@ func GetLogDir() (string, error) {
 }
 
 func getLogDir() (string, error) {
-	homeDir, err := userHomeDirFn()
+	configDir, err := getConfigDir()
 	if err != nil {
-		slog.Error("获取家目录失败", "err", err)
-		homeDir = "tmp"
-	}
-	if devPath := getDevHomeDir(); devPath != "" {
-		homeDir = devPath
+		return "", err
 	}
-	configDir := path.Join(homeDir, ".roosterTaskConfig")
 	logDir := path.Join(configDir, "log")
 	if _, err = os.Stat(logDir); os.IsNotExist(err) {
 		if err = os.MkdirAll(logDir, os.ModePerm); err != nil {
@@ -352,44 +467,52 @@ func RegByUserConfig() error {
 	if err != nil {
 		return err
 	}
-	fileData, err := os.ReadFile(jobConfigPath)
-	if err != nil || len(fileData) == 0 {
-		def := generateDefaultJobConfig()
-		b, _ := json.MarshalIndent(def, "", "  ")
-		_ = os.WriteFile(jobConfigPath, b, 0644)
-		RegV2(b)
-		return nil
-	}
-	var tmp JobConfig
-	if json.Unmarshal(fileData, &tmp) != nil {
-		def := generateDefaultJobConfig()
-		b, _ := json.MarshalIndent(def, "", "  ")
-		_ = os.WriteFile(jobConfigPath, b, 0644)
-		RegV2(b)
-		return nil
+	slog.Info("当前目录", "configDir", filepath.Dir(jobConfigPath))
+	store := &FileStore{Path: jobConfigPath}
+	// 配置文件存在但为空或无法解析时（例如写入中途崩溃），不能用默认配置覆盖，
+	// FileStore 只会尝试从历史版本恢复
+	config, err := store.Load()
+	if os.IsNotExist(err) {
+		config = generateDefaultJobConfig()
+		if err = store.Save(config); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
 	}
-	RegV2(fileData)
+	regConfig(config)
 	return nil
 }
 
 func (m *Manager) flushConfig() {
-	jobConfigPath, err := getConfigPath()
-	slog.Error("flushConfigErr", "err", err)
-	if err != nil {
-		slog.Error("flushConfigErr", "err", err)
-		return
-	}
 	m.configLock.Lock()
 	defer m.configLock.Unlock()
-	data, err := json.MarshalIndent(m.config, "", "  ")
-	if err != nil {
-		slog.Error("flushConfigErr", "err", err)
+	if err := m.configStore().Save(m.config); err != nil {
+		m.log().Error("flushConfigErr", "err", err)
 		return
 	}
-	err = os.WriteFile(jobConfigPath, data, 0644)
-	if err != nil {
-		slog.Error("flushConfigErr", "err", err)
-		return
+	if data, err := json.Marshal(m.config); err == nil && !bytes.Equal(data, m.configSnapshot) {
+		m.configSnapshot = data
+		m.emit(Event{Type: EventConfigChanged})
+	}
+}
+
+// cronFunc 返回定时触发时执行的函数。并发策略为 forbid 时，上次运行尚未结束则跳过本次触发
+func (m *Manager) cronFunc(job *Job) func() {
+	return func() {
+		fireTime := m.now()
+		spec := job.specSnapshot()
+		if spec.Options.Concurrency == ConcurrencyForbid {
+			if !job.runOnceLock.TryLock() {
+				e := jobEvent(EventCronSkipped, job)
+				e.Message = "上次运行尚未结束"
+				m.emit(e)
+				return
+			}
+			defer job.runOnceLock.Unlock()
+		}
+		m.emit(jobEvent(EventCronFired, job))
+		m.execAction(job, spec, fireTime)
 	}
 }
 
@@ -398,13 +521,14 @@ func (m *Manager) flushConfig() {
 // RunScheduledJob 运行一次定时任务（手动触发或定时触发）
 func (m *Manager) RunScheduledJob(job *Job) error {
 	if job.runOnceLock.TryLock() {
+		spec := job.specSnapshot()
 		go func(j *Job) {
 			defer j.runOnceLock.Unlock()
-			m.execAction(j)
+			m.execAction(j, spec, m.now())
 		}(job)
 		return nil
 	}
-	return errors.New("上次手动运行尚未结束")
+	return newJobError(ErrJobBusy, "上次手动运行尚未结束")
 }
 
 func RunScheduledJob(job *Job) error {
@@ -414,8 +538,8 @@ func RunScheduledJob(job *Job) error {
 	return errors.New("manager not initialized")
 }
 
-// execAction 执行一次性任务并记录观测值
-func (m *Manager) execAction(job *Job) {
+// execAction 以配置副本 spec 执行一次性任务并记录观测值，fireTime 为本次触发时间
+func (m *Manager) execAction(job *Job, spec JobSpec, fireTime time.Time) {
 	ctx, cancel := context.WithCancel(context.Background())
 	job.SetCancel(cancel)
 	defer func() {
@@ -423,11 +547,11 @@ func (m *Manager) execAction(job *Job) {
 		cancel()
 	}()
 
-	executor := NewJobExecutor()
-	result := executor.Execute(ctx, job, nil)
+	executor := m.newExecutor()
+	result := executor.Execute(ctx, job, spec, RunInfo{FireTime: fireTime}, nil)
 
 	if result.Error != nil {
-		slog.Info(result.Error.Error())
+		m.log().Info(result.Error.Error())
 	}
 }
 
@@ -437,7 +561,7 @@ func (m *Manager) StartResidentJob(job *Job) error {
 	// 如果已经在运行中，则不重复启动
 	if job.RunningLoop {
 		job.confLock.Unlock()
-		return errors.New("程序运行中")
+		return newJobError(ErrJobBusy, "程序运行中")
 	}
 	job.Run = true
 	job.RunningLoop = true
@@ -491,8 +615,6 @@ func generateDefaultJobConfig() JobConfig {
 	}
 	return JobConfig{
 		TaskList: []*Job{resident, scheduled},
-		Config: BaseConfig{Dashboard: struct {
-			Port int `json:"port"`
-		}{Port: 9090}, DefaultOptions: RunOptions{OutputType: OutputTypeFile, OutputPath: logDir, MaxFailures: 5}},
+		Config:   BaseConfig{Dashboard: DashboardConfig{Port: 9090}, DefaultOptions: RunOptions{OutputType: OutputTypeFile, OutputPath: logDir, MaxFailures: 5}},
 	}
 }
@@ -30,19 +30,53 @@ const maxConsecutiveFailures = 3
 type OutputType int
 
 const (
-	OutputTypeStd  OutputType = iota + 1 // 输出到标准输入输出
-	OutputTypeFile                       // 输出到文件
+	OutputTypeStd     OutputType = iota + 1 // 输出到标准输出，每行前加任务名
+	OutputTypeFile                          // 输出到文件
+	OutputTypeBoth                          // 同时输出到文件与标准输出
+	OutputTypeDiscard                       // 丢弃输出
 )
 
+// WritesFile 是否写入日志文件，未设置时按文件处理
+func (t OutputType) WritesFile() bool {
+	return t == 0 || t == OutputTypeFile || t == OutputTypeBoth
+}
+
+// WritesStdout 是否转发到标准输出
+func (t OutputType) WritesStdout() bool {
+	return t == OutputTypeStd || t == OutputTypeBoth
+}
+
 // RunOptions 定义任务的运行选项
 type RunOptions struct {
-	OutputType    OutputType `json:"outputType"`  // 输出方式
-	OutputPath    string     `json:"outputPath"`  // 输出路径
-	MaxFailures   int        `json:"maxFailures"` // 最大失败次数
-	ShellPath     string     `json:"shellPath"`
-	MinRunSeconds int        `json:"minRunSeconds"`
+	OutputType    OutputType    `json:"outputType"`  // 输出方式
+	OutputPath    string        `json:"outputPath"`  // 输出路径
+	MaxFailures   int           `json:"maxFailures"` // 最大失败次数
+	ShellPath     string        `json:"shellPath"`
+	MinRunSeconds int           `json:"minRunSeconds"`
+	PerRunLog     bool          `json:"perRunLog,omitempty"`   // 每次运行写入单独的日志 <输出路径>/<任务名>/<运行ID>.log
+	KeepRuns      int           `json:"keepRuns,omitempty"`    // 保留的运行日志数量，默认 50
+	KeepDays      int           `json:"keepDays,omitempty"`    // 运行日志保留天数，0 表示不按天数清理
+	Rotation      string        `json:"rotation,omitempty"`    // 日志轮转方式 size / daily，为空时使用默认选项
+	MaxSizeMB     int           `json:"maxSizeMB,omitempty"`   // 单个日志文件达到该大小（MB）时轮转，默认 10
+	MaxBackups    int           `json:"maxBackups,omitempty"`  // 保留的轮转文件数量，默认 3，-1 表示不限制
+	MaxAgeDays    int           `json:"maxAgeDays,omitempty"`  // 轮转文件保留天数，默认 28，-1 表示不限制
+	Compress      *bool         `json:"compress,omitempty"`    // 是否压缩轮转文件，默认压缩
+	LogFormat     string        `json:"logFormat,omitempty"`   // 日志格式 raw / text / json，默认 raw
+	Concurrency   string        `json:"concurrency,omitempty"` // 定时触发时上次运行尚未结束的处理方式 allow / forbid，默认 allow
+	Syslog        *SyslogConfig `json:"syslog,omitempty"`      // 转发到 syslog，未设置的字段使用默认选项
+	Loki          *LokiConfig   `json:"loki,omitempty"`        // 推送到 Loki，未设置的字段使用默认选项
+	PTY           bool          `json:"pty,omitempty"`         // 在 PTY 中运行，可以通过终端连接输入命令，只用于常驻任务
 }
 
+// 日志轮转方式
+const (
+	RotationSize  = "size"  // 只按大小轮转
+	RotationDaily = "daily" // 每天轮转一次，同时按大小轮转
+
+	ConcurrencyAllow  = "allow"  // 照常运行，与上次运行重叠
+	ConcurrencyForbid = "forbid" // 跳过本次触发并发布 cron.skipped 事件
+)
+
 // JobType 表示任务类型（常驻或定时）
 type JobType int
 
@@ -53,15 +87,19 @@ const (
 
 // JobSpec 定义任务的静态配置
 type JobSpec struct {
-	UUID    string     `json:"uuid"`
-	JobName string     `json:"jobName"`
-	Link    string     `json:"link"`
-	Type    JobType    `json:"type"` // 运行模式 1 常驻 / 2 定时
-	Run     bool       `json:"run"`
-	BinPath string     `json:"binPath"`
-	Dir     string     `json:"dir"`
-	Spec    string     `json:"spec"`
-	Options RunOptions `json:"options"` // 运行选项
+	UUID    string            `json:"uuid"`
+	JobName string            `json:"jobName"`
+	Link    string            `json:"link"`
+	Type    JobType           `json:"type"` // 运行模式 1 常驻 / 2 定时
+	Run     bool              `json:"run"`
+	BinPath string            `json:"binPath"`
+	Dir     string            `json:"dir"`
+	Spec    string            `json:"spec"`
+	Options RunOptions        `json:"options"`          // 运行选项
+	Group   string            `json:"group"`            // 分组，例如所属项目
+	Tags    []string          `json:"tags"`             // 标签，用于筛选与批量操作
+	Env     map[string]string `json:"env"`              // 附加环境变量，值支持变量展开
+	Notify  *NotifyRule       `json:"notify,omitempty"` // 通知规则，为空时使用全局默认规则
 }
 
 // RunStatus 运行状态
@@ -90,6 +128,8 @@ type JobRuntime struct {
 	LastDuration time.Duration `json:"-"`
 
 	runtimeLogPath string
+	logWriter      logRotator // 正在运行时的日志写入器，用于立即轮转
+	terminal       *Terminal  // PTY 模式下正在运行时的终端
 }
 
 // Job 表示任务及其运行时状态
@@ -108,6 +148,57 @@ func (j *Job) SetStartInfo(startTime time.Time) {
 	j.status = Running
 }
 
+// specSnapshot 返回任务配置的副本，运行期间使用副本，不受并发修改影响
+func (j *Job) specSnapshot() JobSpec {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	return j.JobSpec
+}
+
+// setRun 设置任务的开启状态
+func (j *Job) setRun(run bool) {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	j.Run = run
+}
+
+// setRuntimeLogPath 记录当前运行写入的日志文件
+func (j *Job) setRuntimeLogPath(path string) {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	j.runtimeLogPath = path
+}
+
+func (j *Job) getRuntimeLogPath() string {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	return j.runtimeLogPath
+}
+
+func (j *Job) setLogWriter(w logRotator) {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	j.logWriter = w
+}
+
+func (j *Job) getLogWriter() logRotator {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	return j.logWriter
+}
+
+func (j *Job) setTerminal(t *Terminal) {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	j.terminal = t
+}
+
+func (j *Job) getTerminal() *Terminal {
+	j.confLock.Lock()
+	defer j.confLock.Unlock()
+	return j.terminal
+}
+
 // SetExitInfo 记录任务退出状态
 func (j *Job) SetExitInfo(endTime time.Time, duration time.Duration, exitCode int) {
 	j.confLock.Lock()
@@ -146,12 +237,24 @@ func (j *Job) SetRunningLoop(running bool) {
 	j.RunningLoop = running
 }
 
+// DashboardConfig dashboard 与 HTTP API 的配置
+type DashboardConfig struct {
+	Port           int      `json:"port"`
+	Bind           string   `json:"bind,omitempty"`               // 监听地址，默认 127.0.0.1，0.0.0.0 监听所有网卡
+	StrictPort     bool     `json:"strictPort,omitempty"`         // 端口被占用时启动失败，而不是依次尝试后续端口
+	TLS            bool     `json:"tls,omitempty"`                // 以 HTTPS 提供服务
+	TLSCert        string   `json:"tlsCert,omitempty"`            // 证书路径，与 tlsKey 都为空时使用配置目录中自动生成的自签名证书
+	TLSKey         string   `json:"tlsKey,omitempty"`             // 私钥路径
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`     // 允许跨域访问 API 的来源，如 https://ops.example.com
+	AuditRetention int      `json:"auditRetentionDays,omitempty"` // 审计日志保留天数，默认 90，小于 0 时不删除
+}
+
 // BaseConfig 为全局配置
 type BaseConfig struct {
-	Dashboard struct {
-		Port int `json:"port"`
-	} `json:"dashboard"`
-	DefaultOptions RunOptions `json:"defaultOptions"` // 运行选项
+	Dashboard      DashboardConfig   `json:"dashboard"`
+	DefaultOptions RunOptions        `json:"defaultOptions"`   // 运行选项
+	Vars           map[string]string `json:"vars"`             // 自定义变量，可在 binPath、dir、outputPath、env 中以 ${NAME} 引用
+	Notify         *NotifyConfig     `json:"notify,omitempty"` // 通知渠道与默认规则
 }
 
 // JobConfig 是任务列表与配置的组合
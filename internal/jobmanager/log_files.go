@@ -0,0 +1,222 @@
+package jobmanager
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lumberjack 轮转文件名中的时间格式，例如 job_log-2024-01-02T15-04-05.000.txt
+const lumberjackBackupTimeFormat = "2006-01-02T15-04-05.000"
+
+// LogFile 任务的一个日志文件
+type LogFile struct {
+	Name       string    `json:"name"` // 文件名，在同一任务内唯一
+	Path       string    `json:"-"`
+	Size       int64     `json:"size"` // 文件大小，压缩文件为压缩后的大小
+	ModTime    time.Time `json:"modTime"`
+	Current    bool      `json:"current"`    // 正在写入的日志文件
+	Compressed bool      `json:"compressed"` // gzip 压缩的轮转文件
+}
+
+// Open 打开日志文件，压缩文件返回解压后的内容
+func (f LogFile) Open() (io.ReadCloser, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Compressed {
+		return file, nil
+	}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &gzipFile{Reader: zr, file: file}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	return errors.Join(g.Reader.Close(), g.file.Close())
+}
+
+// listLogFiles 返回日志文件与 lumberjack 的轮转文件，当前文件在前，轮转文件按时间倒序
+func listLogFiles(logPath string) ([]LogFile, error) {
+	dir := filepath.Dir(logPath)
+	base := filepath.Base(logPath)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []LogFile{}, nil
+		}
+		return nil, err
+	}
+	files := []LogFile{}
+	rotated := map[string]time.Time{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		lf := LogFile{Name: name, Path: filepath.Join(dir, name)}
+		switch {
+		case name == base:
+			lf.Current = true
+		case strings.HasPrefix(name, prefix):
+			stamp := strings.TrimPrefix(name, prefix)
+			if strings.HasSuffix(stamp, ".gz") {
+				lf.Compressed = true
+				stamp = strings.TrimSuffix(stamp, ".gz")
+			}
+			if !strings.HasSuffix(stamp, ext) {
+				continue
+			}
+			t, err := time.Parse(lumberjackBackupTimeFormat, strings.TrimSuffix(stamp, ext))
+			if err != nil {
+				continue
+			}
+			rotated[name] = t
+		default:
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		lf.Size, lf.ModTime = info.Size(), info.ModTime()
+		files = append(files, lf)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Current != files[j].Current {
+			return files[i].Current
+		}
+		return rotated[files[i].Name].After(rotated[files[j].Name])
+	})
+	return files, nil
+}
+
+// jobLogFiles 任务的全部日志文件，最新的在前。单次运行日志模式下为各次运行的日志
+func (m *Manager) jobLogFiles(jobId string) ([]LogFile, error) {
+	job := m.getJobByJobId(jobId)
+	if job == nil {
+		return nil, newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	expanded := m.expandJobSpec(job.JobSpec, RunInfo{})
+	if expanded.Options.PerRunLog {
+		dir, err := runLogDir(m.logDir, expanded.JobName, expanded.Options)
+		if err != nil {
+			return nil, err
+		}
+		runs, err := listRunLogs(dir)
+		if err != nil {
+			return nil, err
+		}
+		files := make([]LogFile, 0, len(runs))
+		for i, r := range runs {
+			files = append(files, LogFile{Name: filepath.Base(r.Path), Path: r.Path, Size: r.Size, ModTime: r.ModTime, Current: i == 0})
+		}
+		return files, nil
+	}
+	logPath, err := resolveLogPath(m.logDir, expanded.JobName, expanded.Options)
+	if err != nil {
+		return nil, err
+	}
+	return listLogFiles(logPath)
+}
+
+// 分页读取日志的默认与最大长度
+const (
+	defaultLogReadLength = 64 * 1024
+	maxLogReadLength     = 1024 * 1024
+)
+
+// LogChunk 日志文件中的一段内容
+type LogChunk struct {
+	File       string `json:"file"`
+	Offset     int64  `json:"offset"`     // 本段在（解压后）文件中的起始位置
+	NextOffset int64  `json:"nextOffset"` // 下一段的起始位置
+	EOF        bool   `json:"eof"`        // 已读到文件末尾
+	Data       string `json:"data"`
+}
+
+// LogFiles 列出任务的全部日志文件，包括 lumberjack 的轮转文件，最新的在前
+func (m *Manager) LogFiles(jobId string) ([]LogFile, error) {
+	return m.jobLogFiles(jobId)
+}
+
+// LogFile 按文件名查找任务的日志文件，只能访问 LogFiles 列出的文件。name 为空时返回最新的文件
+func (m *Manager) LogFile(jobId, name string) (LogFile, error) {
+	files, err := m.jobLogFiles(jobId)
+	if err != nil {
+		return LogFile{}, err
+	}
+	for _, f := range files {
+		if f.Name == name || name == "" {
+			return f, nil
+		}
+	}
+	return LogFile{}, fmt.Errorf("日志文件不存在: %v", name)
+}
+
+// ReadLogFile 从 offset 开始读取最多 length 字节，压缩文件按解压后的内容计算位置。
+// offset 为负数时从文件末尾倒数，只支持未压缩的文件
+func (m *Manager) ReadLogFile(jobId, name string, offset int64, length int) (LogChunk, error) {
+	f, err := m.LogFile(jobId, name)
+	if err != nil {
+		return LogChunk{}, err
+	}
+	if length <= 0 {
+		length = defaultLogReadLength
+	}
+	length = min(length, maxLogReadLength)
+	r, err := f.Open()
+	if err != nil {
+		return LogChunk{}, err
+	}
+	defer r.Close()
+
+	if offset < 0 {
+		if f.Compressed {
+			return LogChunk{}, errors.New("压缩文件不支持从末尾读取")
+		}
+		// 文件可能仍在写入，以打开时的大小为准
+		st, err := r.(*os.File).Stat()
+		if err != nil {
+			return LogChunk{}, err
+		}
+		offset = max(st.Size()+offset, 0)
+	}
+	if seeker, ok := r.(io.Seeker); ok {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return LogChunk{}, err
+		}
+	} else if _, err := io.CopyN(io.Discard, r, offset); err != nil && !errors.Is(err, io.EOF) {
+		return LogChunk{}, err
+	}
+
+	// 多读一个字节判断是否到达末尾
+	buf := make([]byte, length+1)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return LogChunk{}, err
+	}
+	chunk := LogChunk{File: f.Name, Offset: offset, EOF: n <= length}
+	n = min(n, length)
+	chunk.NextOffset = offset + int64(n)
+	chunk.Data = string(buf[:n])
+	return chunk, nil
+}
@@ -0,0 +1,41 @@
+package jobmanager
+
+import (
+	"testing"
+)
+
+func TestReadLogFile(t *testing.T) {
+	m, _, jobId := newSearchManager(t)
+
+	files, err := m.LogFiles(jobId)
+	if err != nil || len(files) != 3 || files[0].Name != "api_log.txt" || files[1].Name != "api_log-2024-01-02T00-00-00.000.txt" {
+		t.Fatalf("unexpected files: %+v %v", files, err)
+	}
+
+	chunk, err := m.ReadLogFile(jobId, "", 0, 4)
+	if err != nil || chunk.Data != "ok\ne" || chunk.NextOffset != 4 || chunk.EOF {
+		t.Fatalf("unexpected first page: %+v %v", chunk, err)
+	}
+	chunk, err = m.ReadLogFile(jobId, "api_log.txt", chunk.NextOffset, 100)
+	if err != nil || chunk.Data != "rror new 1\r\nerror new 2" || !chunk.EOF {
+		t.Fatalf("unexpected second page: %+v %v", chunk, err)
+	}
+	chunk, err = m.ReadLogFile(jobId, "api_log.txt", -11, 0)
+	if err != nil || chunk.Data != "error new 2" || chunk.Offset != 16 {
+		t.Fatalf("unexpected tail: %+v %v", chunk, err)
+	}
+
+	gz := "api_log-2024-01-01T00-00-00.000.txt.gz"
+	chunk, err = m.ReadLogFile(jobId, gz, 6, 6)
+	if err != nil || chunk.Data != "oldest" || chunk.EOF {
+		t.Fatalf("unexpected compressed page: %+v %v", chunk, err)
+	}
+	if _, err := m.ReadLogFile(jobId, gz, -3, 0); err == nil {
+		t.Fatalf("tail of a compressed file should be rejected")
+	}
+	for _, name := range []string{"../api_log.txt", "api-v2_log.txt", "api_log-notes.txt"} {
+		if _, err := m.ReadLogFile(jobId, name, 0, 0); err == nil {
+			t.Fatalf("expected error for %q", name)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package jobmanager
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LogCursor 日志流中的位置，由文件标识与偏移组成，轮转后仍然有效
+type LogCursor struct {
+	Gen    string // 文件标识，轮转（重命名）后不变
+	Offset int64
+}
+
+// String 格式为 <文件标识>:<偏移>，用作 SSE 事件 ID
+func (c LogCursor) String() string {
+	return c.Gen + ":" + strconv.FormatInt(c.Offset, 10)
+}
+
+// ParseLogCursor 解析 LogCursor.String 的结果
+func ParseLogCursor(s string) (LogCursor, bool) {
+	gen, off, ok := strings.Cut(s, ":")
+	if !ok || gen == "" {
+		return LogCursor{}, false
+	}
+	offset, err := strconv.ParseInt(off, 10, 64)
+	if err != nil || offset < 0 {
+		return LogCursor{}, false
+	}
+	return LogCursor{Gen: gen, Offset: offset}, true
+}
+
+// 检查 copy-truncate 时比对的已读内容长度
+const followCheckSize = 64
+
+// LogFollower 持续读取一个日志文件的新内容。文件被轮转（重命名）后先读完旧文件再切换到同名的新文件；
+// 文件被截断（copy-truncate）后从头读取，即使截断后又写入了超过原位置的内容
+type LogFollower struct {
+	path    string
+	f       *os.File
+	gen     string
+	offset  int64
+	check   []byte // 当前位置之前的最后一段内容，用于发现截断后重新写入
+	rotated bool   // 旧文件已被轮转，读完后切换
+	truncs  int    // 发现截断的次数
+}
+
+// FollowLog 打开日志文件。from 不为空时从该位置继续：文件已被轮转时先从未压缩的轮转文件中读取剩余内容，
+// 找不到时从当前文件开头读取。from 为空时从末尾倒数 tail 字节开始
+func FollowLog(path string, from *LogCursor, tail int64) (*LogFollower, error) {
+	l := &LogFollower{path: path}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	size, err := l.size()
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	switch {
+	case from == nil:
+		l.offset = max(size-max(tail, 0), 0)
+	case from.Gen == l.gen:
+		if from.Offset <= size {
+			l.offset = from.Offset
+		}
+	default:
+		if old := findRotatedLog(path, from.Gen); old != nil {
+			_ = l.f.Close()
+			l.f, l.gen, l.offset, l.rotated = old, from.Gen, from.Offset, true
+		}
+	}
+	l.remember()
+	return l, nil
+}
+
+// findRotatedLog 在轮转文件中查找标识为 gen 的文件，压缩后的文件标识已改变，无法找到
+func findRotatedLog(path, gen string) *os.File {
+	files, err := listLogFiles(path)
+	if err != nil {
+		return nil
+	}
+	for _, lf := range files {
+		if lf.Current || lf.Compressed {
+			continue
+		}
+		f, err := os.Open(lf.Path)
+		if err != nil {
+			continue
+		}
+		if id, err := fileID(f); err == nil && id == gen {
+			return f
+		}
+		_ = f.Close()
+	}
+	return nil
+}
+
+func (l *LogFollower) open() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	gen, err := fileID(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.f, l.gen, l.offset, l.check = f, gen, 0, nil
+	return nil
+}
+
+func (l *LogFollower) size() (int64, error) {
+	st, err := l.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
+
+// remember 记录当前位置之前的内容
+func (l *LogFollower) remember() {
+	n := min(l.offset, followCheckSize)
+	l.check = l.check[:0]
+	if n == 0 {
+		return
+	}
+	buf := make([]byte, n)
+	if _, err := l.f.ReadAt(buf, l.offset-n); err == nil {
+		l.check = buf
+	}
+}
+
+// truncated 文件变短，或当前位置之前的内容已经改变
+func (l *LogFollower) truncated(size int64) bool {
+	if size < l.offset {
+		return true
+	}
+	if len(l.check) == 0 {
+		return false
+	}
+	buf := make([]byte, len(l.check))
+	if _, err := l.f.ReadAt(buf, l.offset-int64(len(buf))); err != nil {
+		return true
+	}
+	return !bytes.Equal(buf, l.check)
+}
+
+// replaced 路径是否已指向另一个文件
+func (l *LogFollower) replaced() bool {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	id, err := fileID(f)
+	return err == nil && id != l.gen
+}
+
+// Read 返回当前位置之后最多 limit 字节的内容，不移动位置，处理后调用 Advance。
+// final 为 true 表示文件已被轮转且这是旧文件剩余的全部内容，下次调用 Read 时切换到新文件
+func (l *LogFollower) Read(limit int) (data []byte, final bool, err error) {
+	if l.rotated {
+		size, err := l.size()
+		if err != nil || l.offset >= size {
+			if err := l.switchFile(); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	size, err := l.size()
+	if err != nil {
+		return nil, false, err
+	}
+	if l.truncated(size) {
+		l.offset, l.check = 0, nil
+		l.truncs++
+	}
+	if !l.rotated && l.replaced() {
+		// 旧文件不会再写入，读完剩余内容后切换
+		l.rotated = true
+		if size <= l.offset {
+			return l.Read(limit)
+		}
+		final = size-l.offset <= int64(limit)
+	} else if l.rotated {
+		final = size-l.offset <= int64(limit)
+	}
+	if size <= l.offset {
+		return nil, false, nil
+	}
+	buf := make([]byte, min(size-l.offset, int64(limit)))
+	n, err := l.f.ReadAt(buf, l.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	return buf[:n], final, nil
+}
+
+func (l *LogFollower) switchFile() error {
+	old := l.f
+	if err := l.open(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// 新文件还未创建，等下次写入
+			return nil
+		}
+		return err
+	}
+	_ = old.Close()
+	l.rotated = false
+	return nil
+}
+
+// Advance 处理完 n 字节后前进
+func (l *LogFollower) Advance(n int) {
+	if n <= 0 {
+		return
+	}
+	l.offset += int64(n)
+	l.remember()
+}
+
+// Cursor 当前位置
+func (l *LogFollower) Cursor() LogCursor {
+	return LogCursor{Gen: l.gen, Offset: l.offset}
+}
+
+// Truncations 发现文件被截断的次数，截断后的内容从文件开头重新读取
+func (l *LogFollower) Truncations() int {
+	return l.truncs
+}
+
+func (l *LogFollower) Close() {
+	if l.f != nil {
+		_ = l.f.Close()
+	}
+}
@@ -0,0 +1,136 @@
+package jobmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readAll 读出 follower 目前能读到的全部内容
+func readAll(t *testing.T, l *LogFollower) string {
+	t.Helper()
+	var out []byte
+	for {
+		data, _, err := l.Read(4)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if len(data) == 0 {
+			return string(out)
+		}
+		out = append(out, data...)
+		l.Advance(len(data))
+	}
+}
+
+func appendFile(t *testing.T, p, s string) {
+	t.Helper()
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogFollowerRotation(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "job_log.txt")
+	appendFile(t, p, "old1\n")
+	l, err := FollowLog(p, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if got := readAll(t, l); got != "" {
+		t.Fatalf("should start at the end: %q", got)
+	}
+	appendFile(t, p, "old2\n")
+	oldGen := l.Cursor().Gen
+
+	// 按 lumberjack 的方式轮转：重命名后新建同名文件
+	if err := os.Rename(p, filepath.Join(dir, "job_log-2024-01-02T03-04-05.000.txt")); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, p, "new1\n")
+	if got := readAll(t, l); got != "old2\nnew1\n" {
+		t.Fatalf("should drain the old file then follow the new one: %q", got)
+	}
+	if c := l.Cursor(); c.Gen == oldGen || c.Offset != 5 {
+		t.Fatalf("cursor should point into the new file: %+v", c)
+	}
+}
+
+func TestLogFollowerResumeAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "job_log.txt")
+	appendFile(t, p, "a\nb\n")
+	l, err := FollowLog(p, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := l.Cursor()
+	l.Close()
+	if cursor.Offset != 2 {
+		t.Fatalf("unexpected tail offset: %+v", cursor)
+	}
+	parsed, ok := ParseLogCursor(cursor.String())
+	if !ok || parsed != cursor {
+		t.Fatalf("cursor round trip failed: %v", cursor.String())
+	}
+
+	// 断线期间轮转
+	appendFile(t, p, "c\n")
+	if err := os.Rename(p, filepath.Join(dir, "job_log-2024-01-02T03-04-05.000.txt")); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, p, "d\n")
+	l, err = FollowLog(p, &parsed, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if got := readAll(t, l); got != "b\nc\nd\n" {
+		t.Fatalf("resume should continue in the rotated file: %q", got)
+	}
+
+	// 找不到原文件时从当前文件开头读取
+	l2, err := FollowLog(p, &LogCursor{Gen: "gone", Offset: 100}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l2.Close()
+	if got := readAll(t, l2); got != "d\n" {
+		t.Fatalf("unknown generation should restart the current file: %q", got)
+	}
+}
+
+func TestLogFollowerCopyTruncate(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "job_log.txt")
+	appendFile(t, p, "first line\n")
+	l, err := FollowLog(p, nil, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	readAll(t, l)
+
+	// 截断后写入的内容超过原来的位置，只比较大小无法发现
+	if err := os.Truncate(p, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, p, "after truncate, longer\n")
+	if got := readAll(t, l); got != "after truncate, longer\n" || l.Truncations() != 1 {
+		t.Fatalf("truncation not detected: %q %d", got, l.Truncations())
+	}
+
+	if err := os.Truncate(p, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, p, "x\n")
+	if got := readAll(t, l); got != "x\n" || l.Truncations() != 2 {
+		t.Fatalf("shrunk file not detected: %q %d", got, l.Truncations())
+	}
+}
@@ -0,0 +1,189 @@
+package jobmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志格式
+const (
+	LogFormatRaw  = "raw"  // 原样写入程序的输出
+	LogFormatText = "text" // 每行前加时间戳与输出流标记
+	LogFormatJSON = "json" // 每行一个 JSON 对象，字段为 ts / stream / runId / line
+)
+
+// 输出流标记
+const (
+	LogStreamOut  = "out"  // 标准输出
+	LogStreamErr  = "err"  // 标准错误
+	LogStreamMeta = "meta" // rooster 写入的运行信息，例如单次运行日志的开头与结尾
+)
+
+// 超过该长度仍没有换行的输出按一行写入，避免无限缓存
+const maxLogLineSize = 64 * 1024
+
+const logTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// LogLine 一行日志，Time 与 Stream 只有 text / json 格式才有
+type LogLine struct {
+	Time   time.Time `json:"ts"`
+	Stream string    `json:"stream,omitempty"`
+	RunID  string    `json:"runId,omitempty"`
+	Line   string    `json:"line"`
+}
+
+var textLogLinePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) \[(out|err|meta)\] (.*)$`)
+
+// ParseLogLine 解析任意格式的一行日志（不含换行），无法识别时整行作为内容
+func ParseLogLine(s string) LogLine {
+	s = strings.TrimSuffix(s, "\r")
+	if strings.HasPrefix(s, "{") {
+		var l struct {
+			LogLine
+			Line *string `json:"line"`
+		}
+		if json.Unmarshal([]byte(s), &l) == nil && l.Line != nil {
+			l.LogLine.Line = *l.Line
+			return l.LogLine
+		}
+	}
+	if m := textLogLinePattern.FindStringSubmatch(s); m != nil {
+		if t, err := time.Parse(time.RFC3339Nano, m[1]); err == nil {
+			return LogLine{Time: t, Stream: m[2], Line: m[3]}
+		}
+	}
+	return LogLine{Line: s}
+}
+
+// FormatLogLine 按 text 格式输出一行日志，没有时间与输出流的行原样返回
+func FormatLogLine(l LogLine) string {
+	if l.Time.IsZero() && l.Stream == "" {
+		return l.Line
+	}
+	return fmt.Sprintf("%v [%v] %v", l.Time.Format(logTimeLayout), l.Stream, l.Line)
+}
+
+// RenderLogLines 将 data 中完整的行转换为 text 格式，返回转换结果与已处理的字节数，
+// 末尾不完整的行留给下一次处理
+func RenderLogLines(data []byte) ([]byte, int) {
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, 0
+	}
+	var out bytes.Buffer
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		out.WriteString(FormatLogLine(ParseLogLine(string(line))))
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), end + 1
+}
+
+// logFramer 将输出按行拆分，并以配置的格式写入 w。多个输出流共用一把锁，保证行不会交错。
+// w 可以为空，此时输出只转发给 sinks
+type logFramer struct {
+	lock    sync.Mutex
+	w       io.Writer
+	format  string
+	runID   string
+	clock   func() time.Time
+	streams []*streamWriter
+	sinks   []lineSink // 需在调用 Stream 之前设置
+}
+
+func newLogFramer(w io.Writer, format, runID string, clock func() time.Time) *logFramer {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &logFramer{w: w, format: format, runID: runID, clock: clock}
+}
+
+// framed 是否按行写入 w，raw 格式原样写入
+func (f *logFramer) framed() bool {
+	return f.format == LogFormatText || f.format == LogFormatJSON
+}
+
+// Stream 返回写入指定输出流的 Writer，raw 格式且没有 sinks 时直接写入底层
+func (f *logFramer) Stream(name string) io.Writer {
+	if !f.framed() && len(f.sinks) == 0 {
+		return f.w
+	}
+	s := &streamWriter{framer: f, name: name}
+	f.streams = append(f.streams, s)
+	return s
+}
+
+// Flush 写出各输出流末尾没有换行的内容，应在进程退出后调用
+func (f *logFramer) Flush() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, s := range f.streams {
+		if len(s.buf) > 0 {
+			_ = f.writeLine(s.name, s.buf)
+			s.buf = s.buf[:0]
+		}
+	}
+}
+
+func (f *logFramer) writeLine(stream string, line []byte) error {
+	l := LogLine{Time: f.clock(), Stream: stream, RunID: f.runID, Line: strings.TrimSuffix(string(line), "\r")}
+	for _, s := range f.sinks {
+		s.WriteLine(l)
+	}
+	if f.w == nil || !f.framed() {
+		return nil
+	}
+	if f.format == LogFormatJSON {
+		data, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		_, err = f.w.Write(append(data, '\n'))
+		return err
+	}
+	_, err := io.WriteString(f.w, FormatLogLine(l)+"\n")
+	return err
+}
+
+type streamWriter struct {
+	framer *logFramer
+	name   string
+	buf    []byte // 尚未遇到换行的内容
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	f := s.framer
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.framed() && f.w != nil {
+		// raw 格式原样写入，拆分的行只用于 sinks
+		if _, err := f.w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	s.buf = append(s.buf, p...)
+	start := 0
+	for {
+		i := bytes.IndexByte(s.buf[start:], '\n')
+		if i < 0 {
+			break
+		}
+		if err := f.writeLine(s.name, s.buf[start:start+i]); err != nil {
+			return 0, err
+		}
+		start += i + 1
+	}
+	for len(s.buf)-start >= maxLogLineSize {
+		if err := f.writeLine(s.name, s.buf[start:start+maxLogLineSize]); err != nil {
+			return 0, err
+		}
+		start += maxLogLineSize
+	}
+	s.buf = append(s.buf[:0], s.buf[start:]...)
+	return len(p), nil
+}
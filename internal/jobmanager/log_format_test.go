@@ -0,0 +1,112 @@
+package jobmanager
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFramerPartialLines(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	f := newLogFramer(&buf, LogFormatText, "r1", func() time.Time { return now })
+	out, errW := f.Stream(LogStreamOut), f.Stream(LogStreamErr)
+
+	_, _ = out.Write([]byte("hel"))
+	_, _ = errW.Write([]byte("oops\r\n"))
+	_, _ = out.Write([]byte("lo\nwor"))
+	_, _ = out.Write([]byte("ld"))
+	if got := buf.String(); got != "2024-01-02T03:04:05.006Z [err] oops\n2024-01-02T03:04:05.006Z [out] hello\n" {
+		t.Fatalf("unexpected framing:\n%s", got)
+	}
+	f.Flush()
+	if !strings.HasSuffix(buf.String(), "[out] world\n") {
+		t.Fatalf("flush should write the partial line:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	_, _ = out.Write(bytes.Repeat([]byte("x"), maxLogLineSize+10))
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Fatalf("over-long line should be split, got %d lines", n)
+	}
+
+	raw := newLogFramer(&buf, LogFormatRaw, "", nil)
+	if w, ok := raw.Stream(LogStreamOut).(*bytes.Buffer); !ok || w != &buf {
+		t.Fatalf("raw format should write directly")
+	}
+}
+
+func TestLogFramerJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	f := newLogFramer(&buf, LogFormatJSON, "run-1", func() time.Time { return now })
+	_, _ = f.Stream(LogStreamErr).Write([]byte("bad \"quote\"\n"))
+	l := ParseLogLine(strings.TrimSuffix(buf.String(), "\n"))
+	if l.Stream != LogStreamErr || l.RunID != "run-1" || l.Line != `bad "quote"` || !l.Time.Equal(now) {
+		t.Fatalf("unexpected parsed line: %+v from %s", l, buf.String())
+	}
+
+	data, consumed := RenderLogLines(append(buf.Bytes(), []byte(`{"ts":"2024`)...))
+	if consumed != buf.Len() || string(data) != "2024-01-02T03:04:05.000Z [err] bad \"quote\"\n" {
+		t.Fatalf("unexpected render: %q %d", data, consumed)
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	for _, s := range []string{"plain output", `{"other":"json"}`, "{broken", "2024-01-02 [out] not a timestamp"} {
+		if l := ParseLogLine(s); l.Line != s || l.Stream != "" {
+			t.Fatalf("raw line misparsed: %q -> %+v", s, l)
+		}
+	}
+	l := ParseLogLine("2024-01-02T03:04:05+08:00 [out] hi [there]")
+	if l.Stream != LogStreamOut || l.Line != "hi [there]" || l.Time.IsZero() {
+		t.Fatalf("text line misparsed: %+v", l)
+	}
+}
+
+func TestExecuteJSONLogFormat(t *testing.T) {
+	logDir := t.TempDir()
+	m, err := New(Options{Store: NewMemoryStore(JobConfig{}), LogDir: logDir})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	err = m.SaveTask(JobStatusShow{
+		JobName: "fmt", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo to-out; echo to-err 1>&2; exit 3",
+		Options: RunOptions{PerRunLog: true, LogFormat: LogFormatJSON},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	job := m.config.TaskList[0]
+	result := m.newExecutor().Execute(context.Background(), job, job.JobSpec, RunInfo{}, nil)
+	if result.ExitCode != 3 {
+		t.Fatalf("unexpected exit code: %v", result.ExitCode)
+	}
+
+	data, err := os.ReadFile(job.runtimeLogPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	streams := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if !strings.HasPrefix(line, "{") {
+			t.Fatalf("every line should be JSON: %q", line)
+		}
+		l := ParseLogLine(line)
+		if l.RunID != result.RunID {
+			t.Fatalf("run id missing: %+v", l)
+		}
+		streams[l.Line] = l.Stream
+	}
+	if streams["to-out"] != LogStreamOut || streams["to-err"] != LogStreamErr || streams[runLogFooterMark] != LogStreamMeta {
+		t.Fatalf("unexpected streams: %v", streams)
+	}
+
+	runs, err := m.ListRunLogs(job.UUID)
+	if err != nil || len(runs) != 1 || runs[0].ExitCode == nil || *runs[0].ExitCode != 3 {
+		t.Fatalf("footer not parsed from JSON log: %+v %v", runs, err)
+	}
+}
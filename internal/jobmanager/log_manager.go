@@ -1,43 +1,123 @@
 package jobmanager
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // LogManager handles log path resolution and writer creation
-type LogManager struct{}
+type LogManager struct {
+	logDir string           // default log directory, empty means ~/.roosterTaskConfig/log
+	clock  func() time.Time // used for daily rotation, nil means time.Now
+	stdout io.Writer        // where stdout output modes write, nil disables them
+}
+
+func (m *LogManager) now() time.Time {
+	if m.clock != nil {
+		return m.clock()
+	}
+	return time.Now()
+}
 
 // NewLogManager creates a new instance
 func NewLogManager() *LogManager {
-	return &LogManager{}
+	return &LogManager{stdout: os.Stdout}
 }
 
-// dualWriter writes to both stdout and a file
+// dualWriter writes to a log file and/or stdout, depending on the output type
 type dualWriter struct {
-	file    *lumberjack.Logger
-	jobName string
+	file   io.WriteCloser // nil when the output type does not write a file
+	stdout io.Writer      // nil when the output type does not write to stdout
 }
 
 func (w *dualWriter) Write(p []byte) (n int, err error) {
-	// 在写入标准输出时增加任务名作为前缀，便于区分多个任务的日志
-	prefix := fmt.Sprintf("[%s] ", w.jobName)
-	os.Stdout.Write([]byte(prefix))
-	os.Stdout.Write(p)
-
+	if w.stdout != nil {
+		_, _ = w.stdout.Write(p)
+	}
+	if w.file == nil {
+		return len(p), nil
+	}
 	return w.file.Write(p)
 }
 
+// Rotate rotates the underlying file when it supports rotation
+func (w *dualWriter) Rotate() error {
+	if r, ok := w.file.(logRotator); ok {
+		return r.Rotate()
+	}
+	return errors.New("log file does not support rotation")
+}
+
 func (w *dualWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
 	return w.file.Close()
 }
 
+// prefixWriter prefixes every line with the job name, so output of several jobs can be told apart
+type prefixWriter struct {
+	w       io.Writer
+	prefix  []byte
+	midLine bool
+}
+
+func newPrefixWriter(w io.Writer, jobName string) *prefixWriter {
+	return &prefixWriter{w: w, prefix: []byte("[" + jobName + "] ")}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	// Build the whole chunk first so one job's line is written in a single call
+	buf := make([]byte, 0, len(b)+len(p.prefix))
+	for len(b) > 0 {
+		if !p.midLine {
+			buf = append(buf, p.prefix...)
+		}
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			buf = append(buf, b...)
+			p.midLine = true
+			break
+		}
+		buf = append(buf, b[:i+1]...)
+		b = b[i+1:]
+		p.midLine = false
+	}
+	if _, err := p.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// newDualWriter creates the writer for the output type. It returns nil when the output is discarded,
+// and a writer without file when the output type does not write a file
+func (m *LogManager) newDualWriter(jobName string, options RunOptions) *dualWriter {
+	w := &dualWriter{}
+	if options.OutputType.WritesStdout() && m.stdout != nil {
+		w.stdout = newPrefixWriter(m.stdout, jobName)
+	}
+	if w.stdout == nil && !options.OutputType.WritesFile() {
+		return nil
+	}
+	return w
+}
+
 // ResolveLogPath determines the full path for the log file based on options
 func ResolveLogPath(jobName string, options RunOptions) (string, error) {
+	return resolveLogPath("", jobName, options)
+}
+
+// resolveLogPath is ResolveLogPath with the default log directory overridden by defaultDir
+func resolveLogPath(defaultDir string, jobName string, options RunOptions) (string, error) {
 	// Determine log directory
 	logDir := options.OutputPath
 	useDefault := false
@@ -52,7 +132,9 @@ func ResolveLogPath(jobName string, options RunOptions) (string, error) {
 		useDefault = true
 	}
 
-	if useDefault {
+	if useDefault && defaultDir != "" {
+		logDir = defaultDir
+	} else if useDefault {
 		if defDir, err := getLogDir(); err == nil {
 			logDir = defDir
 		} else {
@@ -64,9 +146,101 @@ func ResolveLogPath(jobName string, options RunOptions) (string, error) {
 	return filepath.Join(logDir, jobName+"_log.txt"), nil
 }
 
-// SetupLogger resolves the log path and creates a writer
+// Default rotation settings, used when neither the job nor the default options set them
+const (
+	defaultLogMaxSizeMB  = 10
+	defaultLogMaxBackups = 3
+	defaultLogMaxAgeDays = 28
+)
+
+// newLumberjack creates a lumberjack logger with the rotation settings of options
+func newLumberjack(fullLogPath string, options RunOptions) *lumberjack.Logger {
+	l := &lumberjack.Logger{
+		Filename:   fullLogPath,
+		MaxSize:    options.MaxSizeMB,
+		MaxBackups: options.MaxBackups,
+		MaxAge:     options.MaxAgeDays,
+		Compress:   options.Compress == nil || *options.Compress,
+	}
+	if l.MaxSize == 0 {
+		l.MaxSize = defaultLogMaxSizeMB
+	}
+	// lumberjack treats 0 as unlimited, -1 is the explicit form in RunOptions
+	switch l.MaxBackups {
+	case 0:
+		l.MaxBackups = defaultLogMaxBackups
+	case -1:
+		l.MaxBackups = 0
+	}
+	switch l.MaxAge {
+	case 0:
+		l.MaxAge = defaultLogMaxAgeDays
+	case -1:
+		l.MaxAge = 0
+	}
+	return l
+}
+
+// logRotator is a log writer that can be rotated on demand
+type logRotator interface {
+	Rotate() error
+}
+
+// rotatingWriter wraps lumberjack and additionally rotates when the day changes
+type rotatingWriter struct {
+	lock   sync.Mutex
+	logger *lumberjack.Logger
+	daily  bool
+	day    string // date of the current file
+	clock  func() time.Time
+}
+
+func newRotatingWriter(l *lumberjack.Logger, daily bool, clock func() time.Time) *rotatingWriter {
+	w := &rotatingWriter{logger: l, daily: daily, clock: clock}
+	if daily {
+		// The file may be left over from an earlier run on another day
+		if st, err := os.Stat(l.Filename); err == nil {
+			w.day = st.ModTime().Format(time.DateOnly)
+		}
+	}
+	return w
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.daily {
+		today := w.clock().Format(time.DateOnly)
+		if w.day != "" && w.day != today {
+			if err := w.logger.Rotate(); err != nil {
+				return 0, err
+			}
+		}
+		w.day = today
+	}
+	return w.logger.Write(p)
+}
+
+func (w *rotatingWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.day = w.clock().Format(time.DateOnly)
+	return w.logger.Rotate()
+}
+
+func (w *rotatingWriter) Close() error {
+	return w.logger.Close()
+}
+
+// SetupLogger resolves the log path and creates a writer. The returned path is empty
+// when the output type does not write a file, and the writer is nil when the output is discarded
 func (m *LogManager) SetupLogger(jobName string, options RunOptions) (io.WriteCloser, string, error) {
-	fullLogPath, err := ResolveLogPath(jobName, options)
+	writer := m.newDualWriter(jobName, options)
+	if writer == nil || !options.OutputType.WritesFile() {
+		return nilIfEmpty(writer), "", nil
+	}
+
+	fullLogPath, err := resolveLogPath(m.logDir, jobName, options)
 	if err != nil {
 		return nil, "", err
 	}
@@ -78,20 +252,50 @@ func (m *LogManager) SetupLogger(jobName string, options RunOptions) (io.WriteCl
 		return nil, "", fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	// Create lumberjack logger
-	lLogger := &lumberjack.Logger{
-		Filename:   fullLogPath,
-		MaxSize:    10, // megabytes
-		MaxBackups: 3,
-		MaxAge:     28,   // days
-		Compress:   true, // disabled by default
+	writer.file = newRotatingWriter(newLumberjack(fullLogPath, options), options.Rotation == RotationDaily, m.now)
+	return writer, fullLogPath, nil
+}
+
+// nilIfEmpty avoids returning a typed nil inside the io.WriteCloser interface
+func nilIfEmpty(w *dualWriter) io.WriteCloser {
+	if w == nil {
+		return nil
 	}
+	return w
+}
 
-	// Use dualWriter to output to both stdout and file
-	writer := &dualWriter{
-		file:    lLogger,
-		jobName: jobName,
+// RotateLog rotates the log file of a job that is not running
+func (m *LogManager) RotateLog(jobName string, options RunOptions) error {
+	fullLogPath, err := resolveLogPath(m.logDir, jobName, options)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(fullLogPath); err != nil {
+		return err
 	}
+	l := newLumberjack(fullLogPath, options)
+	defer l.Close()
+	return l.Rotate()
+}
 
+// SetupRunLogger creates the log file of a single run at <log dir>/<jobName>/<runID>.log
+func (m *LogManager) SetupRunLogger(jobName, runID string, options RunOptions) (io.WriteCloser, string, error) {
+	writer := m.newDualWriter(jobName, options)
+	if writer == nil || !options.OutputType.WritesFile() {
+		return nilIfEmpty(writer), "", nil
+	}
+	dir, err := runLogDir(m.logDir, jobName, options)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+	fullLogPath := filepath.Join(dir, runID+runLogExt)
+	f, err := os.OpenFile(fullLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, "", err
+	}
+	writer.file = f
 	return writer, fullLogPath, nil
 }
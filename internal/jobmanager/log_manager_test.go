@@ -0,0 +1,170 @@
+package jobmanager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewLumberjackOptions(t *testing.T) {
+	l := newLumberjack("a.log", RunOptions{})
+	if l.MaxSize != 10 || l.MaxBackups != 3 || l.MaxAge != 28 || !l.Compress {
+		t.Fatalf("unexpected defaults: %+v", l)
+	}
+	off := false
+	l = newLumberjack("a.log", RunOptions{MaxSizeMB: 1, MaxBackups: -1, MaxAgeDays: -1, Compress: &off})
+	if l.MaxSize != 1 || l.MaxBackups != 0 || l.MaxAge != 0 || l.Compress {
+		t.Fatalf("options not applied: %+v", l)
+	}
+}
+
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "job_log-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestRotatingWriterDaily(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local)
+	off := false
+	lm := &LogManager{logDir: dir, clock: func() time.Time { return now }}
+	w, p, err := lm.SetupLogger("job", RunOptions{Rotation: RotationDaily, Compress: &off})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	defer w.Close()
+	_, _ = w.Write([]byte("day1\n"))
+	_, _ = w.Write([]byte("day1 again\n"))
+	if n := len(backups(t, dir)); n != 0 {
+		t.Fatalf("should not rotate within a day, got %d backups", n)
+	}
+	now = now.Add(2 * time.Minute)
+	_, _ = w.Write([]byte("day2\n"))
+	if n := len(backups(t, dir)); n != 1 {
+		t.Fatalf("expected a backup after the day changed, got %d", n)
+	}
+	data, _ := os.ReadFile(p)
+	if string(data) != "day2\n" {
+		t.Fatalf("current file should only hold the new day: %q", data)
+	}
+}
+
+func TestRotateLog(t *testing.T) {
+	logDir := t.TempDir()
+	off := false
+	store := NewMemoryStore(JobConfig{Config: BaseConfig{DefaultOptions: RunOptions{MaxSizeMB: 1, MaxBackups: 7, Compress: &off}}})
+	m, err := New(Options{Store: store, LogDir: logDir})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := m.SaveTask(JobStatusShow{JobName: "job", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo hi", Options: RunOptions{MaxBackups: 2}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	job := m.config.TaskList[0]
+	if o := job.Options; o.MaxSizeMB != 1 || o.MaxBackups != 2 || o.Compress == nil || *o.Compress {
+		t.Fatalf("defaults not applied: %+v", o)
+	}
+
+	if err := m.RotateLog(job.UUID); err == nil {
+		t.Fatalf("expected error when the log file does not exist")
+	}
+	if err := os.WriteFile(filepath.Join(logDir, "job_log.txt"), []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RotateLog(job.UUID); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if n := len(backups(t, logDir)); n != 1 {
+		t.Fatalf("expected one backup, got %d", n)
+	}
+
+	results, err := m.BulkAction(BulkRotate, JobSelector{All: true})
+	if err != nil || len(results) != 1 || !results[0].OK {
+		t.Fatalf("bulk rotate: %+v %v", results, err)
+	}
+	if err := m.RotateLog("missing"); err == nil {
+		t.Fatalf("expected error for unknown job")
+	}
+}
+
+func TestSetupLoggerOutputTypes(t *testing.T) {
+	dir := t.TempDir()
+	var console bytes.Buffer
+	lm := &LogManager{logDir: dir, stdout: &console}
+
+	cases := []struct {
+		name       string
+		outputType OutputType
+		file       bool
+		stdout     bool
+	}{
+		{"std", OutputTypeStd, false, true},
+		{"file", OutputTypeFile, true, false},
+		{"both", OutputTypeBoth, true, true},
+		{"discard", OutputTypeDiscard, false, false},
+	}
+	for _, c := range cases {
+		console.Reset()
+		w, p, err := lm.SetupLogger(c.name, RunOptions{OutputType: c.outputType})
+		if err != nil {
+			t.Fatalf("%v: %v", c.name, err)
+		}
+		if (p != "") != c.file {
+			t.Fatalf("%v: unexpected log path %q", c.name, p)
+		}
+		if !c.file && !c.stdout {
+			if w != nil {
+				t.Fatalf("%v: discarded output should have no writer", c.name)
+			}
+			continue
+		}
+		_, _ = w.Write([]byte("a\nb"))
+		_, _ = w.Write([]byte("c\n"))
+		_ = w.Close()
+		if got := console.String(); (got == "["+c.name+"] a\n["+c.name+"] bc\n") != c.stdout {
+			t.Fatalf("%v: unexpected console output %q", c.name, got)
+		}
+		if c.file {
+			data, _ := os.ReadFile(p)
+			if string(data) != "a\nbc\n" {
+				t.Fatalf("%v: unexpected file content %q", c.name, data)
+			}
+		}
+	}
+
+	// 无控制台时标准输出方式的输出被丢弃
+	headless := &LogManager{logDir: dir}
+	if w, p, err := headless.SetupLogger("std", RunOptions{OutputType: OutputTypeStd}); w != nil || p != "" || err != nil {
+		t.Fatalf("headless std output should be discarded: %v %q %v", w, p, err)
+	}
+}
+
+func TestRealLogPathByOutputType(t *testing.T) {
+	m, err := New(Options{Store: NewMemoryStore(JobConfig{}), LogDir: t.TempDir(), Headless: true})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	for i, ot := range []OutputType{OutputTypeStd, OutputTypeBoth} {
+		err := m.SaveTask(JobStatusShow{JobName: fmt.Sprintf("job%d", i), Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo", Options: RunOptions{OutputType: ot}})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	list := m.JobList()
+	if list[0].RealLogPath != "" || list[1].RealLogPath == "" {
+		t.Fatalf("only jobs writing files should have a log path: %q %q", list[0].RealLogPath, list[1].RealLogPath)
+	}
+	if err := m.RotateLog(list[0].UUID); err == nil {
+		t.Fatalf("rotate should fail for jobs without log file")
+	}
+	if e := m.newExecutor(); e.logManager.stdout != nil {
+		t.Fatalf("headless manager should not write to stdout")
+	}
+}
@@ -0,0 +1,197 @@
+package jobmanager
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 日志搜索的限制
+const (
+	defaultLogSearchLimit   = 100
+	maxLogSearchLimit       = 1000
+	defaultLogSearchTimeout = 10 * time.Second
+	maxLogSearchTimeout     = 30 * time.Second
+	maxLogSearchLineSize    = 1024 * 1024 // 超出部分不参与匹配
+)
+
+// LogSearchQuery 日志搜索条件
+type LogSearchQuery struct {
+	Query   string        // 搜索内容
+	Regex   bool          // Query 是否为正则表达式
+	Since   time.Time     // 只搜索该时间之后的日志，零值不限制
+	Until   time.Time     // 只搜索该时间之前的日志，零值不限制
+	Limit   int           // 最多返回的结果数，默认 100，最多 1000
+	Timeout time.Duration // 搜索耗时上限，默认 10 秒，最多 30 秒
+}
+
+// LogMatch 一条搜索结果，File 与 Offset 可用于定位到日志文件中的位置
+type LogMatch struct {
+	File   string     `json:"file"`   // 日志文件名
+	Offset int64      `json:"offset"` // 行首在（解压后）文件中的字节偏移
+	LineNo int        `json:"lineNo"` // 行号，从 1 开始
+	Time   *time.Time `json:"time,omitempty"`
+	Stream string     `json:"stream,omitempty"`
+	Line   string     `json:"line"`
+}
+
+// LogSearchResult 搜索的统计信息
+type LogSearchResult struct {
+	Matches      int  `json:"matches"`      // 返回的结果数
+	FilesScanned int  `json:"filesScanned"` // 搜索过的文件数
+	Truncated    bool `json:"truncated"`    // 结果数达到上限，更早的结果未返回
+	TimedOut     bool `json:"timedOut"`     // 搜索超时，只返回了部分结果
+}
+
+// compile 检查搜索条件并返回匹配函数
+func (q *LogSearchQuery) compile() (func(string) bool, error) {
+	if q.Query == "" {
+		return nil, errors.New("搜索内容不能为空")
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return nil, errors.New("结束时间早于开始时间")
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultLogSearchLimit
+	}
+	q.Limit = min(q.Limit, maxLogSearchLimit)
+	if q.Timeout <= 0 {
+		q.Timeout = defaultLogSearchTimeout
+	}
+	q.Timeout = min(q.Timeout, maxLogSearchTimeout)
+	if !q.Regex {
+		query := q.Query
+		return func(s string) bool { return strings.Contains(s, query) }, nil
+	}
+	re, err := regexp.Compile(q.Query)
+	if err != nil {
+		return nil, fmt.Errorf("正则表达式无效: %w", err)
+	}
+	return re.MatchString, nil
+}
+
+// SearchLog 在任务的当前日志与轮转文件（含压缩文件）中搜索，按时间从新到旧依次调用 emit。
+// 搜索条件无效时在调用 emit 之前返回错误；超时或结果数达到上限时停止并在结果中标记
+func (m *Manager) SearchLog(ctx context.Context, jobId string, q LogSearchQuery, emit func(LogMatch) error) (LogSearchResult, error) {
+	var result LogSearchResult
+	match, err := q.compile()
+	if err != nil {
+		return result, err
+	}
+	files, err := m.jobLogFiles(jobId)
+	if err != nil {
+		return result, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, q.Timeout)
+	defer cancel()
+
+	for _, f := range files {
+		if result.Matches >= q.Limit {
+			result.Truncated = true
+			break
+		}
+		// 最后写入时间早于 since 的文件不可能包含需要的日志
+		if !q.Since.IsZero() && f.ModTime.Before(q.Since) {
+			continue
+		}
+		matches, full, err := searchLogFile(ctx, f, q, match, q.Limit-result.Matches)
+		result.FilesScanned++
+		if full {
+			result.Truncated = true
+		}
+		// 文件内按从新到旧返回
+		for i := len(matches) - 1; i >= 0; i-- {
+			if err := emit(matches[i]); err != nil {
+				return result, err
+			}
+			result.Matches++
+		}
+		if ctx.Err() != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				result.TimedOut = true
+				return result, nil
+			}
+			return result, ctx.Err()
+		}
+		if err != nil {
+			m.log().Warn("搜索日志文件失败", "file", f.Path, "err", err)
+		}
+	}
+	return result, nil
+}
+
+// searchLogFile 返回文件中最后 limit 个匹配的行（按文件顺序），以及是否有更早的匹配被丢弃
+func searchLogFile(ctx context.Context, f LogFile, q LogSearchQuery, match func(string) bool, limit int) ([]LogMatch, bool, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+
+	// 环形保留最后 limit 个结果
+	ring := make([]LogMatch, 0, min(limit, 64))
+	next, dropped := 0, false
+	br := bufio.NewReaderSize(r, 64*1024)
+	var offset int64
+	for lineNo := 1; ; lineNo++ {
+		if lineNo%1024 == 0 && ctx.Err() != nil {
+			break
+		}
+		raw, n, err := readLogLine(br)
+		if n == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return orderRing(ring, next), dropped, err
+		}
+		start := offset
+		offset += int64(n)
+
+		l := ParseLogLine(raw)
+		if !l.Time.IsZero() && (!q.Since.IsZero() && l.Time.Before(q.Since) || !q.Until.IsZero() && l.Time.After(q.Until)) {
+			continue
+		}
+		if !match(l.Line) {
+			continue
+		}
+		lm := LogMatch{File: f.Name, Offset: start, LineNo: lineNo, Stream: l.Stream, Line: l.Line}
+		if !l.Time.IsZero() {
+			t := l.Time
+			lm.Time = &t
+		}
+		if len(ring) < limit {
+			ring = append(ring, lm)
+			continue
+		}
+		ring[next] = lm
+		next = (next + 1) % limit
+		dropped = true
+	}
+	return orderRing(ring, next), dropped, ctx.Err()
+}
+
+func orderRing(ring []LogMatch, next int) []LogMatch {
+	return append(ring[next:], ring[:next]...)
+}
+
+// readLogLine 读取一行（不含换行），返回消耗的字节数。超长的行只保留前 maxLogSearchLineSize 字节
+func readLogLine(br *bufio.Reader) (string, int, error) {
+	var line []byte
+	n := 0
+	for {
+		chunk, err := br.ReadSlice('\n')
+		n += len(chunk)
+		if len(line) < maxLogSearchLineSize {
+			line = append(line, chunk[:min(len(chunk), maxLogSearchLineSize-len(line))]...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		return strings.TrimSuffix(string(line), "\n"), n, err
+	}
+}
@@ -0,0 +1,140 @@
+package jobmanager
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	_, _ = zw.Write([]byte(content))
+	_ = zw.Close()
+	_ = f.Close()
+}
+
+func newSearchManager(t *testing.T) (*Manager, string, string) {
+	t.Helper()
+	logDir := t.TempDir()
+	m, err := New(Options{Store: NewMemoryStore(JobConfig{}), LogDir: logDir})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := m.SaveTask(JobStatusShow{JobName: "api", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	writeGzip(t, filepath.Join(logDir, "api_log-2024-01-01T00-00-00.000.txt.gz"), "error oldest\nok\n")
+	_ = os.WriteFile(filepath.Join(logDir, "api_log-2024-01-02T00-00-00.000.txt"), []byte("error older\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(logDir, "api_log.txt"), []byte("ok\nerror new 1\r\nerror new 2"), 0o644)
+	// 其他任务与无关文件不应被搜索
+	_ = os.WriteFile(filepath.Join(logDir, "api-v2_log.txt"), []byte("error other job\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(logDir, "api_log-notes.txt"), []byte("error notes\n"), 0o644)
+	return m, logDir, m.config.TaskList[0].UUID
+}
+
+func searchAll(t *testing.T, m *Manager, jobId string, q LogSearchQuery) ([]LogMatch, LogSearchResult) {
+	t.Helper()
+	var matches []LogMatch
+	result, err := m.SearchLog(context.Background(), jobId, q, func(lm LogMatch) error {
+		matches = append(matches, lm)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	return matches, result
+}
+
+func TestSearchLogAcrossRotatedFiles(t *testing.T) {
+	m, logDir, jobId := newSearchManager(t)
+
+	matches, result := searchAll(t, m, jobId, LogSearchQuery{Query: "error"})
+	var lines []string
+	for _, lm := range matches {
+		lines = append(lines, lm.Line)
+	}
+	if got := strings.Join(lines, ","); got != "error new 2,error new 1,error older,error oldest" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+	if result.Matches != 4 || result.FilesScanned != 3 || result.Truncated || result.TimedOut {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// 偏移指向行首
+	first := matches[1]
+	data, _ := os.ReadFile(filepath.Join(logDir, first.File))
+	if first.File != "api_log.txt" || first.LineNo != 2 || !strings.HasPrefix(string(data[first.Offset:]), "error new 1") {
+		t.Fatalf("bad reference: %+v", first)
+	}
+	oldest := matches[3]
+	if !strings.HasSuffix(oldest.File, ".gz") || oldest.Offset != 0 {
+		t.Fatalf("bad compressed reference: %+v", oldest)
+	}
+
+	matches, result = searchAll(t, m, jobId, LogSearchQuery{Query: `new \d`, Regex: true, Limit: 1})
+	if len(matches) != 1 || matches[0].Line != "error new 2" || !result.Truncated {
+		t.Fatalf("limit should keep the newest match: %+v %+v", matches, result)
+	}
+
+	if _, err := m.SearchLog(context.Background(), jobId, LogSearchQuery{Query: "(", Regex: true}, nil); err == nil {
+		t.Fatalf("expected invalid regex error")
+	}
+	if _, err := m.SearchLog(context.Background(), jobId, LogSearchQuery{}, nil); err == nil {
+		t.Fatalf("expected empty query error")
+	}
+}
+
+func TestSearchLogTimeRange(t *testing.T) {
+	m, logDir, jobId := newSearchManager(t)
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"api_log-2024-01-01T00-00-00.000.txt.gz", "api_log-2024-01-02T00-00-00.000.txt"} {
+		_ = os.Chtimes(filepath.Join(logDir, name), old, old)
+	}
+	_ = os.WriteFile(filepath.Join(logDir, "api_log.txt"), []byte(
+		"2024-05-01T10:00:00.000Z [out] error early\n"+
+			"2024-05-01T12:00:00.000Z [err] error late\n"+
+			"error untimed\n"), 0o644)
+
+	matches, _ := searchAll(t, m, jobId, LogSearchQuery{
+		Query: "error",
+		Since: time.Now().Add(-time.Hour),
+	})
+	if len(matches) != 1 || matches[0].Line != "error untimed" {
+		t.Fatalf("old files and lines should be skipped: %+v", matches)
+	}
+
+	matches, _ = searchAll(t, m, jobId, LogSearchQuery{
+		Query: "error",
+		Until: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+	})
+	if len(matches) < 2 || matches[0].Line != "error untimed" || matches[1].Line != "error early" || matches[1].Stream != LogStreamOut || matches[1].Time == nil {
+		t.Fatalf("unexpected until filter: %+v", matches)
+	}
+}
+
+func TestLogFileOpenCompressed(t *testing.T) {
+	_, logDir, _ := newSearchManager(t)
+	files, err := listLogFiles(filepath.Join(logDir, "api_log.txt"))
+	if err != nil || len(files) != 3 || !files[0].Current || !files[2].Compressed {
+		t.Fatalf("unexpected files: %+v %v", files, err)
+	}
+	r, err := files[2].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	if string(data) != "error oldest\nok\n" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+}
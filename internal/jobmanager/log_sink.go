@@ -0,0 +1,230 @@
+package jobmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/leancodebox/rooster/internal/logsink"
+)
+
+// SyslogConfig 任务输出转发到 syslog 的配置
+type SyslogConfig = logsink.SyslogConfig
+
+// LokiConfig 任务输出推送到 Loki 的配置
+type LokiConfig = logsink.LokiConfig
+
+// syslog 结构化数据的 ID，32473 为 RFC 5612 中供文档与示例使用的企业号
+const syslogSDID = "rooster@32473"
+
+// 每个 syslog 连接缓冲的消息数，超出后丢弃
+const syslogBufferSize = 1000
+
+// 每个 Loki 客户端缓冲的行数，超出后丢弃
+const lokiBufferSize = 10000
+
+// lineSink 接收任务输出的每一行并转发到外部日志系统，WriteLine 不能阻塞
+type lineSink interface {
+	WriteLine(l LogLine)
+}
+
+// syslogSink 将任务输出以 RFC 5424 消息发送到 syslog
+type syslogSink struct {
+	w        *logsink.SyslogWriter
+	cfg      SyslogConfig
+	hostname string
+	jobName  string
+	jobUUID  string
+}
+
+func (s *syslogSink) WriteLine(l LogLine) {
+	if l.Stream != LogStreamOut && l.Stream != LogStreamErr {
+		return
+	}
+	appName := s.cfg.AppName
+	if appName == "" {
+		appName = s.jobName
+	}
+	msg := logsink.SyslogMessage{
+		Priority: s.cfg.Priority(l.Stream == LogStreamErr),
+		Time:     l.Time,
+		Hostname: s.hostname,
+		AppName:  appName,
+		ProcID:   l.RunID,
+		MsgID:    l.Stream,
+		SDID:     syslogSDID,
+		Params: []logsink.SDParam{
+			{Name: "job", Value: s.jobName},
+			{Name: "uuid", Value: s.jobUUID},
+			{Name: "runId", Value: l.RunID},
+			{Name: "stream", Value: l.Stream},
+		},
+		Text: l.Line,
+	}
+	s.w.Send(msg.Format())
+}
+
+// lokiSink 将任务输出推送到 Loki，任务名、UUID、分组与输出流作为标签
+type lokiSink struct {
+	c      *logsink.LokiClient
+	labels map[string]string
+}
+
+func (s *lokiSink) WriteLine(l LogLine) {
+	if l.Stream != LogStreamOut && l.Stream != LogStreamErr {
+		return
+	}
+	labels := make(map[string]string, len(s.labels)+1)
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	labels["stream"] = l.Stream
+	s.c.Push(logsink.LokiEntry{Labels: labels, Time: l.Time, Line: l.Line})
+}
+
+// effectiveSyslog 任务生效的 syslog 配置，未设置的字段使用默认选项，都未设置时返回 nil
+func effectiveSyslog(options RunOptions, def RunOptions) *SyslogConfig {
+	if options.Syslog == nil && def.Syslog == nil {
+		return nil
+	}
+	var cfg SyslogConfig
+	if options.Syslog != nil {
+		cfg = *options.Syslog
+	}
+	if def.Syslog != nil {
+		cfg = cfg.Merge(*def.Syslog)
+	}
+	return &cfg
+}
+
+// effectiveLoki 任务生效的 Loki 配置，未设置的字段使用默认选项，都未设置时返回 nil
+func effectiveLoki(options RunOptions, def RunOptions) *LokiConfig {
+	if options.Loki == nil && def.Loki == nil {
+		return nil
+	}
+	var cfg LokiConfig
+	if options.Loki != nil {
+		cfg = *options.Loki
+	}
+	if def.Loki != nil {
+		cfg = cfg.Merge(*def.Loki)
+	}
+	return &cfg
+}
+
+// syslogWriter 返回发送到 cfg 地址的连接，相同地址的任务共用一个连接
+func (m *Manager) syslogWriter(cfg SyslogConfig) *logsink.SyslogWriter {
+	m.sinkLock.Lock()
+	defer m.sinkLock.Unlock()
+	if m.syslogWriters == nil {
+		m.syslogWriters = map[string]*logsink.SyslogWriter{}
+	}
+	w := m.syslogWriters[cfg.Key()]
+	if w == nil {
+		w = logsink.NewSyslogWriter(cfg.Network, cfg.Address, syslogBufferSize)
+		m.syslogWriters[cfg.Key()] = w
+	}
+	return w
+}
+
+// lokiClient 返回推送到 cfg 地址的客户端，地址、租户与请求头相同的任务共用一个客户端。
+// 推送失败的批次暂存在 <日志目录>/.spool 下
+func (m *Manager) lokiClient(cfg LokiConfig) *logsink.LokiClient {
+	m.sinkLock.Lock()
+	defer m.sinkLock.Unlock()
+	if m.lokiClients == nil {
+		m.lokiClients = map[string]*logsink.LokiClient{}
+	}
+	c := m.lokiClients[cfg.Key()]
+	if c == nil {
+		spoolDir := ""
+		if dir, err := m.defaultLogDir(); err == nil {
+			spoolDir = filepath.Join(dir, ".spool")
+		}
+		c = logsink.NewLokiClient(cfg, spoolDir, lokiBufferSize)
+		m.lokiClients[cfg.Key()] = c
+	}
+	return c
+}
+
+// jobSinks 返回任务输出需要转发到的 sinks
+func (m *Manager) jobSinks(spec JobSpec) []lineSink {
+	var sinks []lineSink
+	def := m.config.Config.DefaultOptions
+	if cfg := effectiveSyslog(spec.Options, def); cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			m.log().Warn("syslog 配置无效，不转发任务输出", "jobName", spec.JobName, "err", err)
+		} else {
+			hostname, _ := os.Hostname()
+			sinks = append(sinks, &syslogSink{w: m.syslogWriter(*cfg), cfg: *cfg, hostname: hostname, jobName: spec.JobName, jobUUID: spec.UUID})
+		}
+	}
+	if cfg := effectiveLoki(spec.Options, def); cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			m.log().Warn("loki 配置无效，不推送任务输出", "jobName", spec.JobName, "err", err)
+		} else {
+			labels := map[string]string{}
+			for k, v := range cfg.Labels {
+				labels[k] = v
+			}
+			labels["job"], labels["uuid"] = spec.JobName, spec.UUID
+			if spec.Group != "" {
+				labels["group"] = spec.Group
+			}
+			sinks = append(sinks, &lokiSink{c: m.lokiClient(*cfg), labels: labels})
+		}
+	}
+	return sinks
+}
+
+// SinkStats 一个日志转发目标的发送统计
+type SinkStats struct {
+	Type   string `json:"type"` // syslog / loki
+	Target string `json:"target"`
+	logsink.Stats
+}
+
+// SinkStats 返回当前所有日志转发目标的发送统计，按类型与目标排序
+func (m *Manager) SinkStats() []SinkStats {
+	m.sinkLock.Lock()
+	defer m.sinkLock.Unlock()
+	list := []SinkStats{}
+	for key, w := range m.syslogWriters {
+		list = append(list, SinkStats{Type: "syslog", Target: key, Stats: w.Stats()})
+	}
+	for _, c := range m.lokiClients {
+		list = append(list, SinkStats{Type: "loki", Target: c.Target(), Stats: c.Stats()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Type != list[j].Type {
+			return list[i].Type < list[j].Type
+		}
+		return list[i].Target < list[j].Target
+	})
+	return list
+}
+
+// closeSinks 关闭所有转发目标，缓冲中的日志会尽量发出
+func (m *Manager) closeSinks() {
+	m.sinkLock.Lock()
+	writers, clients := m.syslogWriters, m.lokiClients
+	m.syslogWriters, m.lokiClients = nil, nil
+	m.sinkLock.Unlock()
+	var errs []error
+	for key, w := range writers {
+		if n := w.Dropped(); n > 0 {
+			m.log().Warn("syslog 消息被丢弃", "target", key, "dropped", n)
+		}
+		errs = append(errs, w.Close())
+	}
+	for _, c := range clients {
+		errs = append(errs, c.Close())
+		if st := c.Stats(); st.Dropped > 0 || st.Spooled > 0 {
+			m.log().Warn("loki 日志未全部推送", "target", c.Target(), "dropped", st.Dropped, "spooled", st.Spooled)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		m.log().Warn("关闭日志转发失败", "err", err)
+	}
+}
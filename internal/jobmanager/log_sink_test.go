@@ -0,0 +1,139 @@
+package jobmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type lineRecorder []LogLine
+
+func (r *lineRecorder) WriteLine(l LogLine) { *r = append(*r, l) }
+
+func TestLogFramerRawWithSinks(t *testing.T) {
+	var buf bytes.Buffer
+	var rec lineRecorder
+	f := newLogFramer(&buf, LogFormatRaw, "r1", nil)
+	f.sinks = []lineSink{&rec}
+	out := f.Stream(LogStreamOut)
+	_, _ = out.Write([]byte("a\nb"))
+	f.Flush()
+	if buf.String() != "a\nb" {
+		t.Fatalf("raw output should be written as is: %q", buf.String())
+	}
+	if len(rec) != 2 || rec[0].Line != "a" || rec[1].Line != "b" || rec[0].RunID != "r1" {
+		t.Fatalf("unexpected forwarded lines: %+v", rec)
+	}
+
+	// 不写文件时只转发
+	rec = nil
+	f = newLogFramer(nil, LogFormatText, "", nil)
+	f.sinks = []lineSink{&rec}
+	_, _ = f.Stream(LogStreamErr).Write([]byte("e\n"))
+	if len(rec) != 1 || rec[0].Stream != LogStreamErr {
+		t.Fatalf("unexpected forwarded lines: %+v", rec)
+	}
+}
+
+func TestExecuteForwardsToSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	config := JobConfig{Config: BaseConfig{DefaultOptions: RunOptions{Syslog: &SyslogConfig{Address: conn.LocalAddr().String(), Facility: "local0"}}}}
+	m, err := New(Options{Store: NewMemoryStore(config), LogDir: t.TempDir(), Headless: true})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer m.closeSinks()
+	err = m.SaveTask(JobStatusShow{
+		JobName: "sys", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo to-out; echo to-err 1>&2",
+		Options: RunOptions{OutputType: OutputTypeDiscard, Syslog: &SyslogConfig{AppName: "app"}},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	job := m.config.TaskList[0]
+	result := m.newExecutor().Execute(context.Background(), job, job.JobSpec, RunInfo{}, nil)
+	if result.ExitCode != 0 {
+		t.Fatalf("unexpected exit code: %v", result.ExitCode)
+	}
+
+	got := map[string]string{}
+	buf := make([]byte, 2048)
+	// 登录 shell 可能输出其他内容，直到收到两行为止
+	for got["to-out"] == "" || got["to-err"] == "" {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v (got %v)", err, got)
+		}
+		msg := string(buf[:n])
+		got[msg[strings.LastIndex(msg, " ")+1:]] = msg
+	}
+	// local0 = 16，标准输出默认 info(6)，标准错误默认 err(3)
+	if out := got["to-out"]; !strings.HasPrefix(out, "<134>1 ") || !strings.Contains(out, " app "+result.RunID+" out [rooster@32473 job=\"sys\"") {
+		t.Fatalf("unexpected stdout message: %q", out)
+	}
+	if errMsg := got["to-err"]; !strings.HasPrefix(errMsg, "<131>1 ") || !strings.Contains(errMsg, `stream="err"`) {
+		t.Fatalf("unexpected stderr message: %q", errMsg)
+	}
+
+	bad := JobSpec{JobName: "bad", Type: JobTypeScheduled, Spec: "@daily", BinPath: "echo", Options: RunOptions{Syslog: &SyslogConfig{Network: "http"}}}
+	if r := validateJobSpec(bad, nil, BaseConfig{}, ""); r.Valid() {
+		t.Fatalf("invalid syslog config should fail validation")
+	}
+}
+
+func TestExecutePushesToLoki(t *testing.T) {
+	pushed := make(chan map[string]any, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Streams []map[string]any `json:"streams"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for _, st := range body.Streams {
+			pushed <- st
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	config := JobConfig{Config: BaseConfig{DefaultOptions: RunOptions{Loki: &LokiConfig{URL: srv.URL, Labels: map[string]string{"env": "test"}}}}}
+	m, err := New(Options{Store: NewMemoryStore(config), LogDir: t.TempDir(), Headless: true})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	err = m.SaveTask(JobStatusShow{
+		JobName: "loki", Group: "web", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo to-err 1>&2",
+		Options: RunOptions{OutputType: OutputTypeDiscard},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	job := m.config.TaskList[0]
+	m.newExecutor().Execute(context.Background(), job, job.JobSpec, RunInfo{}, nil)
+	m.closeSinks()
+
+	for {
+		select {
+		case st := <-pushed:
+			labels := st["stream"].(map[string]any)
+			if labels["stream"] != LogStreamErr {
+				continue
+			}
+			if labels["job"] != "loki" || labels["uuid"] != job.UUID || labels["group"] != "web" || labels["env"] != "test" {
+				t.Fatalf("unexpected labels: %v", labels)
+			}
+			return
+		default:
+			t.Fatalf("stderr line was not pushed")
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package jobmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/notify"
+)
+
+// NotifyConfig 通知配置
+type NotifyConfig struct {
+	Channels     []notify.Channel `json:"channels"`
+	Default      *NotifyRule      `json:"default"`      // 未单独配置通知的任务使用的规则
+	DedupSeconds int              `json:"dedupSeconds"` // 同一任务同一类通知的最短间隔，默认 600
+	MaxPerHour   int              `json:"maxPerHour"`   // 每小时最多发送的通知数，默认 30
+}
+
+// NotifyRule 任务的通知规则
+type NotifyRule struct {
+	Channels    []string `json:"channels"`    // 使用的渠道名，为空时使用全部渠道
+	OnFailure   bool     `json:"onFailure"`   // 异常退出
+	OnGiveUp    bool     `json:"onGiveUp"`    // 常驻任务连续失败，停止重启
+	OnRecovery  bool     `json:"onRecovery"`  // 失败后恢复
+	LongRunning int      `json:"longRunning"` // 单次运行超过该秒数时通知，0 表示不检查
+	LogLines    int      `json:"logLines"`    // 附带的日志尾部行数，默认 20，负数表示不附带
+	Template    string   `json:"template"`    // 消息正文模板（text/template），为空时使用默认模板
+}
+
+// 通知类型
+const (
+	NotifyFailure     = "failure"
+	NotifyGiveUp      = "give_up"
+	NotifyRecovery    = "recovery"
+	NotifyLongRunning = "long_running"
+	NotifyTest        = "test"
+)
+
+var notifyTitles = map[string]string{
+	NotifyFailure:     "运行失败",
+	NotifyGiveUp:      "连续失败，已停止重启",
+	NotifyRecovery:    "已恢复",
+	NotifyLongRunning: "运行时间过长",
+	NotifyTest:        "测试通知",
+}
+
+const (
+	defaultNotifyDedup      = 600
+	defaultNotifyMaxPerHour = 30
+	defaultNotifyLogLines   = 20
+)
+
+const defaultNotifyTemplate = `任务: {{.JobName}}
+主机: {{.Host}}
+时间: {{.Time.Format "2006-01-02 15:04:05"}}
+{{- if .ExitCode}}
+退出码: {{.ExitCode}}{{end}}
+{{- if .Duration}}
+运行时长: {{.Duration}}{{end}}
+{{- if .Message}}
+信息: {{.Message}}{{end}}
+{{- if .LogTail}}
+
+最近日志:
+{{.LogTail}}{{end}}`
+
+// NotifyData 通知模板可以使用的字段
+type NotifyData struct {
+	Kind     string        // failure / give_up / recovery / long_running
+	Title    string        // 例如 "运行失败"
+	JobName  string        //
+	UUID     string        //
+	RunID    string        //
+	ExitCode string        // 无退出码时为空
+	Reason   string        // 退出原因
+	Message  string        // 错误信息等
+	Duration time.Duration // 本次运行时长
+	Host     string        //
+	Time     time.Time     //
+	LogTail  string        // 日志尾部
+}
+
+// notifier 订阅生命周期事件，按任务规则发送通知
+type notifier struct {
+	m      *Manager
+	send   func(ctx context.Context, c notify.Channel, msg notify.Message) error
+	cancel func()
+
+	lock    sync.Mutex
+	failing map[string]bool        // 处于失败状态、等待恢复的任务
+	timers  map[string]*time.Timer // 按运行 ID 记录的恢复与超时检查
+	sent    map[string]time.Time   // 去重：任务与通知类型 -> 最近发送时间
+	history []time.Time            // 最近一小时的发送时间，用于限流
+}
+
+func newNotifier(m *Manager) *notifier {
+	return &notifier{
+		m:       m,
+		send:    notify.Send,
+		failing: map[string]bool{},
+		timers:  map[string]*time.Timer{},
+		sent:    map[string]time.Time{},
+	}
+}
+
+// startNotifier 开始处理通知，重复调用时只启动一次
+func (m *Manager) startNotifier() {
+	m.notifierLock.Lock()
+	defer m.notifierLock.Unlock()
+	if m.notifier == nil {
+		m.notifier = newNotifier(m)
+	}
+	if m.notifier.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.notifier.cancel = cancel
+	go m.notifier.run(ctx)
+}
+
+func (m *Manager) stopNotifier() {
+	m.notifierLock.Lock()
+	defer m.notifierLock.Unlock()
+	if m.notifier != nil && m.notifier.cancel != nil {
+		m.notifier.cancel()
+		m.notifier.cancel = nil
+	}
+}
+
+func (n *notifier) run(ctx context.Context) {
+	bus := n.m.Events()
+	last := bus.LastID()
+	for {
+		_, ch, cancel := bus.SubscribeFrom(last, 256)
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case e, ok := <-ch:
+				if !ok {
+					// 处理不过来被取消订阅，从最后处理的事件重新订阅
+					open = false
+					break
+				}
+				last = e.ID
+				n.handle(e)
+			}
+		}
+		cancel()
+	}
+}
+
+// ruleFor 返回任务使用的通知规则，没有规则或没有渠道时返回 nil
+func (n *notifier) ruleFor(job *Job) (*NotifyRule, *NotifyConfig) {
+	cfg := n.m.config.Config.Notify
+	if cfg == nil || len(cfg.Channels) == 0 {
+		return nil, nil
+	}
+	if job.Notify != nil {
+		return job.Notify, cfg
+	}
+	return cfg.Default, cfg
+}
+
+func (n *notifier) handle(e Event) {
+	if e.UUID == "" {
+		return
+	}
+	job := n.m.config.GetJob(e.UUID)
+	if job == nil {
+		return
+	}
+	rule, cfg := n.ruleFor(job)
+	if rule == nil {
+		return
+	}
+	switch e.Type {
+	case EventJobStarted:
+		n.lock.Lock()
+		failing := n.failing[e.UUID]
+		n.lock.Unlock()
+		// 常驻任务失败后重新运行超过最短运行时间，视为恢复
+		if failing && rule.OnRecovery && job.Type == JobTypeResident {
+			threshold := maxExecutionTime
+			if job.Options.MinRunSeconds > 0 {
+				threshold = time.Duration(job.Options.MinRunSeconds) * time.Second
+			}
+			n.after(e.RunID+"/recovery", threshold, func() {
+				n.lock.Lock()
+				delete(n.failing, e.UUID)
+				n.lock.Unlock()
+				n.notify(job, rule, cfg, NotifyRecovery, e)
+			})
+		}
+		if rule.LongRunning > 0 {
+			n.after(e.RunID+"/long", time.Duration(rule.LongRunning)*time.Second, func() {
+				n.notify(job, rule, cfg, NotifyLongRunning, e)
+			})
+		}
+	case EventJobExited:
+		n.stopTimers(e.RunID)
+		switch e.Reason {
+		case ExitReasonFailed, ExitReasonStartFailed:
+			n.lock.Lock()
+			n.failing[e.UUID] = true
+			n.lock.Unlock()
+			if rule.OnFailure {
+				n.notify(job, rule, cfg, NotifyFailure, e)
+			}
+		case ExitReasonExited:
+			n.lock.Lock()
+			failing := n.failing[e.UUID]
+			if job.Type == JobTypeScheduled {
+				delete(n.failing, e.UUID)
+			}
+			n.lock.Unlock()
+			if failing && job.Type == JobTypeScheduled && rule.OnRecovery {
+				n.notify(job, rule, cfg, NotifyRecovery, e)
+			}
+		}
+	case EventJobGaveUp:
+		n.lock.Lock()
+		n.failing[e.UUID] = true
+		n.lock.Unlock()
+		if rule.OnGiveUp {
+			n.notify(job, rule, cfg, NotifyGiveUp, e)
+		}
+	}
+}
+
+func (n *notifier) after(key string, d time.Duration, fn func()) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.timers[key] = time.AfterFunc(d, func() {
+		n.lock.Lock()
+		_, ok := n.timers[key]
+		delete(n.timers, key)
+		n.lock.Unlock()
+		if ok {
+			fn()
+		}
+	})
+}
+
+func (n *notifier) stopTimers(runID string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, key := range []string{runID + "/recovery", runID + "/long"} {
+		if t, ok := n.timers[key]; ok {
+			t.Stop()
+			delete(n.timers, key)
+		}
+	}
+}
+
+// allow 去重与限流，允许发送时记录本次发送
+func (n *notifier) allow(cfg *NotifyConfig, key string, now time.Time) bool {
+	dedup := time.Duration(cfg.DedupSeconds) * time.Second
+	if cfg.DedupSeconds == 0 {
+		dedup = defaultNotifyDedup * time.Second
+	}
+	maxPerHour := cfg.MaxPerHour
+	if maxPerHour == 0 {
+		maxPerHour = defaultNotifyMaxPerHour
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if last, ok := n.sent[key]; ok && dedup > 0 && now.Sub(last) < dedup {
+		return false
+	}
+	recent := n.history[:0]
+	for _, t := range n.history {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	n.history = recent
+	if maxPerHour > 0 && len(n.history) >= maxPerHour {
+		n.m.log().Warn("通知过多，已丢弃", "key", key, "maxPerHour", maxPerHour)
+		return false
+	}
+	n.sent[key] = now
+	n.history = append(n.history, now)
+	return true
+}
+
+func (n *notifier) notify(job *Job, rule *NotifyRule, cfg *NotifyConfig, kind string, e Event) {
+	now := n.m.now()
+	if !n.allow(cfg, job.UUID+"/"+kind, now) {
+		return
+	}
+	data := NotifyData{
+		Kind:     kind,
+		Title:    notifyTitles[kind],
+		JobName:  job.JobName,
+		UUID:     job.UUID,
+		RunID:    e.RunID,
+		Reason:   e.Reason,
+		Message:  e.Message,
+		Duration: e.Duration,
+		Time:     now,
+	}
+	data.Host, _ = os.Hostname()
+	if e.ExitCode != nil {
+		data.ExitCode = strconv.Itoa(*e.ExitCode)
+	}
+	lines := rule.LogLines
+	if lines == 0 {
+		lines = defaultNotifyLogLines
+	}
+	if logPath := job.getRuntimeLogPath(); lines > 0 && logPath != "" {
+		data.LogTail = tailLines(logPath, lines)
+	}
+	msg, err := renderNotify(rule.Template, data)
+	if err != nil {
+		n.m.log().Error("通知模板错误", "jobName", job.JobName, "err", err)
+		return
+	}
+	msg.ExitCode = e.ExitCode
+	for _, c := range cfg.Channels {
+		if len(rule.Channels) > 0 && !slices.Contains(rule.Channels, c.Name) {
+			continue
+		}
+		go func(c notify.Channel) {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+			if err := n.send(ctx, c, msg); err != nil {
+				n.m.log().Error("发送通知失败", "channel", c.Name, "jobName", job.JobName, "err", err)
+			}
+		}(c)
+	}
+}
+
+func renderNotify(tpl string, data NotifyData) (notify.Message, error) {
+	if tpl == "" {
+		tpl = defaultNotifyTemplate
+	}
+	t, err := template.New("notify").Parse(tpl)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return notify.Message{}, err
+	}
+	return notify.Message{
+		Title:   fmt.Sprintf("[rooster] %v %v", data.JobName, data.Title),
+		Text:    buf.String(),
+		Kind:    data.Kind,
+		JobName: data.JobName,
+		UUID:    data.UUID,
+		RunID:   data.RunID,
+		Host:    data.Host,
+		Time:    data.Time,
+	}, nil
+}
+
+// tailLines 读取文件最后 n 行，最多读取末尾 64KB
+func tailLines(path string, n int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	const maxTail = 64 * 1024
+	offset := st.Size() - maxTail
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return ""
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if offset > 0 && len(lines) > 1 {
+		lines = lines[1:] // 第一行可能不完整
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for i, line := range lines {
+		lines[i] = FormatLogLine(ParseLogLine(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestNotify 通过指定渠道发送一条测试通知，不受去重与限流影响
+func (m *Manager) TestNotify(channel string) error {
+	cfg := m.config.Config.Notify
+	if cfg != nil {
+		for _, c := range cfg.Channels {
+			if c.Name != channel {
+				continue
+			}
+			data := NotifyData{Kind: NotifyTest, Title: notifyTitles[NotifyTest], JobName: "rooster", Time: m.now(), Message: "这是一条测试通知"}
+			data.Host, _ = os.Hostname()
+			msg, err := renderNotify("", data)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+			return notify.Send(ctx, c, msg)
+		}
+	}
+	return errors.New("通知渠道不存在: " + channel)
+}
+
+// validateNotifyConfig 校验全局通知配置
+func validateNotifyConfig(r *ValidationResult, cfg *NotifyConfig) {
+	if cfg == nil {
+		return
+	}
+	names := map[string]bool{}
+	for i, c := range cfg.Channels {
+		field := fmt.Sprintf("config.notify.channels[%d]", i)
+		if err := c.Validate(); err != nil {
+			r.addError(field, "%v", err)
+		}
+		if names[c.Name] {
+			r.addError(field, "渠道名称重复: %v", c.Name)
+		}
+		names[c.Name] = true
+	}
+	if cfg.DedupSeconds < 0 || cfg.MaxPerHour < 0 {
+		r.addError("config.notify", "去重间隔与每小时上限不能为负数")
+	}
+	if cfg.Default != nil {
+		validateNotifyRule(r, "config.notify.default", *cfg.Default, cfg)
+	}
+}
+
+// validateNotifyRule 校验通知规则，引用的渠道需存在
+func validateNotifyRule(r *ValidationResult, prefix string, rule NotifyRule, cfg *NotifyConfig) {
+	for _, name := range rule.Channels {
+		found := false
+		if cfg != nil {
+			for _, c := range cfg.Channels {
+				if c.Name == name {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			r.addError(prefix+".channels", "通知渠道不存在: %v", name)
+		}
+	}
+	if rule.LongRunning < 0 {
+		r.addError(prefix+".longRunning", "超时时间不能为负数")
+	}
+	if rule.Template != "" {
+		if _, err := template.New("notify").Parse(rule.Template); err != nil {
+			r.addError(prefix+".template", "模板错误: %v", err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package jobmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/notify"
+)
+
+type sentMessages struct {
+	lock sync.Mutex
+	msgs []notify.Message
+	done chan struct{}
+}
+
+func (s *sentMessages) send(_ context.Context, _ notify.Channel, msg notify.Message) error {
+	s.lock.Lock()
+	s.msgs = append(s.msgs, msg)
+	s.lock.Unlock()
+	s.done <- struct{}{}
+	return nil
+}
+
+func (s *sentMessages) wait(t *testing.T) notify.Message {
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not sent")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.msgs[len(s.msgs)-1]
+}
+
+func (s *sentMessages) none(t *testing.T) {
+	select {
+	case <-s.done:
+		t.Fatalf("unexpected notification: %+v", s.msgs[len(s.msgs)-1])
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierRules(t *testing.T) {
+	m := createTestManager()
+	logPath := filepath.Join(t.TempDir(), "job_log.txt")
+	if err := os.WriteFile(logPath, []byte("line1\nline2\npanic: boom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.config.Config.Notify = &NotifyConfig{
+		Channels:   []notify.Channel{{Name: "hook", Type: notify.TypeWebhook, URL: "http://127.0.0.1/hook"}},
+		Default:    &NotifyRule{OnFailure: true, OnGiveUp: true, OnRecovery: true, LogLines: 2},
+		MaxPerHour: 3,
+	}
+	job := &Job{JobSpec: JobSpec{UUID: "u1", JobName: "cron", Type: JobTypeScheduled}}
+	job.confLock = &sync.Mutex{}
+	job.runtimeLogPath = logPath
+	m.config.AddJob(job)
+
+	sent := &sentMessages{done: make(chan struct{}, 10)}
+	n := newNotifier(m)
+	n.send = sent.send
+	code := 2
+	failed := Event{Type: EventJobExited, UUID: "u1", RunID: "r1", ExitCode: &code, Reason: ExitReasonFailed}
+
+	n.handle(failed)
+	msg := sent.wait(t)
+	if msg.Kind != NotifyFailure || !strings.Contains(msg.Title, "cron 运行失败") ||
+		!strings.Contains(msg.Text, "退出码: 2") || !strings.Contains(msg.Text, "line2\npanic: boom") || strings.Contains(msg.Text, "line1") {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	// 相同任务与类型在去重间隔内只发送一次
+	n.handle(failed)
+	sent.none(t)
+
+	ok := 0
+	n.handle(Event{Type: EventJobExited, UUID: "u1", RunID: "r2", ExitCode: &ok, Reason: ExitReasonExited})
+	if msg := sent.wait(t); msg.Kind != NotifyRecovery {
+		t.Fatalf("expected recovery, got %+v", msg)
+	}
+	// 已恢复后的成功不再通知
+	n.handle(Event{Type: EventJobExited, UUID: "u1", RunID: "r3", ExitCode: &ok, Reason: ExitReasonExited})
+	sent.none(t)
+
+	// 单任务规则覆盖默认规则
+	job.Notify = &NotifyRule{OnGiveUp: true, Template: "{{.JobName}}/{{.Kind}}"}
+	n.handle(Event{Type: EventJobGaveUp, UUID: "u1"})
+	if msg := sent.wait(t); msg.Text != "cron/give_up" {
+		t.Fatalf("unexpected template output: %q", msg.Text)
+	}
+
+	// 超过每小时上限后丢弃
+	other := &Job{JobSpec: JobSpec{UUID: "u2", JobName: "other", Type: JobTypeScheduled}}
+	other.confLock = &sync.Mutex{}
+	m.config.AddJob(other)
+	n.handle(Event{Type: EventJobExited, UUID: "u2", ExitCode: &code, Reason: ExitReasonFailed})
+	sent.none(t)
+}
+
+func TestNotifierLongRunning(t *testing.T) {
+	m := createTestManager()
+	m.config.Config.Notify = &NotifyConfig{Channels: []notify.Channel{{Name: "hook", Type: notify.TypeWebhook, URL: "http://127.0.0.1/hook"}}}
+	job := &Job{JobSpec: JobSpec{UUID: "u1", JobName: "slow", Type: JobTypeScheduled, Notify: &NotifyRule{LongRunning: 1}}}
+	job.confLock = &sync.Mutex{}
+	m.config.AddJob(job)
+	sent := &sentMessages{done: make(chan struct{}, 10)}
+	n := newNotifier(m)
+	n.send = sent.send
+
+	// 在超时前退出不通知
+	n.handle(Event{Type: EventJobStarted, UUID: "u1", RunID: "r1"})
+	n.handle(Event{Type: EventJobExited, UUID: "u1", RunID: "r1", Reason: ExitReasonExited})
+	n.handle(Event{Type: EventJobStarted, UUID: "u1", RunID: "r2"})
+	if msg := sent.wait(t); msg.Kind != NotifyLongRunning || msg.RunID != "r2" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	base := BaseConfig{Notify: &NotifyConfig{Channels: []notify.Channel{
+		{Name: "hook", Type: notify.TypeWebhook, URL: "http://127.0.0.1/hook"},
+		{Name: "hook", Type: "pager"},
+	}}}
+	r := newValidationResult()
+	validateNotifyConfig(&r, base.Notify)
+	if len(r.Errors) != 2 {
+		t.Fatalf("expected type and duplicate errors: %+v", r.Errors)
+	}
+	spec := JobSpec{JobName: "a", Type: JobTypeResident, BinPath: "echo", Notify: &NotifyRule{Channels: []string{"missing"}, Template: "{{"}}
+	fields := issueFields(ValidateJobSpec(spec, nil, base).Errors)
+	if !fields["notify.channels"] || !fields["notify.template"] {
+		t.Fatalf("expected notify errors: %v", fields)
+	}
+}
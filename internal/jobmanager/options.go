@@ -0,0 +1,108 @@
+package jobmanager
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Options 创建 Manager 的选项，零值使用 ~/.roosterTaskConfig 下的配置与日志目录
+type Options struct {
+	ConfigPath string           // 配置文件路径，格式由扩展名决定
+	Store      ConfigStore      // 自定义配置存储，指定后忽略 ConfigPath
+	LogDir     string           // 未指定输出目录的任务使用的日志目录，默认为配置文件所在目录下的 log
+	Clock      func() time.Time // 时间来源，默认 time.Now
+	Logger     *slog.Logger     // 默认 slog.Default()
+	EventLimit int              // 保留的最近事件数量，用于订阅者断线后补发，默认 1000
+	Headless   bool             // 没有控制台时设置，任务输出不转发到标准输出
+}
+
+// New 按选项创建一个独立的 Manager 并读取配置，不会启动任何任务，也不会设置 DefaultManager。
+// 配置不存在时从空配置开始，第一次修改时写入。同一进程中可以创建多个互不影响的 Manager
+func New(opts Options) (*Manager, error) {
+	store := opts.Store
+	if store == nil {
+		store = &FileStore{Path: opts.ConfigPath, Logger: opts.Logger}
+	}
+	logDir := opts.LogDir
+	if logDir == "" && opts.Store == nil && opts.ConfigPath != "" {
+		logDir = filepath.Join(filepath.Dir(opts.ConfigPath), "log")
+	}
+	config, err := store.Load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	m := newManagerWithConfig(config)
+	m.store = store
+	m.logDir = logDir
+	m.clock = opts.Clock
+	m.logger = opts.Logger
+	m.headless = opts.Headless
+	m.startTime = m.now()
+	m.events = NewEventBus(opts.EventLimit)
+	m.events.clock = m.now
+	for _, job := range m.config.TaskList {
+		job.confLock = &sync.Mutex{}
+		job.runOnceLock = &sync.Mutex{}
+	}
+	return m, nil
+}
+
+func (m *Manager) now() time.Time {
+	if m.clock != nil {
+		return m.clock()
+	}
+	return time.Now()
+}
+
+func (m *Manager) log() *slog.Logger {
+	if m.logger != nil {
+		return m.logger
+	}
+	return slog.Default()
+}
+
+// defaultLogDir 未指定输出目录的任务使用的日志目录，不存在时创建
+func (m *Manager) defaultLogDir() (string, error) {
+	if m.logDir == "" {
+		return getLogDir()
+	}
+	if err := os.MkdirAll(m.logDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	return m.logDir, nil
+}
+
+// configStore 返回配置存储，未指定时使用默认位置的配置文件
+func (m *Manager) configStore() ConfigStore {
+	if m.store == nil {
+		return &FileStore{Logger: m.logger}
+	}
+	return m.store
+}
+
+// configFilePath 返回配置文件路径，配置不是保存在文件中时返回错误
+func (m *Manager) configFilePath() (string, error) {
+	fs, ok := m.configStore().(*FileStore)
+	if !ok {
+		return "", errors.New("当前配置存储不是文件，不支持该操作")
+	}
+	return fs.path()
+}
+
+// configDir 配置文件所在目录，用于 ${CONFIG_DIR}，配置不是保存在文件中时为空
+func (m *Manager) configDir() string {
+	configPath, err := m.configFilePath()
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(configPath)
+}
+
+// ConfigDir 配置文件所在目录，dashboard 的令牌等文件保存在这里，配置不是保存在文件中时为空
+func (m *Manager) ConfigDir() string {
+	return m.configDir()
+}
@@ -0,0 +1,81 @@
+package jobmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew_IndependentFileInstances(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	a, err := New(Options{ConfigPath: filepath.Join(dirA, "jobs.yaml")})
+	if err != nil {
+		t.Fatalf("new a: %v", err)
+	}
+	b, err := New(Options{ConfigPath: filepath.Join(dirB, "jobs.json")})
+	if err != nil {
+		t.Fatalf("new b: %v", err)
+	}
+
+	if err := a.SaveTask(JobStatusShow{JobName: "only-a", Type: int(JobTypeScheduled), Spec: "* * * * *", BinPath: "echo a"}); err != nil {
+		t.Fatalf("save a: %v", err)
+	}
+	if len(a.JobList()) != 1 || len(b.JobList()) != 0 {
+		t.Fatalf("instances should not share jobs: a=%v b=%v", len(a.JobList()), len(b.JobList()))
+	}
+	if a.JobList()[0].Options.OutputPath != filepath.Join(dirA, "log") {
+		t.Fatalf("default log dir should be next to the config: %v", a.JobList()[0].Options.OutputPath)
+	}
+	if _, err := os.Stat(filepath.Join(dirA, "jobs.yaml")); err != nil {
+		t.Fatalf("config a not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dirB, "jobs.json")); !os.IsNotExist(err) {
+		t.Fatalf("config b should not be written: %v", err)
+	}
+
+	// 重新打开读取到相同的任务
+	again, err := New(Options{ConfigPath: filepath.Join(dirA, "jobs.yaml")})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if list := again.JobList(); len(list) != 1 || list[0].JobName != "only-a" {
+		t.Fatalf("unexpected reopened jobs: %+v", list)
+	}
+	if versions, err := again.ListConfigVersions(); err != nil || len(versions) == 0 {
+		t.Fatalf("expected history next to the config: %v %v", versions, err)
+	}
+}
+
+func TestNew_MemoryStoreAndClock(t *testing.T) {
+	logDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store := NewMemoryStore(JobConfig{Config: BaseConfig{Vars: map[string]string{"ENV": "prod"}}})
+	m, err := New(Options{Store: store, LogDir: logDir, Clock: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	now = now.Add(90 * time.Second)
+	if m.GetRunTime() != 90*time.Second || !m.GetStartTime().Equal(start) {
+		t.Fatalf("clock not used: %v %v", m.GetStartTime(), m.GetRunTime())
+	}
+
+	if err := m.SaveTask(JobStatusShow{JobName: "deploy", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo ${ENV} ${DATE}"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	saved, err := store.Load()
+	if err != nil || len(saved.TaskList) != 1 || saved.TaskList[0].Options.OutputPath != logDir {
+		t.Fatalf("memory store not updated: %+v %v", saved, err)
+	}
+	p := m.PreviewJobSpec(saved.TaskList[0].JobSpec, time.Time{})
+	if p.BinPath != "echo prod 2024-01-01" {
+		t.Fatalf("unexpected preview: %v", p.BinPath)
+	}
+	if _, ok := p.Vars[VarConfigDir]; ok {
+		t.Fatalf("CONFIG_DIR should be undefined for a memory store")
+	}
+	if _, err := m.ListConfigVersions(); err == nil {
+		t.Fatalf("config history requires a file store")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package jobmanager
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// startPTY 在新的会话中启动命令并以 PTY 作为控制终端。会话首进程同时是进程组长，
+// KillProcessGroup 仍能终止整个进程组
+func startPTY(cmd *exec.Cmd, cols, rows uint16) (*os.File, error) {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	// Setsid 之后不能再设置 Setpgid
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	return pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
+func resizePTY(f *os.File, cols, rows uint16) error {
+	return pty.Setsize(f, &pty.Winsize{Cols: cols, Rows: rows})
+}
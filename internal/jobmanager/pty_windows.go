@@ -0,0 +1,19 @@
+//go:build windows
+
+package jobmanager
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errPTYUnsupported = errors.New("Windows 不支持 PTY 模式")
+
+func startPTY(cmd *exec.Cmd, cols, rows uint16) (*os.File, error) {
+	return nil, errPTYUnsupported
+}
+
+func resizePTY(f *os.File, cols, rows uint16) error {
+	return errPTYUnsupported
+}
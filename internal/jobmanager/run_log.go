@@ -0,0 +1,200 @@
+package jobmanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 未指定 KeepRuns 时每个任务保留的运行日志数量
+const defaultKeepRuns = 50
+
+const (
+	runLogExt        = ".log"
+	runLogFooterMark = "===== rooster run end ====="
+)
+
+// RunLog 单次运行的日志文件
+type RunLog struct {
+	RunID    string    `json:"runId"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	Finished bool      `json:"finished"`           // 日志末尾已写入运行结果
+	ExitCode *int      `json:"exitCode,omitempty"` // 运行中或异常中断时为空
+}
+
+var (
+	runIDPattern       = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+	runLogExitCodeLine = regexp.MustCompile(`(?m)^exit code:\s+(-?\d+)\s*$`)
+)
+
+// runLogDir 单次运行日志所在的目录 <输出目录>/<任务名>
+func runLogDir(defaultDir, jobName string, options RunOptions) (string, error) {
+	logPath, err := resolveLogPath(defaultDir, jobName, options)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(logPath), jobName), nil
+}
+
+func writeRunLogHeader(w io.Writer, jobName string, run RunInfo, start time.Time, args []string, dir string) {
+	fmt.Fprintf(w, "===== rooster run %v =====\n", run.RunID)
+	fmt.Fprintf(w, "job:       %v\n", jobName)
+	fmt.Fprintf(w, "start:     %v\n", start.Format(time.RFC3339))
+	fmt.Fprintf(w, "command:   %v\n", strings.Join(args, " "))
+	if dir != "" {
+		fmt.Fprintf(w, "dir:       %v\n", dir)
+	}
+	fmt.Fprintln(w, "=====")
+}
+
+func writeRunLogFooter(w io.Writer, result ExecutionResult, reason string) {
+	fmt.Fprintln(w, runLogFooterMark)
+	fmt.Fprintf(w, "end:       %v\n", result.EndTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "exit code: %v\n", result.ExitCode)
+	fmt.Fprintf(w, "reason:    %v\n", reason)
+	fmt.Fprintf(w, "duration:  %v\n", result.Duration.Round(time.Millisecond))
+	if result.Error != nil {
+		fmt.Fprintf(w, "error:     %v\n", result.Error)
+	}
+}
+
+// readRunLogResult 从日志尾部读取运行结果
+func readRunLogResult(path string, size int64) (bool, *int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, nil
+	}
+	defer f.Close()
+	const tailSize = 4096
+	offset := size - tailSize
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, size-offset)
+	if _, err = f.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	// 日志可能是 text 或 json 格式，先还原每行的内容
+	lines := strings.Split(string(buf), "\n")
+	for i, line := range lines {
+		lines[i] = ParseLogLine(line).Line
+	}
+	text := strings.Join(lines, "\n")
+	idx := strings.LastIndex(text, runLogFooterMark)
+	if idx < 0 {
+		return false, nil
+	}
+	match := runLogExitCodeLine.FindStringSubmatch(text[idx:])
+	if match == nil {
+		return true, nil
+	}
+	code, err := strconv.Atoi(match[1])
+	if err != nil {
+		return true, nil
+	}
+	return true, &code
+}
+
+// listRunLogs 返回目录下的运行日志，最新的在前
+func listRunLogs(dir string) ([]RunLog, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []RunLog{}, nil
+		}
+		return nil, err
+	}
+	logs := []RunLog{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, runLogExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, RunLog{
+			RunID:   strings.TrimSuffix(name, runLogExt),
+			Path:    filepath.Join(dir, name),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	// 运行 ID 以触发时间开头，按名称倒序即按时间倒序
+	sort.Slice(logs, func(i, j int) bool { return logs[i].RunID > logs[j].RunID })
+	return logs, nil
+}
+
+// pruneRunLogs 只保留最近 keepRuns 个运行日志，keepDays 大于 0 时同时删除更早的日志
+func pruneRunLogs(dir string, keepRuns, keepDays int, now time.Time) error {
+	if keepRuns <= 0 {
+		keepRuns = defaultKeepRuns
+	}
+	logs, err := listRunLogs(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, l := range logs {
+		expired := keepDays > 0 && now.Sub(l.ModTime) > time.Duration(keepDays)*24*time.Hour
+		if i < keepRuns && !expired {
+			continue
+		}
+		if err := os.Remove(l.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// jobRunLogDir 任务的运行日志目录
+func (m *Manager) jobRunLogDir(jobId string) (string, error) {
+	job := m.getJobByJobId(jobId)
+	if job == nil {
+		return "", newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	expanded := m.expandJobSpec(job.JobSpec, RunInfo{})
+	return runLogDir(m.logDir, expanded.JobName, expanded.Options)
+}
+
+// ListRunLogs 列出任务的单次运行日志，最新的在前。只有开启 perRunLog 的任务才会产生
+func (m *Manager) ListRunLogs(jobId string) ([]RunLog, error) {
+	dir, err := m.jobRunLogDir(jobId)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := listRunLogs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logs {
+		logs[i].Finished, logs[i].ExitCode = readRunLogResult(logs[i].Path, logs[i].Size)
+	}
+	return logs, nil
+}
+
+// RunLogPath 返回任务某次运行的日志文件路径
+func (m *Manager) RunLogPath(jobId, runID string) (string, error) {
+	if !runIDPattern.MatchString(runID) {
+		return "", fmt.Errorf("运行ID无效: %q", runID)
+	}
+	dir, err := m.jobRunLogDir(jobId)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(dir, runID+runLogExt)
+	if _, err := os.Stat(p); err != nil {
+		return "", fmt.Errorf("运行日志不存在: %v", runID)
+	}
+	return p, nil
+}
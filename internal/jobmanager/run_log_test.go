@@ -0,0 +1,102 @@
+package jobmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPerRunLog(t *testing.T) {
+	logDir := t.TempDir()
+	m, err := New(Options{Store: NewMemoryStore(JobConfig{}), LogDir: logDir})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	err = m.SaveTask(JobStatusShow{
+		JobName: "nightly", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo hello",
+		Options: RunOptions{PerRunLog: true, KeepRuns: 2},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	job := m.config.TaskList[0]
+
+	fire := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var runIDs []string
+	for i := 0; i < 3; i++ {
+		run := RunInfo{FireTime: fire.Add(time.Duration(i) * time.Minute)}
+		run.RunID = newRunID(run.FireTime)
+		runIDs = append(runIDs, run.RunID)
+		result := m.newExecutor().Execute(context.Background(), job, job.JobSpec, run, nil)
+		if result.ExitCode != 0 {
+			t.Fatalf("run %d failed: %+v", i, result)
+		}
+		if job.runtimeLogPath != filepath.Join(logDir, "nightly", run.RunID+".log") {
+			t.Fatalf("runtime log should point at the current run: %v", job.runtimeLogPath)
+		}
+	}
+
+	runs, err := m.ListRunLogs(job.UUID)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunID != runIDs[2] || runs[1].RunID != runIDs[1] {
+		t.Fatalf("expected the two newest runs, got %+v", runs)
+	}
+	if !runs[0].Finished || runs[0].ExitCode == nil || *runs[0].ExitCode != 0 {
+		t.Fatalf("exit code not read from footer: %+v", runs[0])
+	}
+
+	p, err := m.RunLogPath(job.UUID, runIDs[2])
+	if err != nil {
+		t.Fatalf("run log path: %v", err)
+	}
+	data, _ := os.ReadFile(p)
+	for _, want := range []string{"===== rooster run " + runIDs[2], "command:", "hello\n", runLogFooterMark, "exit code: 0", "reason:    exited"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("run log missing %q:\n%s", want, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(logDir, "nightly_log.txt")); !os.IsNotExist(err) {
+		t.Fatalf("shared log should not be written in per-run mode: %v", err)
+	}
+
+	for _, bad := range []string{"", "../nightly_log", "a/b", runIDs[0]} {
+		if _, err := m.RunLogPath(job.UUID, bad); err == nil {
+			t.Fatalf("expected error for run id %q", bad)
+		}
+	}
+	if _, err := m.ListRunLogs("missing"); err == nil {
+		t.Fatalf("expected error for unknown job")
+	}
+}
+
+func TestPruneRunLogsByAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, age := range []int{0, 2, 10} {
+		p := filepath.Join(dir, newRunID(now.Add(-time.Duration(i)*time.Hour))+runLogExt)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-time.Duration(age) * 24 * time.Hour)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0o644)
+
+	if err := pruneRunLogs(dir, 0, 7, now); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	logs, _ := listRunLogs(dir)
+	if len(logs) != 2 {
+		t.Fatalf("expected logs older than 7 days removed, got %+v", logs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Fatalf("non-log files should be kept: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package jobmanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// JobSelector 按 UUID、分组或标签选择任务，多个条件同时生效
+type JobSelector struct {
+	All   bool     `json:"all"`   // 选择全部任务
+	IDs   []string `json:"ids"`   // UUID 或任务名，命中任意一个即可
+	Group string   `json:"group"` // 分组
+	Tags  []string `json:"tags"`  // 需同时包含全部标签
+}
+
+// Empty 是否未指定任何条件
+func (s JobSelector) Empty() bool {
+	return !s.All && len(s.IDs) == 0 && s.Group == "" && len(s.Tags) == 0
+}
+
+// Match 判断任务是否满足选择条件，未指定条件时匹配全部
+func (s JobSelector) Match(spec JobSpec) bool {
+	if len(s.IDs) > 0 && !slices.Contains(s.IDs, spec.UUID) && !slices.Contains(s.IDs, spec.JobName) {
+		return false
+	}
+	if s.Group != "" && spec.Group != s.Group {
+		return false
+	}
+	for _, tag := range s.Tags {
+		if !slices.Contains(spec.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manager) selectJobs(sel JobSelector) []*Job {
+	var r []*Job
+	for _, job := range m.config.TaskList {
+		if sel.Match(job.JobSpec) {
+			r = append(r, job)
+		}
+	}
+	return r
+}
+
+// JobListBySelector 返回满足条件的任务状态
+func (m *Manager) JobListBySelector(sel JobSelector) []JobStatusShow {
+	var jobNameList []JobStatusShow
+	for _, job := range m.selectJobs(sel) {
+		jobNameList = append(jobNameList, job.ToStatusShow())
+	}
+	return jobNameList
+}
+
+// JobLabels 汇总所有任务使用的分组与标签
+type JobLabels struct {
+	Groups []string `json:"groups"`
+	Tags   []string `json:"tags"`
+}
+
+func (m *Manager) Labels() JobLabels {
+	groups := map[string]bool{}
+	tags := map[string]bool{}
+	for _, job := range m.config.TaskList {
+		if job.Group != "" {
+			groups[job.Group] = true
+		}
+		for _, tag := range job.Tags {
+			tags[tag] = true
+		}
+	}
+	labels := JobLabels{Groups: []string{}, Tags: []string{}}
+	for g := range groups {
+		labels.Groups = append(labels.Groups, g)
+	}
+	for t := range tags {
+		labels.Tags = append(labels.Tags, t)
+	}
+	sort.Strings(labels.Groups)
+	sort.Strings(labels.Tags)
+	return labels
+}
+
+// 批量操作
+const (
+	BulkStart   = "start"   // 启动常驻任务
+	BulkStop    = "stop"    // 停止常驻任务
+	BulkEnable  = "enable"  // 开启任务：常驻任务启动，定时任务注册
+	BulkDisable = "disable" // 关闭任务：常驻任务停止，定时任务注销
+	BulkRun     = "run"     // 立即运行一次定时任务
+	BulkRotate  = "rotate"  // 立即轮转日志文件
+)
+
+// BulkResult 批量操作中单个任务的结果
+type BulkResult struct {
+	UUID    string `json:"uuid"`
+	JobName string `json:"jobName"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// BulkAction 对所有满足条件的任务执行同一操作，返回每个任务的结果
+func (m *Manager) BulkAction(action string, sel JobSelector) ([]BulkResult, error) {
+	switch action {
+	case BulkStart, BulkStop, BulkEnable, BulkDisable, BulkRun, BulkRotate:
+	default:
+		return nil, fmt.Errorf("未知的批量操作: %v", action)
+	}
+	if sel.Empty() {
+		return nil, errors.New("选择条件不能为空，如需操作全部任务请指定 all")
+	}
+	results := []BulkResult{}
+	for _, job := range m.selectJobs(sel) {
+		err := m.applyAction(action, job)
+		r := BulkResult{UUID: job.UUID, JobName: job.JobName, OK: err == nil, Message: "success"}
+		if err != nil {
+			r.Message = err.Error()
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// JobAction 对单个任务执行与批量操作相同的 action，jobId 为任务的 UUID
+func (m *Manager) JobAction(action, jobId string) error {
+	job := m.getJobByJobId(jobId)
+	if job == nil {
+		return newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	return m.applyAction(action, job)
+}
+
+func (m *Manager) applyAction(action string, job *Job) error {
+	resident := job.Type == JobTypeResident
+	switch action {
+	case BulkStart:
+		if !resident {
+			return newJobError(ErrUnsupported, "定时任务不支持 start，请使用 enable 或 run")
+		}
+		return m.JobRunResidentTask(job.UUID)
+	case BulkStop:
+		if !resident {
+			return newJobError(ErrUnsupported, "定时任务不支持 stop，请使用 disable")
+		}
+		return m.JobStopResidentTask(job.UUID)
+	case BulkEnable, BulkDisable:
+		run := action == BulkEnable
+		if resident {
+			if run {
+				return m.JobRunResidentTask(job.UUID)
+			}
+			return m.JobStopResidentTask(job.UUID)
+		}
+		return m.OpenCloseTask(job.UUID, run)
+	case BulkRun:
+		if resident {
+			return newJobError(ErrUnsupported, "常驻任务不支持 run，请使用 start")
+		}
+		return m.RunTask(job.UUID)
+	case BulkRotate:
+		return m.RotateLog(job.UUID)
+	}
+	return newJobError(ErrUnsupported, fmt.Sprintf("未知的批量操作: %v", action))
+}
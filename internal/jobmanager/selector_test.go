@@ -0,0 +1,112 @@
+package jobmanager
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJobSelectorMatch(t *testing.T) {
+	spec := JobSpec{UUID: "u1", JobName: "api", Group: "shop", Tags: []string{"web", "prod"}}
+	cases := []struct {
+		sel  JobSelector
+		want bool
+	}{
+		{JobSelector{}, true},
+		{JobSelector{Group: "shop"}, true},
+		{JobSelector{Group: "blog"}, false},
+		{JobSelector{Tags: []string{"web"}}, true},
+		{JobSelector{Tags: []string{"web", "dev"}}, false},
+		{JobSelector{IDs: []string{"api"}}, true},
+		{JobSelector{IDs: []string{"u1"}, Group: "blog"}, false},
+	}
+	for _, c := range cases {
+		if got := c.sel.Match(spec); got != c.want {
+			t.Errorf("%+v Match = %v, want %v", c.sel, got, c.want)
+		}
+	}
+}
+
+func TestBulkAction_PerJobResults(t *testing.T) {
+	m := createTestManager()
+	_, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	jobs := []*Job{
+		{JobSpec: JobSpec{UUID: "s1", JobName: "s1", Type: JobTypeScheduled, Spec: "* * * * *", BinPath: "echo 1", Tags: []string{"batch"}}},
+		{JobSpec: JobSpec{UUID: "s2", JobName: "s2", Type: JobTypeScheduled, Spec: "* * * * *", BinPath: "echo 2", Tags: []string{"batch"}}},
+		{JobSpec: JobSpec{UUID: "r1", JobName: "r1", Type: JobTypeResident, BinPath: "sleep 1", Tags: []string{"web"}}},
+	}
+	for _, j := range jobs {
+		m.ConfigInit(j)
+		m.config.AddJob(j)
+	}
+
+	if _, err := m.BulkAction(BulkEnable, JobSelector{}); err == nil {
+		t.Fatal("empty selector should be rejected")
+	}
+	if _, err := m.BulkAction("explode", JobSelector{All: true}); err == nil {
+		t.Fatal("unknown action should be rejected")
+	}
+
+	results, err := m.BulkAction(BulkEnable, JobSelector{Tags: []string{"batch"}})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("unexpected results %+v err %v", results, err)
+	}
+	for _, r := range results {
+		if !r.OK {
+			t.Fatalf("enable failed: %+v", r)
+		}
+	}
+	if !jobs[0].Run || jobs[0].entityId == 0 {
+		t.Fatalf("scheduled job not registered")
+	}
+
+	// run 对常驻任务不适用，应返回该任务的失败结果而不是整体失败
+	results, err = m.BulkAction(BulkRun, JobSelector{All: true})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("unexpected results %+v err %v", results, err)
+	}
+	if results[2].OK || results[2].UUID != "r1" {
+		t.Fatalf("expected failure for resident job: %+v", results[2])
+	}
+
+	results, _ = m.BulkAction(BulkDisable, JobSelector{Tags: []string{"batch"}})
+	for _, r := range results {
+		if !r.OK {
+			t.Fatalf("disable failed: %+v", r)
+		}
+	}
+	if jobs[1].Run || jobs[1].entityId != 0 {
+		t.Fatalf("scheduled job not unregistered")
+	}
+	// 等待后台运行结束，再恢复 userHomeDirFn
+	for _, j := range jobs[:2] {
+		j.runOnceLock.Lock()
+		j.runOnceLock.Unlock()
+	}
+}
+
+func TestJobAction_ErrorKinds(t *testing.T) {
+	m := createTestManager()
+	_, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	job := &Job{JobSpec: JobSpec{UUID: "s1", JobName: "s1", Type: JobTypeScheduled, Spec: "* * * * *", BinPath: "echo 1"}}
+	m.ConfigInit(job)
+	m.config.AddJob(job)
+
+	if err := m.JobAction(BulkEnable, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+	if err := m.JobAction(BulkStart, "s1"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if err := m.JobAction(BulkEnable, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	defer m.OpenCloseTask("s1", false)
+	err := m.RemoveTask(job.ToStatusShow())
+	if !errors.Is(err, ErrJobEnabled) || err.Error() != "任务处于开启状态不允许修改,如需修改请先关闭" {
+		t.Fatalf("expected ErrJobEnabled with the original message, got %v", err)
+	}
+}
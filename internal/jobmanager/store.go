@@ -0,0 +1,112 @@
+package jobmanager
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// ConfigStore 任务配置的读写方式
+type ConfigStore interface {
+	// Load 读取配置，配置不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+	Load() (JobConfig, error)
+	// Save 保存配置
+	Save(config JobConfig) error
+}
+
+// FileStore 将配置保存在本地文件中，格式由扩展名决定，并在同目录的 history 下记录历史版本
+type FileStore struct {
+	Path   string       // 配置文件路径，为空时使用 ~/.roosterTaskConfig 下的配置文件
+	Logger *slog.Logger // 为空时使用 slog.Default()
+}
+
+func (s *FileStore) path() (string, error) {
+	if s.Path != "" {
+		return s.Path, nil
+	}
+	return getConfigPath()
+}
+
+func (s *FileStore) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+// Load 读取配置文件。文件存在但为空或无法解析时（例如写入中途崩溃），只尝试从历史版本恢复
+func (s *FileStore) Load() (JobConfig, error) {
+	configPath, err := s.path()
+	if err != nil {
+		return JobConfig{}, err
+	}
+	format := configFormatOf(configPath)
+	fileData, err := os.ReadFile(configPath)
+	if err != nil {
+		return JobConfig{}, err
+	}
+	config, err := decodeConfig(fileData, format)
+	if err == nil {
+		return config, nil
+	}
+	s.logger().Error("配置文件解析失败", "path", configPath, "err", err)
+	if fileData, err = recoverBrokenConfig(configPath, fileData, s.logger()); err != nil {
+		return JobConfig{}, err
+	}
+	return decodeConfig(fileData, format)
+}
+
+// Save 按文件格式写入配置，内容没有变化时不重写，保留手工编辑的格式与注释
+func (s *FileStore) Save(config JobConfig) error {
+	configPath, err := s.path()
+	if err != nil {
+		return err
+	}
+	format := configFormatOf(configPath)
+	if old, err := os.ReadFile(configPath); err == nil {
+		if oldConfig, err := decodeConfig(old, format); err == nil && sameConfig(oldConfig, config) {
+			return nil
+		}
+	}
+	data, err := encodeConfig(config, format)
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(configPath, data, s.logger())
+}
+
+// MemoryStore 将配置保存在内存中，适合嵌入使用与测试
+type MemoryStore struct {
+	lock sync.Mutex
+	data []byte
+}
+
+// NewMemoryStore 创建以 config 为初始内容的内存存储
+func NewMemoryStore(config JobConfig) *MemoryStore {
+	s := &MemoryStore{}
+	_ = s.Save(config)
+	return s
+}
+
+func (s *MemoryStore) Load() (JobConfig, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var config JobConfig
+	if s.data == nil {
+		return config, os.ErrNotExist
+	}
+	err := json.Unmarshal(s.data, &config)
+	return config, err
+}
+
+func (s *MemoryStore) Save(config JobConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data = data
+	return nil
+}
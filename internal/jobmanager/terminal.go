@@ -0,0 +1,226 @@
+package jobmanager
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// 终端的默认大小、保留的输出与每个连接缓冲的输出块数
+const (
+	defaultTerminalCols = 120
+	defaultTerminalRows = 30
+	terminalScrollback  = 256 * 1024
+	terminalClientQueue = 256
+)
+
+var (
+	errTerminalClosed = errors.New("任务已退出")
+	errNotWriter      = errors.New("只读连接不能写入")
+)
+
+// Terminal 运行在 PTY 中的任务的终端。输出同时写入日志、保留最近的内容并转发给所有连接；
+// 可以有多个只读连接，最多一个可写入的连接
+type Terminal struct {
+	lock       sync.Mutex
+	pty        *os.File
+	scrollback []byte
+	clients    map[*TerminalClient]struct{}
+	writer     *TerminalClient
+	cols, rows uint16
+	closed     bool
+	done       chan struct{} // 输出读取结束后关闭
+}
+
+// TerminalClient 连接到终端的一个客户端
+type TerminalClient struct {
+	term      *Terminal
+	ch        chan []byte
+	write     bool
+	closeOnce sync.Once
+}
+
+func newTerminal() *Terminal {
+	return &Terminal{
+		clients: map[*TerminalClient]struct{}{},
+		cols:    defaultTerminalCols,
+		rows:    defaultTerminalRows,
+		done:    make(chan struct{}),
+	}
+}
+
+// start 在 PTY 中启动命令，输出写入 out（可为空）
+func (t *Terminal) start(cmd *exec.Cmd, out io.Writer) error {
+	ptmx, err := startPTY(cmd, t.cols, t.rows)
+	if err != nil {
+		return err
+	}
+	t.pty = ptmx
+	go t.pump(out)
+	return nil
+}
+
+// pump 读取终端输出直到子进程关闭终端
+func (t *Terminal) pump(out io.Writer) {
+	defer close(t.done)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := t.pty.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			if out != nil {
+				_, _ = out.Write(chunk)
+			}
+			t.broadcast(chunk)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *Terminal) broadcast(chunk []byte) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.scrollback = append(t.scrollback, chunk...)
+	if over := len(t.scrollback) - terminalScrollback; over > 0 {
+		t.scrollback = append(t.scrollback[:0], t.scrollback[over:]...)
+	}
+	for c := range t.clients {
+		select {
+		case c.ch <- chunk:
+		default:
+			// 跟不上输出的连接被断开，不阻塞任务
+			t.detach(c)
+		}
+	}
+}
+
+// wait 等待输出读取结束，子进程的后代仍占用终端时最多等待 timeout，之后关闭终端并断开所有连接
+func (t *Terminal) wait(timeout time.Duration) {
+	select {
+	case <-t.done:
+	case <-time.After(timeout):
+	}
+	_ = t.pty.Close()
+	<-t.done
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.closed = true
+	for c := range t.clients {
+		t.detach(c)
+	}
+}
+
+// detach 需持有锁
+func (t *Terminal) detach(c *TerminalClient) {
+	if _, ok := t.clients[c]; !ok {
+		return
+	}
+	delete(t.clients, c)
+	if t.writer == c {
+		t.writer = nil
+	}
+	close(c.ch)
+}
+
+// Attach 连接到终端，返回最近的输出。write 为 true 时请求写入权限，已有写入者时返回错误
+func (t *Terminal) Attach(write bool) (*TerminalClient, []byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return nil, nil, errTerminalClosed
+	}
+	if write && t.writer != nil {
+		return nil, nil, errors.New("已有其他连接在写入，请以只读方式连接")
+	}
+	c := &TerminalClient{term: t, ch: make(chan []byte, terminalClientQueue), write: write}
+	t.clients[c] = struct{}{}
+	if write {
+		t.writer = c
+	}
+	return c, append([]byte(nil), t.scrollback...), nil
+}
+
+// Size 当前的终端大小
+func (c *TerminalClient) Size() (cols, rows uint16) {
+	t := c.term
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.cols, t.rows
+}
+
+// Output 终端的输出，终端关闭或连接被断开后关闭
+func (c *TerminalClient) Output() <-chan []byte {
+	return c.ch
+}
+
+// Writable 是否为可写入的连接
+func (c *TerminalClient) Writable() bool {
+	return c.write
+}
+
+// Write 向终端输入，只有写入者可以调用
+func (c *TerminalClient) Write(p []byte) (int, error) {
+	if !c.write {
+		return 0, errNotWriter
+	}
+	t := c.term
+	t.lock.Lock()
+	closed := t.closed || t.writer != c
+	t.lock.Unlock()
+	if closed {
+		return 0, errTerminalClosed
+	}
+	return t.pty.Write(p)
+}
+
+// Resize 调整终端大小，只有写入者可以调用
+func (c *TerminalClient) Resize(cols, rows uint16) error {
+	if !c.write {
+		return errNotWriter
+	}
+	if cols == 0 || rows == 0 {
+		return errors.New("终端大小无效")
+	}
+	t := c.term
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed || t.writer != c {
+		return errTerminalClosed
+	}
+	if err := resizePTY(t.pty, cols, rows); err != nil {
+		return err
+	}
+	t.cols, t.rows = cols, rows
+	return nil
+}
+
+// Close 断开连接，写入者断开后其他连接可以获得写入权限
+func (c *TerminalClient) Close() {
+	c.closeOnce.Do(func() {
+		t := c.term
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.detach(c)
+	})
+}
+
+// AttachTerminal 连接到运行中的 PTY 任务的终端，返回连接与最近的输出
+func (m *Manager) AttachTerminal(jobId string, write bool) (*TerminalClient, []byte, error) {
+	job := m.getJobByJobId(jobId)
+	if job == nil {
+		return nil, nil, newJobError(ErrJobNotFound, "jobId不存在")
+	}
+	if !job.specSnapshot().Options.PTY {
+		return nil, nil, errors.New("任务未开启 PTY 模式")
+	}
+	t := job.getTerminal()
+	if t == nil {
+		return nil, nil, errors.New("任务未运行")
+	}
+	return t.Attach(write)
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+
+package jobmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTerminalAttach(t *testing.T) {
+	m, err := New(Options{Store: NewMemoryStore(JobConfig{}), LogDir: t.TempDir(), Headless: true})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	err = m.SaveTask(JobStatusShow{
+		JobName: "repl", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "read line; echo got:$line",
+		Options: RunOptions{PTY: true},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	job := m.config.TaskList[0]
+	if _, _, err := m.AttachTerminal(job.UUID, false); err == nil {
+		t.Fatalf("attach before start should fail")
+	}
+
+	done := make(chan ExecutionResult, 1)
+	go func() { done <- m.newExecutor().Execute(context.Background(), job, job.JobSpec, RunInfo{}, nil) }()
+	deadline := time.Now().Add(30 * time.Second)
+	for job.getTerminal() == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("terminal not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	writer, _, err := m.AttachTerminal(job.UUID, true)
+	if err != nil {
+		t.Fatalf("attach writer: %v", err)
+	}
+	if _, _, err := m.AttachTerminal(job.UUID, true); err == nil {
+		t.Fatalf("second writer should be rejected")
+	}
+	viewer, _, err := m.AttachTerminal(job.UUID, false)
+	if err != nil {
+		t.Fatalf("attach viewer: %v", err)
+	}
+	if _, err := viewer.Write([]byte("x")); err == nil {
+		t.Fatalf("viewer must not write")
+	}
+	if err := writer.Resize(80, 24); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	if cols, rows := viewer.Size(); cols != 80 || rows != 24 {
+		t.Fatalf("unexpected size %vx%v", cols, rows)
+	}
+	if _, err := writer.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// 只读连接也能收到输出，任务退出后输出关闭
+	var out strings.Builder
+	for chunk := range viewer.Output() {
+		out.Write(chunk)
+	}
+	if !strings.Contains(out.String(), "got:hello") {
+		t.Fatalf("unexpected terminal output: %q", out.String())
+	}
+	if result := <-done; result.ExitCode != 0 {
+		t.Fatalf("unexpected exit code: %v", result.ExitCode)
+	}
+	if _, _, err := m.AttachTerminal(job.UUID, false); err == nil {
+		t.Fatalf("attach after exit should fail")
+	}
+}
@@ -0,0 +1,295 @@
+package jobmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ValidationIssue 描述一个字段级别的校验问题
+type ValidationIssue struct {
+	Field   string `json:"field"` // 字段路径，例如 options.outputType
+	Message string `json:"message"`
+}
+
+// ValidationResult 任务校验结果，Errors 不为空时任务不可保存或启动
+type ValidationResult struct {
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+func newValidationResult() ValidationResult {
+	return ValidationResult{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+}
+
+func (r *ValidationResult) addError(field, format string, args ...any) {
+	r.Errors = append(r.Errors, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationResult) addWarning(field, format string, args ...any) {
+	r.Warnings = append(r.Warnings, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Valid 是否没有错误（警告不影响）
+func (r ValidationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// ValidationError 携带字段级错误的校验失败
+type ValidationError struct {
+	Result ValidationResult
+}
+
+func (e *ValidationError) Error() string {
+	var msgs []string
+	for _, issue := range e.Result.Errors {
+		msgs = append(msgs, issue.Field+": "+issue.Message)
+	}
+	return "任务配置校验失败: " + strings.Join(msgs, "; ")
+}
+
+// Err 存在错误时返回 *ValidationError
+func (r ValidationResult) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	return &ValidationError{Result: r}
+}
+
+// shell 内建命令与关键字，不检查 PATH
+var shellBuiltins = map[string]bool{
+	"echo": true, "cd": true, "export": true, "source": true, ".": true, "exec": true, "eval": true,
+	"while": true, "for": true, "if": true, "until": true, "case": true, "set": true, "ulimit": true,
+	"nohup": true, "env": true, "sleep": true, "test": true, "[": true, "true": true, "false": true,
+	"call": true, "start": true, "timeout": true,
+}
+
+// ValidateJobSpec 校验任务定义，others 为用于检查重名的其他任务，base 提供自定义变量
+func ValidateJobSpec(spec JobSpec, others []*Job, base BaseConfig) ValidationResult {
+	configDir, _ := getConfigDir()
+	return validateJobSpec(spec, others, base, configDir)
+}
+
+// validateJobSpec configDir 为 ${CONFIG_DIR} 的值
+func validateJobSpec(spec JobSpec, others []*Job, base BaseConfig, configDir string) ValidationResult {
+	r := newValidationResult()
+	vc := newVarContext(base, configDir, spec, RunInfo{})
+	expanded := vc.expandSpec(spec)
+
+	name := strings.TrimSpace(spec.JobName)
+	switch {
+	case name == "":
+		r.addError("jobName", "任务名不能为空")
+	case strings.ContainsAny(name, `/\`) || name == "." || name == "..":
+		r.addError("jobName", "任务名不能包含路径分隔符，任务名会用于日志文件名")
+	default:
+		for _, other := range others {
+			if spec.UUID != "" && other.UUID == spec.UUID {
+				continue
+			}
+			if other.JobName == spec.JobName {
+				r.addError("jobName", "任务名重复，两个任务会写入同一个日志文件")
+				break
+			}
+		}
+	}
+
+	switch spec.Type {
+	case JobTypeResident:
+	case JobTypeScheduled:
+		if strings.TrimSpace(spec.Spec) == "" {
+			r.addError("spec", "定时任务的 cron 表达式不能为空")
+		} else if _, err := cron.ParseStandard(spec.Spec); err != nil {
+			r.addError("spec", "cron 表达式无效: %v", err)
+		}
+	default:
+		r.addError("type", "未知的任务类型 %v，只支持 1 常驻 / 2 定时", int(spec.Type))
+	}
+
+	bin := strings.TrimSpace(spec.BinPath)
+	if bin == "" {
+		r.addError("binPath", "执行命令不能为空")
+	} else if prog := firstCommandWord(expanded.BinPath); prog != "" && !shellBuiltins[prog] {
+		if !commandExists(prog, expanded.Dir) {
+			r.addWarning("binPath", "在 PATH 中未找到 %v", prog)
+		}
+	}
+
+	if names := vc.unresolved(spec.Dir); len(names) > 0 {
+		r.addError("dir", "未定义的变量: %v", strings.Join(names, ", "))
+	} else if expanded.Dir != "" {
+		if st, err := os.Stat(expanded.Dir); err != nil {
+			r.addError("dir", "工作目录不存在: %v", expanded.Dir)
+		} else if !st.IsDir() {
+			r.addError("dir", "工作目录不是目录: %v", expanded.Dir)
+		}
+	}
+	if names := vc.unresolved(spec.Options.OutputPath); len(names) > 0 {
+		r.addError("options.outputPath", "未定义的变量: %v", strings.Join(names, ", "))
+	}
+	for k := range spec.Env {
+		if k == "" || strings.ContainsAny(k, "= \t") {
+			r.addError("env."+k, "环境变量名无效: %q", k)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, tag := range spec.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.TrimSpace(tag) == "":
+			r.addError(field, "标签不能为空")
+		case tag != strings.TrimSpace(tag) || strings.Contains(tag, ","):
+			r.addError(field, "标签不能包含逗号或首尾空白: %q", tag)
+		case seen[tag]:
+			r.addWarning(field, "标签重复: %v", tag)
+		}
+		seen[tag] = true
+	}
+
+	validateRunOptions(&r, "options", expanded.Options)
+	if spec.Options.PTY {
+		switch {
+		case runtime.GOOS == "windows":
+			r.addError("options.pty", "Windows 不支持 PTY 模式")
+		case spec.Type == JobTypeScheduled:
+			r.addWarning("options.pty", "定时任务只能在运行期间连接终端")
+		}
+	}
+	if cfg := effectiveSyslog(expanded.Options, base.DefaultOptions); cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			r.addError("options.syslog", "%v", err)
+		}
+	}
+	if cfg := effectiveLoki(expanded.Options, base.DefaultOptions); cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			r.addError("options.loki", "%v", err)
+		}
+	}
+	if spec.Notify != nil {
+		validateNotifyRule(&r, "notify", *spec.Notify, base.Notify)
+	}
+	return r
+}
+
+func validateRunOptions(r *ValidationResult, prefix string, options RunOptions) {
+	switch options.OutputType {
+	case 0, OutputTypeStd, OutputTypeFile, OutputTypeBoth, OutputTypeDiscard:
+	default:
+		r.addError(prefix+".outputType", "未知的输出方式 %v", int(options.OutputType))
+	}
+	if options.PerRunLog && !options.OutputType.WritesFile() {
+		r.addWarning(prefix+".perRunLog", "输出方式不写入文件，单次运行日志不会生效")
+	}
+	if options.OutputPath != "" {
+		if st, err := os.Stat(options.OutputPath); err == nil && !st.IsDir() {
+			r.addError(prefix+".outputPath", "日志路径不是目录: %v", options.OutputPath)
+		} else if err != nil {
+			r.addWarning(prefix+".outputPath", "日志目录不存在，运行时会自动创建: %v", options.OutputPath)
+		}
+	}
+	if options.MaxFailures < 0 {
+		r.addError(prefix+".maxFailures", "最大失败次数不能为负数")
+	}
+	if options.MinRunSeconds < 0 {
+		r.addError(prefix+".minRunSeconds", "最短运行时间不能为负数")
+	}
+	if options.KeepRuns < 0 {
+		r.addError(prefix+".keepRuns", "运行日志保留数量不能为负数")
+	}
+	if options.KeepDays < 0 {
+		r.addError(prefix+".keepDays", "运行日志保留天数不能为负数")
+	}
+	switch options.Rotation {
+	case "", RotationSize, RotationDaily:
+	default:
+		r.addError(prefix+".rotation", "未知的轮转方式 %v", options.Rotation)
+	}
+	switch options.LogFormat {
+	case "", LogFormatRaw, LogFormatText, LogFormatJSON:
+	default:
+		r.addError(prefix+".logFormat", "未知的日志格式 %v", options.LogFormat)
+	}
+	switch options.Concurrency {
+	case "", ConcurrencyAllow, ConcurrencyForbid:
+	default:
+		r.addError(prefix+".concurrency", "未知的并发策略 %v", options.Concurrency)
+	}
+	if options.MaxSizeMB < 0 {
+		r.addError(prefix+".maxSizeMB", "日志文件大小不能为负数")
+	}
+	if options.MaxBackups < -1 {
+		r.addError(prefix+".maxBackups", "轮转文件数量不能小于 -1")
+	}
+	if options.MaxAgeDays < -1 {
+		r.addError(prefix+".maxAgeDays", "轮转文件保留天数不能小于 -1")
+	}
+	if options.ShellPath != "" {
+		if _, err := exec.LookPath(options.ShellPath); err != nil {
+			r.addError(prefix+".shellPath", "shell 不存在或不可执行: %v", options.ShellPath)
+		}
+	}
+}
+
+// firstCommandWord 取命令行中的第一个程序名，跳过前置的环境变量赋值
+func firstCommandWord(cmdline string) string {
+	for _, f := range strings.Fields(cmdline) {
+		if strings.Contains(f, "=") && !strings.ContainsAny(f, `/\`) {
+			continue
+		}
+		// 以引号、括号等开头的复杂 shell 语句不做检查
+		if strings.ContainsAny(f[:1], `"'(${;&|<>`+"`") {
+			return ""
+		}
+		return strings.TrimRight(f, ";&|")
+	}
+	return ""
+}
+
+func commandExists(prog, dir string) bool {
+	if strings.ContainsAny(prog, `/\`) {
+		p := prog
+		if !filepath.IsAbs(p) && dir != "" {
+			p = filepath.Join(dir, p)
+		}
+		return fileExists(p)
+	}
+	_, err := exec.LookPath(prog)
+	return err == nil
+}
+
+// ValidateConfig 校验整个配置，有错误时返回汇总错误
+func ValidateConfig(config JobConfig) error {
+	configDir, _ := getConfigDir()
+	return validateConfig(config, configDir)
+}
+
+func validateConfig(config JobConfig, configDir string) error {
+	var msgs []string
+	base := newValidationResult()
+	validateNotifyConfig(&base, config.Config.Notify)
+	if !base.Valid() {
+		msgs = append(msgs, fmt.Sprintf("config: %v", base.Err()))
+	}
+	for i, job := range config.TaskList {
+		r := validateJobSpec(job.JobSpec, config.TaskList[:i], config.Config, configDir)
+		if !r.Valid() {
+			msgs = append(msgs, fmt.Sprintf("%v: %v", job.JobName, r.Err()))
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+// ValidateTask 按当前配置校验一个待保存的任务
+func (m *Manager) ValidateTask(job JobStatusShow) ValidationResult {
+	return validateJobSpec(job.toJobSpec(), m.config.TaskList, m.config.Config, m.configDir())
+}
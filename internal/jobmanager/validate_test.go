@@ -0,0 +1,126 @@
+package jobmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func issueFields(issues []ValidationIssue) map[string]bool {
+	fields := map[string]bool{}
+	for _, issue := range issues {
+		fields[issue.Field] = true
+	}
+	return fields
+}
+
+func TestValidateJobSpec_FieldErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := ValidateJobSpec(JobSpec{
+		Type:    3,
+		Dir:     file,
+		Options: RunOptions{OutputType: 9, MaxFailures: -1},
+	}, nil, BaseConfig{})
+	fields := issueFields(r.Errors)
+	for _, want := range []string{"jobName", "binPath", "type", "dir", "options.outputType", "options.maxFailures"} {
+		if !fields[want] {
+			t.Errorf("missing error for %v: %+v", want, r.Errors)
+		}
+	}
+	if r.Valid() {
+		t.Fatal("expected invalid result")
+	}
+	var ve *ValidationError
+	if !errors.As(r.Err(), &ve) {
+		t.Fatalf("Err should return *ValidationError")
+	}
+}
+
+func TestValidateJobSpec_ScheduledAndWarnings(t *testing.T) {
+	r := ValidateJobSpec(JobSpec{JobName: "a", Type: JobTypeScheduled, Spec: "not a cron", BinPath: "definitely-not-a-real-binary --flag"}, nil, BaseConfig{})
+	if !issueFields(r.Errors)["spec"] {
+		t.Fatalf("expected spec error: %+v", r.Errors)
+	}
+	if !issueFields(r.Warnings)["binPath"] {
+		t.Fatalf("expected binPath warning: %+v", r.Warnings)
+	}
+
+	r = ValidateJobSpec(JobSpec{JobName: "a", Type: JobTypeScheduled, Spec: "*/5 * * * *", BinPath: "FOO=1 echo hi"}, nil, BaseConfig{})
+	if !r.Valid() || len(r.Warnings) != 0 {
+		t.Fatalf("expected clean result: %+v", r)
+	}
+}
+
+func TestValidateJobSpec_DuplicateName(t *testing.T) {
+	existing := []*Job{{JobSpec: JobSpec{UUID: "u1", JobName: "api"}}}
+	r := ValidateJobSpec(JobSpec{UUID: "u2", JobName: "api", Type: JobTypeResident, BinPath: "echo"}, existing, BaseConfig{})
+	if !issueFields(r.Errors)["jobName"] {
+		t.Fatalf("expected duplicate name error: %+v", r.Errors)
+	}
+	// 编辑自身时不算重名
+	r = ValidateJobSpec(JobSpec{UUID: "u1", JobName: "api", Type: JobTypeResident, BinPath: "echo"}, existing, BaseConfig{})
+	if !r.Valid() {
+		t.Fatalf("unexpected errors: %+v", r.Errors)
+	}
+}
+
+func TestSaveTask_RejectsInvalid(t *testing.T) {
+	m := createTestManager()
+	_, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	err := m.SaveTask(JobStatusShow{JobName: "", Type: int(JobTypeResident)})
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if len(m.config.TaskList) != 0 {
+		t.Fatalf("invalid task should not be added")
+	}
+}
+
+func TestStart_KeepsSavedJobsThatFailValidation(t *testing.T) {
+	m, err := New(Options{Store: NewMemoryStore(JobConfig{TaskList: []*Job{
+		{JobSpec: JobSpec{UUID: "u1", JobName: "nightly", Type: JobTypeScheduled, Run: true, Spec: "@daily", BinPath: "echo 1"}},
+		{JobSpec: JobSpec{UUID: "u2", JobName: "nightly", Type: JobTypeScheduled, Run: true, Spec: "@daily", BinPath: "echo 2", Dir: "/missing/dir"}},
+	}}), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start()
+	defer m.StopAll()
+	// 加载时的校验问题只作为警告，任务照常注册
+	for _, job := range m.config.TaskList {
+		if job.entityId == 0 {
+			t.Fatalf("saved job %v should still be scheduled", job.UUID)
+		}
+	}
+	// 修改时需要先修正
+	show := m.config.TaskList[1].ToStatusShow()
+	show.Run = false
+	if err := m.OpenCloseTask("u2", false); err != nil {
+		t.Fatal(err)
+	}
+	var ve *ValidationError
+	if err := m.SaveTask(show); !errors.As(err, &ve) {
+		t.Fatalf("expected validation error on save, got %v", err)
+	}
+}
+
+func TestCreateTask_ReturnsUUID(t *testing.T) {
+	m := createTestManager()
+	_, cleanup := mockHomeDir(t)
+	defer cleanup()
+
+	id, err := m.CreateTask(JobStatusShow{UUID: "ignored", JobName: "nightly", Type: int(JobTypeScheduled), Spec: "@daily", BinPath: "echo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" || id == "ignored" || m.config.GetJob(id) == nil || m.config.GetJob(id).JobName != "nightly" {
+		t.Fatalf("CreateTask should return the assigned UUID, got %q", id)
+	}
+}
@@ -0,0 +1,616 @@
+package logsink
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LokiConfig 以 Loki push API 格式推送任务输出的配置，任务未设置的字段使用默认选项中的值
+type LokiConfig struct {
+	URL              string            `json:"url"`                        // 例如 http://loki:3100/loki/api/v1/push
+	TenantID         string            `json:"tenantId,omitempty"`         // 多租户时的 X-Scope-OrgID
+	Headers          map[string]string `json:"headers,omitempty"`          // 额外的请求头，例如 Authorization
+	Labels           map[string]string `json:"labels,omitempty"`           // 额外的静态标签
+	BatchSizeKB      int               `json:"batchSizeKB,omitempty"`      // 批量达到该大小时推送，默认 1024
+	BatchWaitSeconds int               `json:"batchWaitSeconds,omitempty"` // 最多等待该时间后推送，默认 1
+	MaxRetries       int               `json:"maxRetries,omitempty"`       // 不暂存到磁盘时每个批次的重试次数，默认 5，-1 表示不重试
+	SpoolMB          int               `json:"spoolMB,omitempty"`          // 推送失败时暂存到磁盘的上限，默认 64，-1 表示不暂存
+}
+
+// Loki 的默认值
+const (
+	defaultLokiBatchSizeKB = 1024
+	defaultLokiBatchWait   = time.Second
+	defaultLokiMaxRetries  = 5
+	defaultLokiSpoolMB     = 64
+	lokiRequestTimeout     = 10 * time.Second
+	maxMemoryBatches       = 16 // 不暂存到磁盘时在内存中等待重试的批次上限
+)
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Merge 用 def 补全未设置的字段，标签与请求头按名称合并
+func (c LokiConfig) Merge(def LokiConfig) LokiConfig {
+	if c.URL == "" {
+		c.URL = def.URL
+	}
+	if c.TenantID == "" {
+		c.TenantID = def.TenantID
+	}
+	c.Headers = mergeMap(def.Headers, c.Headers)
+	c.Labels = mergeMap(def.Labels, c.Labels)
+	if c.BatchSizeKB == 0 {
+		c.BatchSizeKB = def.BatchSizeKB
+	}
+	if c.BatchWaitSeconds == 0 {
+		c.BatchWaitSeconds = def.BatchWaitSeconds
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = def.MaxRetries
+	}
+	if c.SpoolMB == 0 {
+		c.SpoolMB = def.SpoolMB
+	}
+	return c
+}
+
+func mergeMap(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range override {
+		out[k] = v
+	}
+	return out
+}
+
+// Validate 检查配置是否有效，URL 必须设置
+func (c LokiConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("loki 地址不能为空")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("loki 地址无效: %v", c.URL)
+	}
+	for name := range c.Labels {
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("loki 标签名无效: %q", name)
+		}
+	}
+	if c.BatchSizeKB < 0 || c.BatchWaitSeconds < 0 {
+		return errors.New("loki 批量大小与等待时间不能为负数")
+	}
+	if c.MaxRetries < -1 || c.SpoolMB < -1 {
+		return errors.New("loki 重试次数与暂存大小不能小于 -1")
+	}
+	return nil
+}
+
+// Key 客户端的标识，地址、租户与请求头相同的配置共用一个客户端
+func (c LokiConfig) Key() string {
+	keys := make([]string, 0, len(c.Headers))
+	for k := range c.Headers {
+		keys = append(keys, k+"="+c.Headers[k])
+	}
+	sort.Strings(keys)
+	return c.URL + "#" + c.TenantID + "#" + strings.Join(keys, "&")
+}
+
+// LokiEntry 一行日志及其标签
+type LokiEntry struct {
+	Labels map[string]string
+	Time   time.Time
+	Line   string
+}
+
+// Stats 发送统计
+type Stats struct {
+	Sent    uint64 `json:"sent"`    // 已发送的行数
+	Dropped uint64 `json:"dropped"` // 丢弃的行数
+	Spooled uint64 `json:"spooled"` // 暂存在磁盘或内存、等待重新发送的行数
+}
+
+// LokiClient 异步批量推送日志到 Loki。Push 不会阻塞：缓冲区满时丢弃。推送失败的批次立即
+// 写入磁盘暂存目录（未设置时保存在内存中），由后台按顺序以指数退避重试，此后的批次排在其后，
+// 接收日志不会因重试而阻塞。暂存目录超出上限时删除最早的批次
+type LokiClient struct {
+	cfg      LokiConfig
+	spoolDir string // 为空时不暂存到磁盘
+	client   *http.Client
+
+	ch        chan LokiEntry
+	wake      chan struct{} // 有新的待重试批次时通知后台重试
+	quit      chan struct{}
+	done      chan struct{}
+	retryDone chan struct{}
+	closeLock sync.RWMutex // Push 持有读锁，Close 持有写锁关闭 ch
+	closed    bool
+	closeOnce sync.Once
+
+	queueLock sync.Mutex // 保护暂存目录与 memQueue
+	memQueue  []pendingBatch
+	nextID    uint64
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	sent    atomic.Uint64
+	dropped atomic.Uint64
+	spooled atomic.Int64
+}
+
+// NewLokiClient 创建推送到 cfg.URL 的客户端，spoolDir 为暂存目录的父目录，为空时不暂存
+func NewLokiClient(cfg LokiConfig, spoolDir string, buffer int) *LokiClient {
+	if buffer <= 0 {
+		buffer = 10000
+	}
+	c := &LokiClient{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: lokiRequestTimeout},
+		ch:         make(chan LokiEntry, buffer),
+		wake:       make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+		retryDone:  make(chan struct{}),
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+	if spoolDir != "" && cfg.SpoolMB >= 0 {
+		sum := sha1.Sum([]byte(cfg.Key()))
+		c.spoolDir = filepath.Join(spoolDir, "loki-"+hex.EncodeToString(sum[:6]))
+		c.spooled.Store(int64(c.countSpooled()))
+	}
+	go c.run()
+	go c.retry()
+	if c.spooled.Load() > 0 {
+		c.notifyRetry()
+	}
+	return c
+}
+
+// Push 将一行放入缓冲区，缓冲区已满或已关闭时丢弃并返回 false
+func (c *LokiClient) Push(e LokiEntry) bool {
+	c.closeLock.RLock()
+	defer c.closeLock.RUnlock()
+	if c.closed {
+		c.dropped.Add(1)
+		return false
+	}
+	select {
+	case c.ch <- e:
+		return true
+	default:
+		c.dropped.Add(1)
+		return false
+	}
+}
+
+// Target 推送地址，多租户时附带租户，地址中的密码被隐藏
+func (c *LokiClient) Target() string {
+	target := c.cfg.URL
+	if u, err := url.Parse(target); err == nil {
+		target = u.Redacted()
+	}
+	if c.cfg.TenantID != "" {
+		return target + " (" + c.cfg.TenantID + ")"
+	}
+	return target
+}
+
+// Stats 返回发送统计
+func (c *LokiClient) Stats() Stats {
+	return Stats{Sent: c.sent.Load(), Dropped: c.dropped.Load(), Spooled: uint64(max(c.spooled.Load(), 0))}
+}
+
+// Close 推送缓冲区中剩余的日志后停止，不再重试。暂存在磁盘的批次在下次启动时补发，
+// 内存中的批次被丢弃
+func (c *LokiClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.closeLock.Lock()
+		c.closed = true
+		close(c.ch)
+		c.closeLock.Unlock()
+	})
+	<-c.done
+	<-c.retryDone
+	c.queueLock.Lock()
+	for _, b := range c.memQueue {
+		c.dropped.Add(uint64(b.lines))
+		c.spooled.Add(-int64(b.lines))
+	}
+	c.memQueue = nil
+	c.queueLock.Unlock()
+	return nil
+}
+
+func (c *LokiClient) batchSize() int {
+	if c.cfg.BatchSizeKB > 0 {
+		return c.cfg.BatchSizeKB * 1024
+	}
+	return defaultLokiBatchSizeKB * 1024
+}
+
+func (c *LokiClient) batchWait() time.Duration {
+	if c.cfg.BatchWaitSeconds > 0 {
+		return time.Duration(c.cfg.BatchWaitSeconds) * time.Second
+	}
+	return defaultLokiBatchWait
+}
+
+func (c *LokiClient) maxRetries() int {
+	switch {
+	case c.cfg.MaxRetries < 0:
+		return 0
+	case c.cfg.MaxRetries == 0:
+		return defaultLokiMaxRetries
+	}
+	return c.cfg.MaxRetries
+}
+
+func (c *LokiClient) spoolLimit() int64 {
+	if c.cfg.SpoolMB > 0 {
+		return int64(c.cfg.SpoolMB) << 20
+	}
+	return defaultLokiSpoolMB << 20
+}
+
+func (c *LokiClient) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.batchWait())
+	defer ticker.Stop()
+	var batch []LokiEntry
+	size := 0
+	for {
+		select {
+		case e, ok := <-c.ch:
+			if !ok {
+				if len(batch) > 0 {
+					c.flush(batch)
+				}
+				return
+			}
+			batch = append(batch, e)
+			size += len(e.Line)
+			if size >= c.batchSize() {
+				c.flush(batch)
+				batch, size = nil, 0
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				c.flush(batch)
+				batch, size = nil, 0
+			}
+		}
+	}
+}
+
+// flush 推送一个批次。有待重试的批次时排在其后，保持推送顺序；推送失败时只尝试一次，
+// 随即交给后台重试
+func (c *LokiClient) flush(batch []LokiEntry) {
+	body, err := encodeLokiPush(batch)
+	if err != nil {
+		c.dropped.Add(uint64(len(batch)))
+		return
+	}
+	if c.spooled.Load() > 0 {
+		c.enqueue(body, len(batch))
+		return
+	}
+	err = c.send(body)
+	var perm *permanentError
+	switch {
+	case err == nil:
+		c.sent.Add(uint64(len(batch)))
+	case errors.As(err, &perm) || (c.spoolDir == "" && c.maxRetries() == 0):
+		c.dropped.Add(uint64(len(batch)))
+	default:
+		c.enqueue(body, len(batch))
+	}
+}
+
+// permanentError 服务端拒绝的请求，重试也不会成功
+type permanentError struct {
+	status int
+	msg    string
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("loki 拒绝推送: %d %s", e.status, e.msg)
+}
+
+// pendingBatch 等待重试的批次，path 为空时保存在内存中
+type pendingBatch struct {
+	id    uint64
+	path  string
+	body  []byte
+	lines int
+}
+
+// enqueue 将批次排到待重试批次的末尾并通知后台重试
+func (c *LokiClient) enqueue(body []byte, lines int) {
+	if c.spoolDir != "" {
+		c.spool(body, lines)
+	} else {
+		c.queueLock.Lock()
+		c.nextID++
+		c.memQueue = append(c.memQueue, pendingBatch{id: c.nextID, body: body, lines: lines})
+		c.spooled.Add(int64(lines))
+		if len(c.memQueue) > maxMemoryBatches {
+			c.dropped.Add(uint64(c.memQueue[0].lines))
+			c.spooled.Add(-int64(c.memQueue[0].lines))
+			c.memQueue = c.memQueue[1:]
+		}
+		c.queueLock.Unlock()
+	}
+	c.notifyRetry()
+}
+
+func (c *LokiClient) notifyRetry() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// retry 在后台按顺序重试待发送的批次
+func (c *LokiClient) retry() {
+	defer close(c.retryDone)
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-c.wake:
+			c.drainQueue()
+		}
+	}
+}
+
+// drainQueue 按顺序发送待重试的批次直到全部完成，失败时以指数退避重试。
+// 暂存在磁盘的批次一直重试（超出暂存上限时被删除），内存中的批次最多重试 maxRetries 次
+func (c *LokiClient) drainQueue() {
+	delay := c.minBackoff
+	attempts := 0
+	for {
+		b, ok := c.head()
+		if !ok {
+			return
+		}
+		err := c.send(b.body)
+		var perm *permanentError
+		switch {
+		case err == nil:
+			if c.dequeue(b) {
+				c.sent.Add(uint64(b.lines))
+			}
+		case errors.As(err, &perm):
+			if c.dequeue(b) {
+				c.dropped.Add(uint64(b.lines))
+			}
+		default:
+			attempts++
+			if b.path == "" && attempts >= c.maxRetries() {
+				if c.dequeue(b) {
+					c.dropped.Add(uint64(b.lines))
+				}
+				break
+			}
+			select {
+			case <-c.quit:
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, c.maxBackoff)
+			continue
+		}
+		delay, attempts = c.minBackoff, 0
+	}
+}
+
+// head 返回最早的待重试批次
+func (c *LokiClient) head() (pendingBatch, bool) {
+	c.queueLock.Lock()
+	defer c.queueLock.Unlock()
+	if c.spoolDir == "" {
+		if len(c.memQueue) == 0 {
+			return pendingBatch{}, false
+		}
+		return c.memQueue[0], true
+	}
+	for _, f := range c.spoolFiles() {
+		body, err := os.ReadFile(f.path)
+		if err != nil {
+			// 无法读取的文件不再重试
+			if os.Remove(f.path) == nil {
+				c.dropped.Add(uint64(f.lines))
+				c.spooled.Add(-int64(f.lines))
+			}
+			continue
+		}
+		return pendingBatch{path: f.path, body: body, lines: f.lines}, true
+	}
+	return pendingBatch{}, false
+}
+
+// dequeue 移除已完成的批次，批次已因超出上限被删除时返回 false
+func (c *LokiClient) dequeue(b pendingBatch) bool {
+	c.queueLock.Lock()
+	defer c.queueLock.Unlock()
+	if b.path != "" {
+		if os.Remove(b.path) != nil {
+			return false
+		}
+	} else {
+		if len(c.memQueue) == 0 || c.memQueue[0].id != b.id {
+			return false
+		}
+		c.memQueue = c.memQueue[1:]
+	}
+	c.spooled.Add(-int64(b.lines))
+	return true
+}
+
+func (c *LokiClient) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{msg: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", c.cfg.TenantID)
+	}
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests:
+		return &permanentError{status: resp.StatusCode, msg: strings.TrimSpace(string(msg))}
+	default:
+		return fmt.Errorf("loki 推送失败: %d %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+}
+
+// lokiPush Loki push API 的请求体
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLokiPush 按标签分组编码批次，组内保持原有顺序
+func encodeLokiPush(batch []LokiEntry) ([]byte, error) {
+	var push lokiPush
+	index := map[string]int{}
+	for _, e := range batch {
+		key := labelsKey(e.Labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(push.Streams)
+			index[key] = i
+			push.Streams = append(push.Streams, lokiStream{Stream: e.Labels})
+		}
+		push.Streams[i].Values = append(push.Streams[i].Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Line})
+	}
+	return json.Marshal(push)
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k + "=" + strconv.Quote(labels[k]) + ",")
+	}
+	return sb.String()
+}
+
+// 暂存文件名 <纳秒时间戳>-<行数>.json，按文件名排序即为写入顺序
+const spoolExt = ".json"
+
+type spoolFile struct {
+	path  string
+	lines int
+	size  int64
+}
+
+func (c *LokiClient) spoolFiles() []spoolFile {
+	entries, err := os.ReadDir(c.spoolDir)
+	if err != nil {
+		return nil
+	}
+	var files []spoolFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+		_, linesPart, ok := strings.Cut(strings.TrimSuffix(name, spoolExt), "-")
+		lines, err := strconv.Atoi(linesPart)
+		if !ok || err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(c.spoolDir, name), lines: lines, size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files
+}
+
+func (c *LokiClient) countSpooled() int {
+	n := 0
+	for _, f := range c.spoolFiles() {
+		n += f.lines
+	}
+	return n
+}
+
+// spool 将推送失败的批次写入暂存目录，超出上限时删除最早的批次
+func (c *LokiClient) spool(body []byte, lines int) {
+	c.queueLock.Lock()
+	defer c.queueLock.Unlock()
+	if c.spoolDir == "" || int64(len(body)) > c.spoolLimit() {
+		c.dropped.Add(uint64(lines))
+		return
+	}
+	if err := os.MkdirAll(c.spoolDir, 0o755); err != nil {
+		c.dropped.Add(uint64(lines))
+		return
+	}
+	files := c.spoolFiles()
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	for len(files) > 0 && total+int64(len(body)) > c.spoolLimit() {
+		if err := os.Remove(files[0].path); err == nil {
+			c.dropped.Add(uint64(files[0].lines))
+			c.spooled.Add(-int64(files[0].lines))
+		}
+		total -= files[0].size
+		files = files[1:]
+	}
+	name := fmt.Sprintf("%020d-%d%s", time.Now().UnixNano(), lines, spoolExt)
+	if err := os.WriteFile(filepath.Join(c.spoolDir, name), body, 0o644); err != nil {
+		c.dropped.Add(uint64(lines))
+		return
+	}
+	c.spooled.Add(int64(lines))
+}
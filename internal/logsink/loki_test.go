@@ -0,0 +1,190 @@
+package logsink
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lokiServer 记录收到的推送，fail 为 true 时返回 503
+type lokiServer struct {
+	*httptest.Server
+	lock   sync.Mutex
+	pushes []lokiPush
+	fail   atomic.Bool
+	tenant string
+}
+
+func newLokiServer(t *testing.T) *lokiServer {
+	s := &lokiServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		var p lokiPush
+		if err := json.Unmarshal(data, &p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.lock.Lock()
+		s.pushes = append(s.pushes, p)
+		s.tenant = r.Header.Get("X-Scope-OrgID")
+		s.lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *lokiServer) lines() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var lines []string
+	for _, p := range s.pushes {
+		for _, st := range p.Streams {
+			for _, v := range st.Values {
+				lines = append(lines, st.Stream["stream"]+":"+v[1])
+			}
+		}
+	}
+	return lines
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLokiBatching(t *testing.T) {
+	srv := newLokiServer(t)
+	// 批量大小 1KB，每个批次在第二行时推送
+	c := NewLokiClient(LokiConfig{URL: srv.URL, TenantID: "team", BatchSizeKB: 1, BatchWaitSeconds: 60}, "", 100)
+	line := string(make([]byte, 600))
+	now := time.Unix(1700000000, 5)
+	for _, stream := range []string{"out", "err", "out"} {
+		c.Push(LokiEntry{Labels: map[string]string{"job": "a", "stream": stream}, Time: now, Line: line})
+	}
+	waitFor(t, func() bool { return c.Stats().Sent == 2 })
+	srv.lock.Lock()
+	if len(srv.pushes) != 1 || len(srv.pushes[0].Streams) != 2 || srv.tenant != "team" {
+		t.Fatalf("unexpected push: %+v tenant %q", srv.pushes, srv.tenant)
+	}
+	if v := srv.pushes[0].Streams[0].Values[0][0]; v != "1700000000000000005" {
+		t.Fatalf("timestamp should be unix nanoseconds: %v", v)
+	}
+	srv.lock.Unlock()
+
+	// 剩余的一行在关闭时推送
+	_ = c.Close()
+	if st := c.Stats(); st.Sent != 3 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestLokiBatchWait(t *testing.T) {
+	srv := newLokiServer(t)
+	c := NewLokiClient(LokiConfig{URL: srv.URL, BatchWaitSeconds: 1}, "", 100)
+	defer c.Close()
+	c.Push(LokiEntry{Labels: map[string]string{"stream": "out"}, Time: time.Now(), Line: "hi"})
+	waitFor(t, func() bool { return len(srv.lines()) == 1 })
+}
+
+func TestLokiSpoolAndRecover(t *testing.T) {
+	srv := newLokiServer(t)
+	srv.fail.Store(true)
+	spool := t.TempDir()
+	cfg := LokiConfig{URL: srv.URL, BatchSizeKB: 1, BatchWaitSeconds: 1, MaxRetries: 1}
+	c := NewLokiClient(cfg, spool, 100)
+	c.minBackoff = time.Millisecond
+	big := string(make([]byte, 1100))
+	c.Push(LokiEntry{Labels: map[string]string{"stream": "out"}, Time: time.Now(), Line: big})
+	waitFor(t, func() bool { return c.Stats().Spooled == 1 })
+	_ = c.Close()
+
+	// 重新启动后从暂存目录补发
+	srv.fail.Store(false)
+	c = NewLokiClient(cfg, spool, 100)
+	defer c.Close()
+	if c.Stats().Spooled != 1 {
+		t.Fatalf("spooled lines should be counted on start: %+v", c.Stats())
+	}
+	c.Push(LokiEntry{Labels: map[string]string{"stream": "err"}, Time: time.Now(), Line: big})
+	waitFor(t, func() bool { return c.Stats().Sent == 2 })
+	if lines := srv.lines(); len(lines) != 2 || lines[0][:4] != "out:" {
+		t.Fatalf("spooled batch should be sent before the new one: %d lines", len(lines))
+	}
+	if st := c.Stats(); st.Spooled != 0 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestLokiRetryInBackground(t *testing.T) {
+	srv := newLokiServer(t)
+	srv.fail.Store(true)
+	c := NewLokiClient(LokiConfig{URL: srv.URL, BatchSizeKB: 1, BatchWaitSeconds: 60, MaxRetries: 100}, "", 100)
+	defer c.Close()
+	c.minBackoff, c.maxBackoff = time.Millisecond, 10*time.Millisecond
+	big := string(make([]byte, 1100))
+	// 重试期间继续接收日志，后续批次排在失败的批次之后
+	for _, stream := range []string{"a", "b", "c"} {
+		c.Push(LokiEntry{Labels: map[string]string{"stream": stream}, Time: time.Now(), Line: big})
+	}
+	waitFor(t, func() bool { return c.Stats().Spooled == 3 })
+	srv.fail.Store(false)
+	waitFor(t, func() bool { return c.Stats().Sent == 3 })
+	lines := srv.lines()
+	if len(lines) != 3 || lines[0][:2] != "a:" || lines[1][:2] != "b:" || lines[2][:2] != "c:" {
+		t.Fatalf("batches should be sent in order: %d lines", len(lines))
+	}
+}
+
+func TestLokiPushAfterClose(t *testing.T) {
+	srv := newLokiServer(t)
+	c := NewLokiClient(LokiConfig{URL: srv.URL}, "", 10)
+	_ = c.Close()
+	// 关闭后仍有任务输出时丢弃，不会 panic
+	if c.Push(LokiEntry{Labels: map[string]string{"stream": "out"}, Time: time.Now(), Line: "late"}) {
+		t.Fatalf("Push should fail after Close")
+	}
+	if st := c.Stats(); st.Dropped != 1 {
+		t.Fatalf("late line should be counted as dropped: %+v", st)
+	}
+}
+
+func TestLokiSpoolLimit(t *testing.T) {
+	c := &LokiClient{cfg: LokiConfig{SpoolMB: 1}, spoolDir: t.TempDir()}
+	body := make([]byte, 600*1024)
+	c.spool(body, 3)
+	c.spool(body, 4)
+	if st := c.Stats(); st.Spooled != 4 || st.Dropped != 3 {
+		t.Fatalf("oldest batch should be dropped when over the limit: %+v", st)
+	}
+	if files := c.spoolFiles(); len(files) != 1 || files[0].lines != 4 {
+		t.Fatalf("unexpected spool files: %+v", files)
+	}
+}
+
+func TestLokiConfig(t *testing.T) {
+	for _, c := range []LokiConfig{{}, {URL: "loki:3100"}, {URL: "http://loki", Labels: map[string]string{"bad-name": "x"}}} {
+		if c.Validate() == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+	merged := LokiConfig{Labels: map[string]string{"env": "dev"}}.Merge(LokiConfig{URL: "http://loki:3100/loki/api/v1/push", Labels: map[string]string{"env": "prod", "dc": "a"}})
+	if merged.Validate() != nil || merged.Labels["env"] != "dev" || merged.Labels["dc"] != "a" {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+}
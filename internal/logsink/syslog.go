@@ -0,0 +1,319 @@
+// Package logsink 将任务输出的每一行转发到外部日志系统
+package logsink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyslogConfig syslog 转发配置，任务未设置的字段使用默认选项中的值
+type SyslogConfig struct {
+	Network     string `json:"network"`     // udp / tcp / unix，默认 udp
+	Address     string `json:"address"`     // host:port 或 unix socket 路径
+	AppName     string `json:"appName"`     // 默认任务名
+	Facility    string `json:"facility"`    // 默认 user
+	Severity    string `json:"severity"`    // 标准输出使用的级别，默认 info
+	ErrSeverity string `json:"errSeverity"` // 标准错误使用的级别，默认 err
+}
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var severities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3, "warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// Merge 用 def 补全未设置的字段
+func (c SyslogConfig) Merge(def SyslogConfig) SyslogConfig {
+	fill := func(v *string, d string) {
+		if *v == "" {
+			*v = d
+		}
+	}
+	fill(&c.Network, def.Network)
+	fill(&c.Address, def.Address)
+	fill(&c.AppName, def.AppName)
+	fill(&c.Facility, def.Facility)
+	fill(&c.Severity, def.Severity)
+	fill(&c.ErrSeverity, def.ErrSeverity)
+	return c
+}
+
+// Validate 检查配置是否有效，Address 必须设置，其余字段为空时使用默认值
+func (c SyslogConfig) Validate() error {
+	switch c.Network {
+	case "", "udp", "tcp", "unix":
+	default:
+		return fmt.Errorf("未知的 syslog 网络类型: %q", c.Network)
+	}
+	if c.Address == "" {
+		return errors.New("syslog 地址不能为空")
+	}
+	if _, ok := facilities[c.facility()]; !ok {
+		return fmt.Errorf("未知的 syslog facility: %q", c.Facility)
+	}
+	for _, s := range []string{c.severity(), c.errSeverity()} {
+		if _, ok := severities[s]; !ok {
+			return fmt.Errorf("未知的 syslog 级别: %q", s)
+		}
+	}
+	return nil
+}
+
+func (c SyslogConfig) network() string {
+	if c.Network == "" {
+		return "udp"
+	}
+	return c.Network
+}
+
+func (c SyslogConfig) facility() string {
+	if c.Facility == "" {
+		return "user"
+	}
+	return c.Facility
+}
+
+func (c SyslogConfig) severity() string {
+	if c.Severity == "" {
+		return "info"
+	}
+	return c.Severity
+}
+
+func (c SyslogConfig) errSeverity() string {
+	if c.ErrSeverity == "" {
+		return "err"
+	}
+	return c.ErrSeverity
+}
+
+// Key 连接的标识，网络类型与地址相同的配置共用一个连接
+func (c SyslogConfig) Key() string {
+	return c.network() + "://" + c.Address
+}
+
+// Priority 返回输出流对应的 PRI 值，标准错误使用 ErrSeverity
+func (c SyslogConfig) Priority(stderr bool) int {
+	sev := severities[c.severity()]
+	if stderr {
+		sev = severities[c.errSeverity()]
+	}
+	return facilities[c.facility()]*8 + sev
+}
+
+// SDParam 结构化数据中的一个参数
+type SDParam struct {
+	Name  string
+	Value string
+}
+
+// SyslogMessage 一条 RFC 5424 消息
+type SyslogMessage struct {
+	Priority int
+	Time     time.Time
+	Hostname string
+	AppName  string
+	ProcID   string
+	MsgID    string
+	SDID     string // 结构化数据的 ID，为空时不输出结构化数据
+	Params   []SDParam
+	Text     string
+}
+
+// headerField 按 RFC 5424 限制字段为可打印 ASCII 且不含空格，空值输出 "-"
+func headerField(s string, maxLen int) string {
+	if s == "" {
+		return "-"
+	}
+	b := make([]byte, 0, min(len(s), maxLen))
+	for i := 0; i < len(s) && len(b) < maxLen; i++ {
+		c := s[i]
+		if c < 33 || c > 126 {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// Format 按 RFC 5424 格式化消息
+func (m SyslogMessage) Format() []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<%d>1 %s %s %s %s %s ",
+		m.Priority,
+		m.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(m.Hostname, 255),
+		headerField(m.AppName, 48),
+		headerField(m.ProcID, 128),
+		headerField(m.MsgID, 32),
+	)
+	if m.SDID == "" {
+		sb.WriteString("-")
+	} else {
+		sb.WriteString("[" + headerField(m.SDID, 32))
+		for _, p := range m.Params {
+			fmt.Fprintf(&sb, ` %s="%s"`, headerField(p.Name, 32), sdEscaper.Replace(p.Value))
+		}
+		sb.WriteString("]")
+	}
+	if m.Text != "" {
+		sb.WriteString(" " + m.Text)
+	}
+	return []byte(sb.String())
+}
+
+// 连接失败后重试的间隔
+const (
+	minRedialDelay = time.Second
+	maxRedialDelay = 30 * time.Second
+	writeTimeout   = 2 * time.Second
+)
+
+// SyslogWriter 异步发送 syslog 消息。消息先进入有界缓冲区，缓冲区满或收集器不可达时丢弃，
+// 调用方永远不会被阻塞
+type SyslogWriter struct {
+	network string
+	address string
+	ch      chan []byte
+	dropped atomic.Uint64
+	sent    atomic.Uint64
+
+	closeLock sync.RWMutex // Send 持有读锁，Close 持有写锁关闭 ch
+	closed    bool
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewSyslogWriter 创建发送到 network/address 的 SyslogWriter，buffer 为缓冲的消息数
+func NewSyslogWriter(network, address string, buffer int) *SyslogWriter {
+	if network == "" {
+		network = "udp"
+	}
+	if buffer <= 0 {
+		buffer = 1000
+	}
+	w := &SyslogWriter{
+		network: network,
+		address: address,
+		ch:      make(chan []byte, buffer),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Send 将消息放入缓冲区，缓冲区已满或已关闭时丢弃并返回 false
+func (w *SyslogWriter) Send(msg []byte) bool {
+	w.closeLock.RLock()
+	defer w.closeLock.RUnlock()
+	if w.closed {
+		w.dropped.Add(1)
+		return false
+	}
+	select {
+	case w.ch <- msg:
+		return true
+	default:
+		w.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped 被丢弃的消息数
+func (w *SyslogWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Sent 已发送的消息数
+func (w *SyslogWriter) Sent() uint64 {
+	return w.sent.Load()
+}
+
+// Close 停止发送，缓冲区中剩余的消息会在关闭前尽量发出
+func (w *SyslogWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.closeLock.Lock()
+		w.closed = true
+		close(w.ch)
+		w.closeLock.Unlock()
+	})
+	<-w.done
+	return nil
+}
+
+func (w *SyslogWriter) run() {
+	defer close(w.done)
+	var conn net.Conn
+	var stream bool
+	var retryAt time.Time
+	delay := minRedialDelay
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+	for msg := range w.ch {
+		if conn == nil {
+			if time.Now().Before(retryAt) {
+				w.dropped.Add(1)
+				continue
+			}
+			var err error
+			if conn, stream, err = w.dial(); err != nil {
+				retryAt = time.Now().Add(delay)
+				delay = min(delay*2, maxRedialDelay)
+				w.dropped.Add(1)
+				continue
+			}
+			delay = minRedialDelay
+		}
+		frame := msg
+		if stream {
+			// RFC 6587 octet counting
+			frame = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := conn.Write(frame); err != nil {
+			_ = conn.Close()
+			conn = nil
+			w.dropped.Add(1)
+			continue
+		}
+		w.sent.Add(1)
+	}
+}
+
+// dial 建立连接，unix 先尝试数据报 socket（例如 /dev/log），再尝试流式 socket
+func (w *SyslogWriter) dial() (net.Conn, bool, error) {
+	switch w.network {
+	case "unix":
+		if conn, err := net.DialTimeout("unixgram", w.address, writeTimeout); err == nil {
+			return conn, false, nil
+		}
+		conn, err := net.DialTimeout("unix", w.address, writeTimeout)
+		return conn, true, err
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", w.address, writeTimeout)
+		return conn, true, err
+	default:
+		conn, err := net.DialTimeout(w.network, w.address, writeTimeout)
+		return conn, false, err
+	}
+}
+
+// Stats 返回发送统计
+func (w *SyslogWriter) Stats() Stats {
+	return Stats{Sent: w.sent.Load(), Dropped: w.dropped.Load()}
+}
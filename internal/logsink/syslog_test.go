@@ -0,0 +1,147 @@
+package logsink
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogMessageFormat(t *testing.T) {
+	cfg := SyslogConfig{Facility: "local3", ErrSeverity: "warning"}
+	msg := SyslogMessage{
+		Priority: cfg.Priority(true),
+		Time:     time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Hostname: "host",
+		AppName:  "my app",
+		ProcID:   "r1",
+		MsgID:    "err",
+		SDID:     "rooster@32473",
+		Params:   []SDParam{{Name: "job", Value: `a "b" [c] \d`}},
+		Text:     "hello",
+	}
+	want := `<156>1 2024-01-02T03:04:05.000006Z host my_app r1 err [rooster@32473 job="a \"b\" [c\] \\d"] hello`
+	if got := string(msg.Format()); got != want {
+		t.Fatalf("unexpected message:\n%s\n%s", got, want)
+	}
+	if p := cfg.Priority(false); p != 19*8+6 {
+		t.Fatalf("stdout should default to info: %d", p)
+	}
+	empty := SyslogMessage{Priority: 14, Time: msg.Time}
+	if got := string(empty.Format()); got != "<14>1 2024-01-02T03:04:05.000006Z - - - - -" {
+		t.Fatalf("empty fields should be nil values: %s", got)
+	}
+}
+
+func TestSyslogConfigValidate(t *testing.T) {
+	bad := []SyslogConfig{
+		{},
+		{Address: "x", Network: "http"},
+		{Address: "x", Facility: "nope"},
+		{Address: "x", ErrSeverity: "fatal"},
+	}
+	for _, c := range bad {
+		if c.Validate() == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+	merged := SyslogConfig{AppName: "job"}.Merge(SyslogConfig{Address: "127.0.0.1:514", AppName: "default", Severity: "notice"})
+	if merged.Validate() != nil || merged.AppName != "job" || merged.Severity != "notice" {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	w := NewSyslogWriter("udp", conn.LocalAddr().String(), 10)
+	defer w.Close()
+	w.Send([]byte("<14>1 - - - - - - one"))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil || string(buf[:n]) != "<14>1 - - - - - - one" {
+		t.Fatalf("unexpected datagram %q: %v", buf[:n], err)
+	}
+}
+
+func TestSyslogWriterTCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w := NewSyslogWriter("tcp", ln.Addr().String(), 10)
+	w.Send([]byte("first line"))
+	w.Send([]byte("second"))
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	br := bufio.NewReader(conn)
+	for _, want := range []string{"first line", "second"} {
+		size, err := br.ReadString(' ')
+		if err != nil {
+			t.Fatalf("read frame size: %v", err)
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(size))
+		body := make([]byte, n)
+		if _, err := io.ReadFull(br, body); err != nil || string(body) != want {
+			t.Fatalf("unexpected frame %q: %v", body, err)
+		}
+	}
+	_ = w.Close()
+	if w.Sent() != 2 || w.Dropped() != 0 {
+		t.Fatalf("unexpected counters: sent %d dropped %d", w.Sent(), w.Dropped())
+	}
+}
+
+func TestSyslogWriterNeverBlocks(t *testing.T) {
+	// 没有监听的端口，连接失败后消息被丢弃
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	w := NewSyslogWriter("tcp", addr, 4)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			w.Send([]byte("x"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Send blocked while the collector is unreachable")
+	}
+	_ = w.Close()
+	if w.Sent() != 0 || w.Dropped() != 1000 {
+		t.Fatalf("all messages should be dropped: sent %d dropped %d", w.Sent(), w.Dropped())
+	}
+}
+
+func TestSyslogWriterSendAfterClose(t *testing.T) {
+	w := NewSyslogWriter("udp", "127.0.0.1:9", 4)
+	_ = w.Close()
+	// 关闭后仍有任务输出时丢弃，不会 panic
+	if w.Send([]byte("late")) {
+		t.Fatalf("Send should fail after Close")
+	}
+	if w.Dropped() != 1 {
+		t.Fatalf("late message should be counted as dropped: %d", w.Dropped())
+	}
+}
@@ -0,0 +1,251 @@
+// Package notify 将通知消息发送到 webhook、即时通讯机器人与邮件
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 渠道类型
+const (
+	TypeWebhook  = "webhook"  // 以 JSON POST 完整的 Message
+	TypeSlack    = "slack"    // Slack incoming webhook
+	TypeDingTalk = "dingtalk" // 钉钉自定义机器人，支持加签
+	TypeFeishu   = "feishu"   // 飞书自定义机器人，支持签名校验
+	TypeSMTP     = "smtp"     // 邮件
+)
+
+// Channel 通知渠道配置
+type Channel struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	URL     string            `json:"url"`     // webhook / slack / dingtalk / feishu 的地址
+	Secret  string            `json:"secret"`  // 钉钉加签或飞书签名校验的密钥
+	Headers map[string]string `json:"headers"` // webhook 附加的请求头
+
+	// smtp
+	Host     string   `json:"host"`
+	Port     int      `json:"port"` // 465 使用 TLS 直连，其他端口在服务器支持时使用 STARTTLS
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Message 通知消息
+type Message struct {
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Kind     string    `json:"kind"` // 触发通知的规则，例如 failure / give_up / recovery / long_running / test
+	JobName  string    `json:"jobName,omitempty"`
+	UUID     string    `json:"uuid,omitempty"`
+	RunID    string    `json:"runId,omitempty"`
+	ExitCode *int      `json:"exitCode,omitempty"`
+	Host     string    `json:"host"`
+	Time     time.Time `json:"time"`
+}
+
+// Validate 检查渠道配置是否完整
+func (c Channel) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("渠道名称不能为空")
+	}
+	switch c.Type {
+	case TypeWebhook, TypeSlack, TypeDingTalk, TypeFeishu:
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("地址无效: %q", c.URL)
+		}
+	case TypeSMTP:
+		if c.Host == "" || c.Port <= 0 {
+			return errors.New("smtp 需要 host 与 port")
+		}
+		if c.From == "" || len(c.To) == 0 {
+			return errors.New("smtp 需要 from 与 to")
+		}
+	default:
+		return fmt.Errorf("未知的渠道类型: %q", c.Type)
+	}
+	return nil
+}
+
+// HTTPClient 发送 HTTP 通知使用的客户端
+var HTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Send 通过渠道发送消息
+func Send(ctx context.Context, c Channel, msg Message) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	switch c.Type {
+	case TypeSMTP:
+		return sendMail(ctx, c, msg)
+	case TypeSlack:
+		return postJSON(ctx, c, c.URL, map[string]any{"text": "*" + msg.Title + "*\n" + msg.Text})
+	case TypeDingTalk:
+		target := c.URL
+		if c.Secret != "" {
+			ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+			mac := hmac.New(sha256.New, []byte(c.Secret))
+			mac.Write([]byte(ts + "\n" + c.Secret))
+			sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+			target = appendQuery(target, url.Values{"timestamp": {ts}, "sign": {sign}})
+		}
+		return postJSON(ctx, c, target, map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": msg.Title, "text": "### " + msg.Title + "\n\n" + markdownText(msg.Text)},
+		})
+	case TypeFeishu:
+		body := map[string]any{
+			"msg_type": "text",
+			"content":  map[string]string{"text": msg.Title + "\n" + msg.Text},
+		}
+		if c.Secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			mac := hmac.New(sha256.New, []byte(ts+"\n"+c.Secret))
+			body["timestamp"] = ts
+			body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		}
+		return postJSON(ctx, c, c.URL, body)
+	default:
+		return postJSON(ctx, c, c.URL, msg)
+	}
+}
+
+func appendQuery(target string, values url.Values) string {
+	sep := "?"
+	if strings.Contains(target, "?") {
+		sep = "&"
+	}
+	return target + sep + values.Encode()
+}
+
+// markdownText 保留换行与日志的缩进
+func markdownText(text string) string {
+	return strings.ReplaceAll(text, "\n", "  \n")
+}
+
+func postJSON(ctx context.Context, c Channel, target string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%v 返回 %v: %s", c.Name, resp.Status, respBody)
+	}
+	// 钉钉与飞书出错时也返回 200，错误码在响应体中
+	var result struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if json.Unmarshal(respBody, &result) == nil {
+		if result.ErrCode != nil && *result.ErrCode != 0 {
+			return fmt.Errorf("%v 返回错误 %v: %v", c.Name, *result.ErrCode, result.ErrMsg)
+		}
+		if result.Code != nil && *result.Code != 0 {
+			return fmt.Errorf("%v 返回错误 %v: %v", c.Name, *result.Code, result.Msg)
+		}
+	}
+	return nil
+}
+
+func sendMail(ctx context.Context, c Channel, msg Message) error {
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "From: %v\r\n", c.From)
+	fmt.Fprintf(&header, "To: %v\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&header, "Subject: =?UTF-8?B?%v?=\r\n", base64.StdEncoding.EncodeToString([]byte(msg.Title)))
+	fmt.Fprintf(&header, "Date: %v\r\n", msg.Time.Format(time.RFC1123Z))
+	header.WriteString("MIME-Version: 1.0\r\n")
+	header.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	header.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Text))
+	for len(body) > 76 {
+		header.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	header.WriteString(body + "\r\n")
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if c.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: c.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+	if c.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(c.From); err != nil {
+		return err
+	}
+	for _, to := range c.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
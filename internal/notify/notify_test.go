@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []Channel{
+		{Type: TypeWebhook, URL: "http://x"},
+		{Name: "a", Type: "pager", URL: "http://x"},
+		{Name: "a", Type: TypeSlack, URL: "not a url"},
+		{Name: "a", Type: TypeSMTP, Host: "smtp.example.com", Port: 25},
+	}
+	for _, c := range cases {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+	ok := Channel{Name: "mail", Type: TypeSMTP, Host: "smtp.example.com", Port: 465, From: "a@example.com", To: []string{"b@example.com"}}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSendPayloads(t *testing.T) {
+	var gotQuery string
+	var gotBody map[string]any
+	var gotHeader string
+	reply := `{}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotHeader = r.Header.Get("X-Token")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = map[string]any{}
+		_ = json.Unmarshal(b, &gotBody)
+		_, _ = w.Write([]byte(reply))
+	}))
+	defer srv.Close()
+	msg := Message{Title: "api 运行失败", Text: "退出码: 1", Kind: "failure", JobName: "api"}
+	ctx := context.Background()
+
+	if err := Send(ctx, Channel{Name: "hook", Type: TypeWebhook, URL: srv.URL, Headers: map[string]string{"X-Token": "t"}}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody["jobName"] != "api" || gotBody["kind"] != "failure" || gotHeader != "t" {
+		t.Fatalf("unexpected webhook payload: %v %v", gotBody, gotHeader)
+	}
+
+	if err := Send(ctx, Channel{Name: "slack", Type: TypeSlack, URL: srv.URL}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := gotBody["text"].(string); !strings.Contains(text, "api 运行失败") {
+		t.Fatalf("unexpected slack payload: %v", gotBody)
+	}
+
+	if err := Send(ctx, Channel{Name: "ding", Type: TypeDingTalk, URL: srv.URL + "?access_token=x", Secret: "s"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody["msgtype"] != "markdown" || !strings.Contains(gotQuery, "access_token=x&") || !strings.Contains(gotQuery, "sign=") {
+		t.Fatalf("unexpected dingtalk request: %v %v", gotQuery, gotBody)
+	}
+
+	if err := Send(ctx, Channel{Name: "feishu", Type: TypeFeishu, URL: srv.URL, Secret: "s"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody["msg_type"] != "text" || gotBody["sign"] == nil || gotBody["timestamp"] == nil {
+		t.Fatalf("unexpected feishu payload: %v", gotBody)
+	}
+
+	reply = `{"errcode":310000,"errmsg":"sign not match"}`
+	if err := Send(ctx, Channel{Name: "ding", Type: TypeDingTalk, URL: srv.URL}, msg); err == nil || !strings.Contains(err.Error(), "310000") {
+		t.Fatalf("expected errcode error, got %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope 令牌的权限范围，高的范围包含低的范围
+type Scope string
+
+const (
+	ScopeRead     Scope = "read"     // 只读：查看任务、日志与事件
+	ScopeOperator Scope = "operator" // 操作：启停、编辑、删除任务等修改状态的操作
+	ScopeAdmin    Scope = "admin"    // 管理：管理令牌，只有管理员令牌拥有
+)
+
+func (s Scope) rank() int {
+	switch s {
+	case ScopeRead:
+		return 1
+	case ScopeOperator:
+		return 2
+	case ScopeAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Allows 是否拥有 required 的权限
+func (s Scope) Allows(required Scope) bool {
+	return s.rank() > 0 && s.rank() >= required.rank()
+}
+
+// 认证文件名、会话有效期与令牌前缀
+const (
+	authFileName  = "auth.json"
+	sessionTTL    = 7 * 24 * time.Hour
+	tokenPrefix   = "rst_"
+	adminIdentity = "admin"
+)
+
+// TokenInfo 令牌的信息，不包含令牌本身
+type TokenInfo struct {
+	Name      string    `json:"name"`
+	Scope     Scope     `json:"scope"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// storedToken 令牌只保存哈希，创建时显示一次
+type storedToken struct {
+	TokenInfo
+	Hash string `json:"hash"`
+}
+
+// authFile 认证文件的内容，管理员令牌以明文保存，文件权限为 0600
+type authFile struct {
+	AdminToken string        `json:"adminToken"`
+	Tokens     []storedToken `json:"tokens,omitempty"`
+}
+
+// Identity 请求的身份，Name 为令牌名（管理员令牌为 admin），Session 表示通过登录会话认证
+type Identity struct {
+	Name    string `json:"name"`
+	Scope   Scope  `json:"scope"`
+	Session bool   `json:"session"`
+}
+
+// session 登录会话，CSRF 为修改状态的请求需要携带的 X-CSRF-Token
+type session struct {
+	Identity
+	CSRF    string
+	Expires time.Time
+}
+
+// Auth dashboard 与 API 的令牌和登录会话。令牌保存在配置目录的 auth.json 中，
+// 文件被其他进程（如 rooster token 命令）修改后自动重新读取
+type Auth struct {
+	path     string // 为空时只保存在内存中
+	lock     sync.Mutex
+	file     authFile
+	stat     os.FileInfo // 上次读取或写入后的文件信息，用于判断文件是否被修改
+	sessions map[string]*session
+}
+
+// LoadAuth 读取 dir 中的认证文件，不存在时生成管理员令牌并保存。dir 为空时令牌只保存在内存中
+func LoadAuth(dir string) (*Auth, error) {
+	a := &Auth{sessions: map[string]*session{}}
+	if dir == "" {
+		a.file.AdminToken = newToken()
+		return a, nil
+	}
+	a.path = filepath.Join(dir, authFileName)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err := a.reload(); err == nil {
+		return a, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	a.file = authFile{AdminToken: newToken()}
+	if err := a.save(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Path 认证文件的路径，只保存在内存中时为空
+func (a *Auth) Path() string {
+	return a.path
+}
+
+// AdminToken 管理员令牌，拥有全部权限
+func (a *Auth) AdminToken() string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.refresh()
+	return a.file.AdminToken
+}
+
+// Tokens 已创建的令牌
+func (a *Auth) Tokens() []TokenInfo {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.refresh()
+	list := make([]TokenInfo, 0, len(a.file.Tokens))
+	for _, t := range a.file.Tokens {
+		list = append(list, t.TokenInfo)
+	}
+	return list
+}
+
+// CreateToken 创建令牌并返回，令牌只保存哈希，之后无法再次获取
+func (a *Auth) CreateToken(name string, scope Scope) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == adminIdentity {
+		return "", errors.New("令牌名称无效")
+	}
+	if scope != ScopeRead && scope != ScopeOperator {
+		return "", errors.New("令牌权限只能是 read 或 operator")
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.refresh()
+	for _, t := range a.file.Tokens {
+		if t.Name == name {
+			return "", errors.New("令牌名称已存在")
+		}
+	}
+	token := newToken()
+	a.file.Tokens = append(a.file.Tokens, storedToken{
+		TokenInfo: TokenInfo{Name: name, Scope: scope, CreatedAt: time.Now()},
+		Hash:      hashToken(token),
+	})
+	if err := a.save(); err != nil {
+		a.file.Tokens = a.file.Tokens[:len(a.file.Tokens)-1]
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeToken 删除令牌，并结束使用该令牌登录的会话
+func (a *Auth) RevokeToken(name string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.refresh()
+	i := slices.IndexFunc(a.file.Tokens, func(t storedToken) bool { return t.Name == name })
+	if i < 0 {
+		return errors.New("令牌不存在")
+	}
+	old := a.file.Tokens
+	a.file.Tokens = slices.Delete(slices.Clone(old), i, i+1)
+	if err := a.save(); err != nil {
+		a.file.Tokens = old
+		return err
+	}
+	for id, s := range a.sessions {
+		if s.Name == name {
+			delete(a.sessions, id)
+		}
+	}
+	return nil
+}
+
+// Authenticate 校验令牌，返回令牌的身份
+func (a *Auth) Authenticate(token string) (Identity, bool) {
+	if token == "" {
+		return Identity{}, false
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.refresh()
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.file.AdminToken)) == 1 {
+		return Identity{Name: adminIdentity, Scope: ScopeAdmin}, true
+	}
+	hash := hashToken(token)
+	for _, t := range a.file.Tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(t.Hash)) == 1 {
+			return Identity{Name: t.Name, Scope: t.Scope}, true
+		}
+	}
+	return Identity{}, false
+}
+
+// login 为令牌的身份创建会话，返回会话 ID 与 CSRF 令牌
+func (a *Auth) login(id Identity) (string, string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := time.Now()
+	for sid, s := range a.sessions {
+		if now.After(s.Expires) {
+			delete(a.sessions, sid)
+		}
+	}
+	sid, csrf := newToken(), newToken()
+	id.Session = true
+	a.sessions[sid] = &session{Identity: id, CSRF: csrf, Expires: now.Add(sessionTTL)}
+	return sid, csrf
+}
+
+// session 返回未过期的会话
+func (a *Auth) session(sid string) (*session, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	s, ok := a.sessions[sid]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(s.Expires) {
+		delete(a.sessions, sid)
+		return nil, false
+	}
+	return s, true
+}
+
+func (a *Auth) logout(sid string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.sessions, sid)
+}
+
+// refresh 认证文件被修改后重新读取，需持有锁
+func (a *Auth) refresh() {
+	if a.path == "" {
+		return
+	}
+	st, err := os.Stat(a.path)
+	if err != nil || !fileChanged(a.stat, st) {
+		return
+	}
+	_ = a.reload()
+}
+
+// reload 需持有锁
+func (a *Auth) reload() error {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+	st, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+	var file authFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if file.AdminToken == "" {
+		return errors.New("认证文件中缺少管理员令牌: " + a.path)
+	}
+	a.file, a.stat = file, st
+	return nil
+}
+
+// save 需持有锁，先写入临时文件再替换
+func (a *Auth) save() error {
+	if a.path == "" {
+		return nil
+	}
+	data, _ := json.MarshalIndent(a.file, "", "  ")
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if st, err := os.Stat(a.path); err == nil {
+		a.stat = st
+	}
+	return nil
+}
+
+// fileChanged 文件时间戳精度有限，同一时刻内的替换还需比较文件本身与大小
+func fileChanged(old, cur os.FileInfo) bool {
+	return old == nil || !os.SameFile(old, cur) || !cur.ModTime().Equal(old.ModTime()) || cur.Size() != old.Size()
+}
+
+func newToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return tokenPrefix + hex.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// discoveryFileName 记录正在运行的 dashboard 地址的文件，保存在配置目录中
+const discoveryFileName = "dashboard.json"
+
+// Discovery 正在运行的 dashboard 的地址，供命令行等工具查找
+type Discovery struct {
+	URL       string    `json:"url"`                // 如 https://127.0.0.1:9090，不含路径
+	Bind      string    `json:"bind"`               // 监听地址
+	Port      int       `json:"port"`               // 实际监听的端口
+	TLS       bool      `json:"tls"`                // 是否为 HTTPS
+	CertFile  string    `json:"certFile,omitempty"` // 证书文件，自签名证书可用于校验连接
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// DiscoveryPath 配置目录中 discovery 文件的路径
+func DiscoveryPath(configDir string) string {
+	return filepath.Join(configDir, discoveryFileName)
+}
+
+// ReadDiscovery 读取配置目录中的 discovery 文件，dashboard 未运行时文件不存在
+func ReadDiscovery(configDir string) (Discovery, error) {
+	var d Discovery
+	data, err := os.ReadFile(DiscoveryPath(configDir))
+	if err != nil {
+		return d, err
+	}
+	err = json.Unmarshal(data, &d)
+	return d, err
+}
+
+func writeDiscovery(configDir string, d Discovery) error {
+	data, _ := json.MarshalIndent(d, "", "  ")
+	tmp := DiscoveryPath(configDir) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, DiscoveryPath(configDir))
+}
+
+// removeDiscovery 只删除本进程写入的 discovery 文件
+func removeDiscovery(configDir string) {
+	if d, err := ReadDiscovery(configDir); err == nil && d.PID == os.Getpid() {
+		_ = os.Remove(DiscoveryPath(configDir))
+	}
+}
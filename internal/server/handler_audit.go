@@ -0,0 +1,146 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/audit"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// 审计日志保存在配置目录下的目录，以及默认保留天数
+const (
+	auditDirName          = "audit"
+	defaultAuditRetention = 90
+)
+
+// openAudit 打开 mgr 配置目录中的审计日志，配置不是保存在文件中时只保存在内存中
+func openAudit(mgr *jobmanager.Manager) *audit.Log {
+	retention := mgr.GetHttpConfig().Dashboard.AuditRetention
+	if retention == 0 {
+		retention = defaultAuditRetention
+	}
+	dir := mgr.ConfigDir()
+	if dir != "" {
+		dir = filepath.Join(dir, auditDirName)
+	}
+	l, err := audit.Open(dir, retention)
+	if err != nil {
+		slog.Error("打开审计日志失败，只保存在内存中", "err", err)
+		l, _ = audit.Open("", retention)
+	}
+	return l
+}
+
+// record 补充请求的客户端与身份后写入审计日志
+func (s *Server) record(c *gin.Context, e audit.Entry) {
+	e.Client = c.RemoteIP()
+	if e.Actor == "" {
+		e.Actor = identityOf(c).Name
+	}
+	if err := s.audit.Append(e); err != nil {
+		slog.Error("写入审计日志失败", "action", e.Action, "err", err)
+	}
+}
+
+// auditEntry 不涉及任务配置的操作的审计记录
+func auditEntry(action, detail string, err error) audit.Entry {
+	result := "success"
+	if err != nil {
+		result = err.Error()
+	}
+	return audit.Entry{Action: action, Detail: detail, Result: result}
+}
+
+// audited 执行修改状态的操作 fn 并记录审计日志：操作前后配置有变化的任务各记录一条及其变化，
+// 都没有变化时为 jobId 记录一条（jobId 为空时不关联任务）
+func (s *Server) audited(c *gin.Context, action, jobId, detail string, fn func() error) error {
+	before := s.mgr.JobSpecs()
+	err := fn()
+	after := s.mgr.JobSpecs()
+
+	entry := auditEntry(action, detail, err)
+
+	beforeByID := map[string]jobmanager.JobSpec{}
+	for _, spec := range before {
+		beforeByID[spec.UUID] = spec
+	}
+	afterByID := map[string]jobmanager.JobSpec{}
+	for _, spec := range after {
+		afterByID[spec.UUID] = spec
+	}
+	changed := 0
+	report := func(id, name string, old, cur *jobmanager.JobSpec) {
+		changes := audit.Diff(old, cur)
+		if len(changes) == 0 {
+			return
+		}
+		// 审计日志对 read 权限可见，只记录凭据发生了变化，不记录其值
+		for i := range changes {
+			changes[i].Before = jobmanager.RedactField(changes[i].Field, changes[i].Before)
+			changes[i].After = jobmanager.RedactField(changes[i].Field, changes[i].After)
+		}
+		e := entry
+		e.JobID, e.JobName, e.Changes = id, name, changes
+		s.record(c, e)
+		changed++
+	}
+	for _, old := range before {
+		if cur, ok := afterByID[old.UUID]; ok {
+			report(old.UUID, cur.JobName, &old, &cur)
+		} else {
+			report(old.UUID, old.JobName, &old, nil)
+		}
+	}
+	for _, cur := range after {
+		if _, ok := beforeByID[cur.UUID]; !ok {
+			report(cur.UUID, cur.JobName, nil, &cur)
+		}
+	}
+	if changed == 0 {
+		entry.JobID = jobId
+		if spec, ok := afterByID[jobId]; ok {
+			entry.JobName = spec.JobName
+		} else if spec, ok := beforeByID[jobId]; ok {
+			entry.JobName = spec.JobName
+		}
+		s.record(c, entry)
+	}
+	return err
+}
+
+// handleAudit 查询审计日志，可按 jobId、actor、action（可重复）、since 与 until（RFC 3339）过滤，
+// limit 默认 100，结果从新到旧
+func (s *Server) handleAudit(c *gin.Context) {
+	f := audit.Filter{
+		JobID:   c.Query("jobId"),
+		Actor:   c.Query("actor"),
+		Actions: c.QueryArray("action"),
+	}
+	for key, t := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := c.Query(key); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusOK, gin.H{"message": key + " 格式错误，应为 RFC 3339"})
+				return
+			}
+			*t = parsed
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		f.Limit, _ = strconv.Atoi(v)
+	}
+	entries, err := s.audit.Query(f)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "entries": entries})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leancodebox/rooster/internal/audit"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+func TestAuditRecordsJobChanges(t *testing.T) {
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(m)
+	h := s.Handler()
+	token, _ := s.Auth().CreateToken("ops", ScopeOperator)
+	post := func(url, body string) {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if !strings.Contains(rec.Body.String(), "success") {
+			t.Fatalf("%v failed: %v", url, rec.Body.String())
+		}
+	}
+	post("/api/save-task", `{"jobName":"nightly","type":2,"spec":"@daily","binPath":"echo a"}`)
+	jobId := m.JobList()[0].UUID
+	post("/api/save-task", `{"uuid":"`+jobId+`","jobName":"nightly","type":2,"spec":"@daily","binPath":"echo b"}`)
+
+	rec := httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/audit?jobId="+jobId, nil))
+	var resp struct {
+		Entries []audit.Entry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 2 {
+		t.Fatalf("unexpected entries %+v", resp.Entries)
+	}
+	update, create := resp.Entries[0], resp.Entries[1]
+	if create.Action != "job.create" || create.Actor != "ops" || create.JobName != "nightly" || create.Result != "success" {
+		t.Fatalf("unexpected create entry %+v", create)
+	}
+	if update.Action != "job.update" || update.Changes[0].Field != "binPath" || update.Changes[0].Before != "echo a" || update.Changes[0].After != "echo b" {
+		t.Fatalf("unexpected update entry %+v", update)
+	}
+
+	// 失败的操作同样记录
+	req := httptest.NewRequest(http.MethodPost, "/api/run-task", strings.NewReader(`{"taskId":"missing"}`))
+	req.Header.Set("Content-Type", "application/json")
+	adminHandler(s).ServeHTTP(httptest.NewRecorder(), req)
+	entries, _ := s.audit.Query(audit.Filter{Actions: []string{"job.run"}})
+	if len(entries) != 1 || entries[0].JobID != "missing" || entries[0].Actor != "admin" || entries[0].Result == "success" {
+		t.Fatalf("unexpected failed entry %+v", entries)
+	}
+}
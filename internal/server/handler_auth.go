@@ -0,0 +1,177 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/audit"
+)
+
+// 登录会话的 cookie 名、CSRF 请求头与请求身份在 gin.Context 中的键
+const (
+	sessionCookie = "rooster_session"
+	csrfHeader    = "X-CSRF-Token"
+	identityKey   = "rooster.identity"
+)
+
+// cors 只对 dashboard.allowedOrigins 中的来源返回跨域响应头，其他来源的跨域请求被浏览器拦截
+func (s *Server) cors(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin != "" && slices.Contains(s.mgr.GetHttpConfig().Dashboard.AllowedOrigins, origin) {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+csrfHeader)
+		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, DELETE, PATCH, PUT")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, Content-Type")
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+	c.Header("Vary", "Origin")
+	if c.Request.Method == http.MethodOptions {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+	c.Next()
+}
+
+// authenticate 通过 Authorization: Bearer 令牌或登录会话认证请求。使用会话的修改状态的请求
+// 需要携带登录时返回的 X-CSRF-Token，令牌认证的请求不依赖 cookie，不需要
+func (s *Server) authenticate(c *gin.Context) {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		id, ok := s.auth.Authenticate(strings.TrimSpace(token))
+		if !ok {
+			abortAuth(c, http.StatusUnauthorized, "令牌无效")
+			return
+		}
+		c.Set(identityKey, id)
+		c.Next()
+		return
+	}
+	sid, err := c.Cookie(sessionCookie)
+	if err != nil {
+		abortAuth(c, http.StatusUnauthorized, "未登录")
+		return
+	}
+	sess, ok := s.auth.session(sid)
+	if !ok {
+		abortAuth(c, http.StatusUnauthorized, "登录已过期")
+		return
+	}
+	if !isSafeMethod(c.Request.Method) && c.GetHeader(csrfHeader) != sess.CSRF {
+		abortAuth(c, http.StatusForbidden, "CSRF 校验失败")
+		return
+	}
+	c.Set(identityKey, sess.Identity)
+	c.Next()
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requireScope 要求请求的身份拥有 scope 的权限
+func requireScope(scope Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !identityOf(c).Scope.Allows(scope) {
+			abortAuth(c, http.StatusForbidden, "权限不足，需要 "+string(scope)+" 权限")
+			return
+		}
+		c.Next()
+	}
+}
+
+// identityOf 请求的身份，未认证时为零值
+func identityOf(c *gin.Context) Identity {
+	if v, ok := c.Get(identityKey); ok {
+		if id, ok := v.(Identity); ok {
+			return id
+		}
+	}
+	return Identity{}
+}
+
+type LoginReq struct {
+	Token string `json:"token"`
+}
+
+// handleLogin 以令牌登录 dashboard，设置会话 cookie 并返回 CSRF 令牌
+func (s *Server) handleLogin(c *gin.Context) {
+	var params LoginReq
+	_ = c.ShouldBind(&params)
+	id, ok := s.auth.Authenticate(strings.TrimSpace(params.Token))
+	if !ok {
+		s.record(c, audit.Entry{Action: "auth.login", Result: "令牌无效"})
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "令牌无效"})
+		return
+	}
+	s.record(c, audit.Entry{Action: "auth.login", Actor: id.Name, Result: "success"})
+	sid, csrf := s.auth.login(id)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    sid,
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	id.Session = true
+	c.JSON(http.StatusOK, gin.H{"message": "success", "identity": id, "csrfToken": csrf})
+}
+
+func (s *Server) handleLogout(c *gin.Context) {
+	if sid, err := c.Cookie(sessionCookie); err == nil {
+		s.auth.logout(sid)
+	}
+	http.SetCookie(c.Writer, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// handleSession 返回当前身份，通过会话认证时同时返回 CSRF 令牌，供页面刷新后使用
+func (s *Server) handleSession(c *gin.Context) {
+	resp := gin.H{"message": "success", "identity": identityOf(c)}
+	if sid, err := c.Cookie(sessionCookie); err == nil {
+		if sess, ok := s.auth.session(sid); ok {
+			resp["csrfToken"] = sess.CSRF
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) handleTokenList(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "success", "tokens": s.auth.Tokens()})
+}
+
+type TokenCreateReq struct {
+	Name  string `json:"name"`
+	Scope Scope  `json:"scope"`
+}
+
+// handleTokenCreate 创建令牌，令牌只在响应中出现一次
+func (s *Server) handleTokenCreate(c *gin.Context) {
+	var params TokenCreateReq
+	_ = c.ShouldBind(&params)
+	token, err := s.auth.CreateToken(params.Name, params.Scope)
+	s.record(c, auditEntry("token.create", params.Name+" "+string(params.Scope), err))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "token": token})
+}
+
+type TokenRevokeReq struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) handleTokenRevoke(c *gin.Context) {
+	var params TokenRevokeReq
+	_ = c.ShouldBind(&params)
+	msg := "success"
+	err := s.auth.RevokeToken(params.Name)
+	s.record(c, auditEntry("token.revoke", params.Name, err))
+	if err != nil {
+		msg = err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{"message": msg})
+}
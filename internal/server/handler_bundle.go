@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+type ImportReq struct {
+	Bundle jobmanager.JobBundle `json:"bundle"`
+	jobmanager.ImportOptions
+}
+
+// handleExport 导出任务包，jobId 可重复传入（UUID 或任务名），不传时导出全部
+func (s *Server) handleExport(c *gin.Context) {
+	bundle, err := s.mgr.ExportBundle(c.QueryArray("jobId"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rooster-jobs-%v.json", time.Now().Format("20060102-150405")))
+	c.JSON(http.StatusOK, bundle)
+}
+
+func (s *Server) handleImport(c *gin.Context) {
+	var params ImportReq
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "任务包格式错误: " + err.Error()})
+		return
+	}
+	var report jobmanager.ImportReport
+	err := s.audited(c, "config.import", "", params.Strategy, func() (err error) {
+		report, err = s.mgr.ImportBundle(params.Bundle, params.ImportOptions)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+		"report":  report,
+	})
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigRollbackReq struct {
+	Version string `json:"version"`
+}
+
+func (s *Server) handleConfigVersions(c *gin.Context) {
+	versions, err := s.mgr.ListConfigVersions()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": versions,
+	})
+}
+
+func (s *Server) handleConfigDiff(c *gin.Context) {
+	diff, err := s.mgr.DiffConfigVersion(c.Query("version"), c.Query("against"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+		"diff":    diff,
+	})
+}
+
+func (s *Server) handleConfigRollback(c *gin.Context) {
+	var params ConfigRollbackReq
+	_ = c.ShouldBind(&params)
+	err := s.audited(c, "config.rollback", "", params.Version, func() error { return s.mgr.RollbackConfig(params.Version) })
+	msg := "success"
+	if err != nil {
+		msg = err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": msg,
+	})
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// handleEvents 以 SSE 推送生命周期事件。
+// 重连时按 Last-Event-ID（或 since 参数）从内存中保留的最近事件补发；
+// 可以用 jobId 与 type（可重复）过滤
+func (s *Server) handleEvents(c *gin.Context) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+
+	bus := s.mgr.Events()
+	after := bus.LastID()
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("since")
+	}
+	if lastEventId != "" {
+		if id, err := strconv.ParseUint(lastEventId, 10, 64); err == nil {
+			after = id
+		}
+	}
+	jobId := c.Query("jobId")
+	types := c.QueryArray("type")
+	match := func(e jobmanager.Event) bool {
+		if jobId != "" && e.UUID != jobId {
+			return false
+		}
+		return len(types) == 0 || slices.Contains(types, string(e.Type))
+	}
+
+	replay, ch, cancel := bus.SubscribeFrom(after, 256)
+	defer cancel()
+
+	send := func(e jobmanager.Event) bool {
+		if !match(e) {
+			return true
+		}
+		err := sse.Encode(c.Writer, sse.Event{
+			Id:    strconv.FormatUint(e.ID, 10),
+			Event: string(e.Type),
+			Data:  e,
+		})
+		if err != nil {
+			slog.Error("sse encode error", "err", err)
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	for _, e := range replay {
+		if !send(e) {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdownCtx.Done():
+			return
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				// 处理不过来被取消订阅，客户端会带着 Last-Event-ID 重连补发
+				return
+			}
+			if !send(e) {
+				return
+			}
+		case <-ticker.C:
+			err := sse.Encode(c.Writer, sse.Event{
+				Event: "ping",
+				Data:  time.Now().Format(time.RFC3339),
+			})
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+func TestHandleEventsResume(t *testing.T) {
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := m.Events()
+	bus.Publish(jobmanager.Event{Type: jobmanager.EventJobStarted, UUID: "a"})
+	bus.Publish(jobmanager.Event{Type: jobmanager.EventJobStarted, UUID: "b"})
+	bus.Publish(jobmanager.Event{Type: jobmanager.EventJobExited, UUID: "a"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/events?jobId=a", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "1")
+	rec := httptest.NewRecorder()
+	adminHandler(New(m)).ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if strings.Contains(body, "id:1\n") || strings.Contains(body, `"uuid":"b"`) {
+		t.Fatalf("events before Last-Event-ID or of other jobs should not be sent:\n%v", body)
+	}
+	if !strings.Contains(body, "id:3\nevent:job.exited\n") {
+		t.Fatalf("expected replayed exit event:\n%v", body)
+	}
+}
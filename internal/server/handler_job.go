@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -20,17 +21,58 @@ type TaskActionReq struct {
 	TaskId string `json:"taskId"`
 }
 
-func handleJobList(c *gin.Context) {
-	all := jobmanager.JobList()
+type BulkActionReq struct {
+	Action   string                 `json:"action"`
+	Selector jobmanager.JobSelector `json:"selector"`
+}
+
+// redactJobs 隐藏任务配置中的凭据，任务列表等响应只返回隐藏后的配置
+func redactJobs(jobs []jobmanager.JobStatusShow) []jobmanager.JobStatusShow {
+	for i := range jobs {
+		jobs[i] = jobs[i].Redacted()
+	}
+	return jobs
+}
+
+// handleJobList 支持按 group 与 tag（可重复，需全部包含）筛选
+func (s *Server) handleJobList(c *gin.Context) {
+	all := redactJobs(s.mgr.JobListBySelector(jobmanager.JobSelector{
+		Group: c.Query("group"),
+		Tags:  c.QueryArray("tag"),
+	}))
 	c.JSON(http.StatusOK, gin.H{
 		"message": all,
 	})
 }
 
-func handleRunJobResidentTask(c *gin.Context) {
+func (s *Server) handleJobLabels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message": s.mgr.Labels(),
+	})
+}
+
+func (s *Server) handleBulkAction(c *gin.Context) {
+	var params BulkActionReq
+	_ = c.ShouldBind(&params)
+	var results []jobmanager.BulkResult
+	err := s.audited(c, "jobs.bulk", "", params.Action, func() (err error) {
+		results, err = s.mgr.BulkAction(params.Action, params.Selector)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+		"results": results,
+	})
+}
+
+func (s *Server) handleRunJobResidentTask(c *gin.Context) {
 	var params JobUpdateReq
 	_ = c.ShouldBind(&params)
-	err := jobmanager.JobRunResidentTask(params.JobId)
+	err := s.audited(c, "job.start", params.JobId, "", func() error { return s.mgr.JobRunResidentTask(params.JobId) })
 	msg := "success"
 	if err != nil {
 		msg = err.Error()
@@ -40,10 +82,10 @@ func handleRunJobResidentTask(c *gin.Context) {
 	})
 }
 
-func handleStopJobResidentTask(c *gin.Context) {
+func (s *Server) handleStopJobResidentTask(c *gin.Context) {
 	var params JobUpdateReq
 	_ = c.ShouldBind(&params)
-	err := jobmanager.JobStopResidentTask(params.JobId)
+	err := s.audited(c, "job.stop", params.JobId, "", func() error { return s.mgr.JobStopResidentTask(params.JobId) })
 	msg := "success"
 	if err != nil {
 		msg = err.Error()
@@ -53,10 +95,14 @@ func handleStopJobResidentTask(c *gin.Context) {
 	})
 }
 
-func handleOpenCloseTask(c *gin.Context) {
+func (s *Server) handleOpenCloseTask(c *gin.Context) {
 	var params RunOpenCloseTask
 	_ = c.ShouldBind(&params)
-	err := jobmanager.OpenCloseTask(params.UUID, params.Run)
+	action := "job.disable"
+	if params.Run {
+		action = "job.enable"
+	}
+	err := s.audited(c, action, params.UUID, "", func() error { return s.mgr.OpenCloseTask(params.UUID, params.Run) })
 	msg := "success"
 	if err != nil {
 		msg = err.Error()
@@ -66,10 +112,23 @@ func handleOpenCloseTask(c *gin.Context) {
 	})
 }
 
-func handleRunTask(c *gin.Context) {
+func (s *Server) handleRunTask(c *gin.Context) {
 	var params TaskActionReq
 	_ = c.ShouldBind(&params)
-	err := jobmanager.RunTask(params.TaskId)
+	err := s.audited(c, "job.run", params.TaskId, "", func() error { return s.mgr.RunTask(params.TaskId) })
+	msg := "success"
+	if err != nil {
+		msg = err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": msg,
+	})
+}
+
+func (s *Server) handleRotateLog(c *gin.Context) {
+	var params JobUpdateReq
+	_ = c.ShouldBind(&params)
+	err := s.audited(c, "job.rotate-log", params.JobId, "", func() error { return s.mgr.RotateLog(params.JobId) })
 	msg := "success"
 	if err != nil {
 		msg = err.Error()
@@ -79,10 +138,23 @@ func handleRunTask(c *gin.Context) {
 	})
 }
 
-func handleSaveTask(c *gin.Context) {
+func (s *Server) handleSaveTask(c *gin.Context) {
 	var params jobmanager.JobStatusShow
 	_ = c.ShouldBind(&params)
-	err := jobmanager.SaveTask(params)
+	action := "job.update"
+	if params.UUID == "" {
+		action = "job.create"
+	}
+	err := s.audited(c, action, params.UUID, "", func() error { return s.mgr.SaveTask(params) })
+	var validationErr *jobmanager.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusOK, gin.H{
+			"message":  err.Error(),
+			"errors":   validationErr.Result.Errors,
+			"warnings": validationErr.Result.Warnings,
+		})
+		return
+	}
 	msg := "success"
 	if err != nil {
 		msg = err.Error()
@@ -92,7 +164,35 @@ func handleSaveTask(c *gin.Context) {
 	})
 }
 
-func handleRemoveTask(c *gin.Context) {
+func (s *Server) handleValidateTask(c *gin.Context) {
+	var params jobmanager.JobStatusShow
+	_ = c.ShouldBind(&params)
+	r := s.mgr.ValidateTask(params)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "success",
+		"valid":    r.Valid(),
+		"errors":   r.Errors,
+		"warnings": r.Warnings,
+	})
+}
+
+func (s *Server) handleJobPreview(c *gin.Context) {
+	preview, err := s.mgr.PreviewJob(c.Query("jobId"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "preview": preview})
+}
+
+func (s *Server) handlePreviewTask(c *gin.Context) {
+	var params jobmanager.JobStatusShow
+	_ = c.ShouldBind(&params)
+	preview := s.mgr.PreviewTask(params)
+	c.JSON(http.StatusOK, gin.H{"message": "success", "preview": preview})
+}
+
+func (s *Server) handleRemoveTask(c *gin.Context) {
 	var req struct {
 		UUID  string `json:"uuid"`
 		JobId string `json:"jobId"`
@@ -106,7 +206,7 @@ func handleRemoveTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "uuid/jobId缺失"})
 		return
 	}
-	err := jobmanager.RemoveTask(jobmanager.JobStatusShow{UUID: id})
+	err := s.audited(c, "job.remove", id, "", func() error { return s.mgr.RemoveTask(jobmanager.JobStatusShow{UUID: id}) })
 	msg := "success"
 	if err != nil {
 		msg = err.Error()
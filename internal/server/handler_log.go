@@ -1,82 +1,216 @@
 package server
 
 import (
-	"io"
+	"bytes"
 	"log/slog"
-	"os"
-	"strconv"
+	"net/http"
+	"path/filepath"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
 )
 
-func handleJobLogStream(c *gin.Context) {
+func (s *Server) handleJobRuns(c *gin.Context) {
+	runs, err := s.mgr.ListRunLogs(c.Query("jobId"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "runs": runs})
+}
+
+// handleJobRunLog 返回单次运行的完整日志
+func (s *Server) handleJobRunLog(c *gin.Context) {
+	p, err := s.mgr.RunLogPath(c.Query("jobId"), c.Query("runId"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.File(p)
+}
+
+// renderLogChunk 返回要发送的内容与对应的字节数。json 格式只发送完整的行，
+// 末尾不完整的行等下次写入后再发送
+func renderLogChunk(buf []byte, renderJSON bool) ([]byte, int) {
+	if !renderJSON {
+		return buf, len(buf)
+	}
+	data, consumed := jobmanager.RenderLogLines(buf)
+	if consumed == 0 && len(buf) >= 1024*1024 {
+		// 超长的行按原样发送，避免一直等待
+		return buf, len(buf)
+	}
+	return data, consumed
+}
+
+// 实时日志每个事件最多发送的字节数，首次连接发送的末尾字节数
+const (
+	maxLogStreamChunk = 1024 * 1024
+	logStreamTail     = 20 * 1024
+)
+
+// logSource 实时日志流中的一个任务日志，日志文件不存在时在之后的读取中重试打开
+type logSource struct {
+	jobId       string
+	jobName     string
+	path        string
+	renderJSON  bool                  // json 格式的日志转换为 text 格式后发送
+	from        *jobmanager.LogCursor // 打开时从该位置继续，为空时从末尾 logStreamTail 字节开始
+	follower    *jobmanager.LogFollower
+	truncations int
+}
+
+func newLogSource(j jobmanager.JobStatusShow, path string, from *jobmanager.LogCursor) *logSource {
+	return &logSource{
+		jobId:      j.UUID,
+		jobName:    j.JobName,
+		path:       path,
+		renderJSON: j.Options.LogFormat == jobmanager.LogFormatJSON,
+		from:       from,
+	}
+}
+
+// open 打开日志文件，文件不存在时返回 false
+func (src *logSource) open() bool {
+	if src.follower != nil {
+		return true
+	}
+	follower, err := jobmanager.FollowLog(src.path, src.from, logStreamTail)
+	if err != nil {
+		return false
+	}
+	src.follower = follower
+	src.truncations = follower.Truncations()
+	if src.renderJSON && src.from == nil && follower.Cursor().Offset > 0 {
+		// 从文件中间开始时跳过不完整的第一行
+		if data, _, err := follower.Read(maxLogStreamChunk); err == nil {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				follower.Advance(i + 1)
+			}
+		}
+	}
+	return true
+}
+
+// next 返回下一段要发送的内容，没有新内容时返回 nil。truncated 表示文件在这段内容之前被截断，
+// 之后的内容从文件开头发送
+func (src *logSource) next() (out []byte, truncated bool) {
+	if !src.open() {
+		return nil, false
+	}
+	data, final, err := src.follower.Read(maxLogStreamChunk)
+	if err != nil {
+		slog.Error("read log error", "path", src.path, "err", err)
+		return nil, false
+	}
+	if n := src.follower.Truncations(); n != src.truncations {
+		src.truncations, truncated = n, true
+	}
+	if len(data) == 0 {
+		return nil, truncated
+	}
+	out, consumed := renderLogChunk(data, src.renderJSON)
+	if final && consumed < len(data) {
+		// 轮转前的最后内容不会再有后续，不完整的行原样发送
+		out, consumed = append(out, data[consumed:]...), len(data)
+	}
+	if consumed == 0 {
+		return nil, truncated
+	}
+	src.follower.Advance(consumed)
+	return out, truncated
+}
+
+// cursor 当前位置，尚未打开时为重连时给出的位置
+func (src *logSource) cursor() (jobmanager.LogCursor, bool) {
+	if src.follower != nil {
+		return src.follower.Cursor(), true
+	}
+	if src.from != nil {
+		return *src.from, true
+	}
+	return jobmanager.LogCursor{}, false
+}
+
+func (src *logSource) close() {
+	if src.follower != nil {
+		src.follower.Close()
+	}
+}
+
+func (s *Server) handleJobLogStream(c *gin.Context) {
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
 	c.Writer.Header().Set("Connection", "keep-alive")
 
 	jobId := c.Query("jobId")
-	j, ok := getJobStatusById(jobId)
+	j, ok := s.getJobStatusById(jobId)
 	if !ok {
 		// If job not found, close stream
 		return
 	}
 	lp, ok := getJobLogPath(j)
 	if !ok {
+		if !j.Options.OutputType.WritesFile() {
+			// 告知客户端没有日志文件，并推迟自动重连
+			_ = sse.Encode(c.Writer, sse.Event{
+				Event: "nolog",
+				Retry: uint(time.Hour / time.Millisecond),
+				Data:  "该任务的输出方式不写入日志文件",
+			})
+			c.Writer.Flush()
+		}
 		return
 	}
 
-	f, err := os.Open(lp)
-	if err != nil {
-		// If file doesn't exist, return. Client will retry.
-		return
+	// 事件 ID 为 <文件标识>:<偏移>，日志轮转后断线重连仍能从原位置继续
+	var from *jobmanager.LogCursor
+	if cursor, ok := jobmanager.ParseLogCursor(c.GetHeader("Last-Event-ID")); ok {
+		from = &cursor
 	}
-	defer f.Close()
-
-	// Determine start offset
-	st, err := f.Stat()
-	if err != nil {
+	src := newLogSource(j, lp, from)
+	if !src.open() {
+		// If file doesn't exist, return. Client will retry.
 		return
 	}
-	fileSize := st.Size()
-	offset := int64(0)
+	defer src.close()
 
-	lastEventId := c.GetHeader("Last-Event-ID")
-	if lastEventId != "" {
-		// Try to resume from last offset
-		if lastOffset, err := strconv.ParseInt(lastEventId, 10, 64); err == nil {
-			if lastOffset >= 0 && lastOffset <= fileSize {
-				offset = lastOffset
+	// send 发送到目前为止的新内容，写入客户端失败时返回 false
+	send := func() bool {
+		for {
+			out, truncated := src.next()
+			if truncated {
+				// 告知客户端日志文件被截断，之后的内容从文件开头发送
+				if err := sse.Encode(c.Writer, sse.Event{Event: "truncated", Data: lp}); err != nil {
+					return false
+				}
 			}
-		}
-	} else {
-		// Initial connection: send tail (last 20KB)
-		initBytes := 20 * 1024
-		if fileSize > int64(initBytes) {
-			offset = fileSize - int64(initBytes)
-		}
-	}
-
-	// Send content from offset to current end
-	if offset < fileSize {
-		_, _ = f.Seek(offset, io.SeekStart)
-		buf := make([]byte, fileSize-offset)
-		n, _ := io.ReadFull(f, buf)
-		if n > 0 {
-			newOffset := offset + int64(n)
-			_ = sse.Encode(c.Writer, sse.Event{
-				Id:    strconv.FormatInt(newOffset, 10),
+			if out == nil {
+				c.Writer.Flush()
+				return true
+			}
+			cursor, _ := src.cursor()
+			err := sse.Encode(c.Writer, sse.Event{
+				Id:    cursor.String(),
 				Event: "message",
-				Data:  string(buf[:n]),
+				Data:  string(out),
 			})
+			if err != nil {
+				slog.Error("sse encode error", "err", err)
+				return false
+			}
 			c.Writer.Flush() // Flush is required to send data immediately
-			offset = newOffset
 		}
 	}
+	if !send() {
+		return
+	}
 
-	// Watch for changes
+	// 监听所在目录而不是文件本身，轮转后新建的同名文件也能收到事件
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		slog.Error("fsnotify error", "err", err)
@@ -84,7 +218,7 @@ func handleJobLogStream(c *gin.Context) {
 	}
 	defer watcher.Close()
 
-	if err = watcher.Add(lp); err != nil {
+	if err = watcher.Add(filepath.Dir(lp)); err != nil {
 		slog.Error("fsnotify add error", "err", err)
 		return
 	}
@@ -93,7 +227,7 @@ func handleJobLogStream(c *gin.Context) {
 	defer ticker.Stop()
 	for {
 		select {
-		case <-shutdownCtx.Done():
+		case <-s.shutdownCtx.Done():
 			return
 		case <-c.Request.Context().Done():
 			return
@@ -101,53 +235,20 @@ func handleJobLogStream(c *gin.Context) {
 			if !ok {
 				return
 			}
-			if event.Has(fsnotify.Write) {
-				st, err := os.Stat(lp)
-				if err != nil {
-					continue
-				}
-				newSize := st.Size()
-
-				if newSize < offset {
-					// File truncated
-					offset = 0
-					_, _ = f.Seek(0, io.SeekStart)
-				}
-
-				if newSize > offset {
-					readSize := newSize - offset
-					// Limit read size to avoid huge allocations if file grew a lot instantly
-					if readSize > 1024*1024 {
-						readSize = 1024 * 1024
-					}
-
-					buf := make([]byte, readSize)
-					_, _ = f.Seek(offset, io.SeekStart)
-					n, err := io.ReadFull(f, buf)
-					if err == nil && n > 0 {
-						newOffset := offset + int64(n)
-						err := sse.Encode(c.Writer, sse.Event{
-							Id:    strconv.FormatInt(newOffset, 10),
-							Event: "message",
-							Data:  string(buf[:n]),
-						})
-						if err != nil {
-							slog.Error("sse encode error", "err", err)
-							return
-						}
-						c.Writer.Flush() // Flush is required
-						offset = newOffset
-					}
-				}
+			if filepath.Clean(event.Name) != filepath.Clean(lp) {
+				continue
 			}
-			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
-				slog.Info("log file removed or renamed")
+			if !send() {
 				return
 			}
 		case <-ticker.C:
 			if c.Request.Context().Err() != nil {
 				return
 			}
+			// 未收到事件时（例如网络文件系统）也能读到新内容
+			if !send() {
+				return
+			}
 			// Send a ping event for client watchdog
 			err := sse.Encode(c.Writer, sse.Event{
 				Event: "ping",
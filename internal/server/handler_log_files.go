@@ -0,0 +1,75 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleJobLogFiles(c *gin.Context) {
+	files, err := s.mgr.LogFiles(c.Query("jobId"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "files": files})
+}
+
+// handleJobLogRead 分页读取日志文件，offset 为负数时从末尾倒数
+func (s *Server) handleJobLogRead(c *gin.Context) {
+	offset, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+	length, _ := strconv.Atoi(c.Query("length"))
+	chunk, err := s.mgr.ReadLogFile(c.Query("jobId"), c.Query("file"), offset, length)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "chunk": chunk})
+}
+
+// handleJobLogDownload 下载日志文件，支持 Range 请求。
+// decompress=true 时解压 .gz 文件后下载，此时不支持 Range
+func (s *Server) handleJobLogDownload(c *gin.Context) {
+	f, err := s.mgr.LogFile(c.Query("jobId"), c.Query("file"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	decompress, _ := strconv.ParseBool(c.Query("decompress"))
+	if f.Compressed && decompress {
+		r, err := f.Open()
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+			return
+		}
+		defer r.Close()
+		name := strings.TrimSuffix(f.Name, ".gz")
+		c.DataFromReader(http.StatusOK, -1, "text/plain; charset=utf-8", r, map[string]string{
+			"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": name}),
+		})
+		return
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+	st, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	contentType := "text/plain; charset=utf-8"
+	if f.Compressed {
+		contentType = "application/gzip"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	http.ServeContent(c.Writer, c.Request, f.Name, st.ModTime(), file)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// parseSearchTime 支持 RFC3339 与 2006-01-02 两种格式，后者按本地时间解析
+func parseSearchTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+// handleJobLogSearch 在任务的全部日志文件中搜索，以 SSE 推送结果：
+// 每个结果一个 match 事件，结束时发送带统计信息的 done 事件，出错时发送 error 事件
+func (s *Server) handleJobLogSearch(c *gin.Context) {
+	q := jobmanager.LogSearchQuery{Query: c.Query("q")}
+	q.Regex, _ = strconv.ParseBool(c.Query("regex"))
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	var err error
+	if q.Since, err = parseSearchTime(c.Query("since")); err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "since 格式错误: " + err.Error()})
+		return
+	}
+	if q.Until, err = parseSearchTime(c.Query("until")); err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "until 格式错误: " + err.Error()})
+		return
+	}
+	if until := c.Query("until"); len(until) == len(time.DateOnly) {
+		// 只有日期时包含当天
+		q.Until = q.Until.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.shutdownCtx, cancel)
+	defer stop()
+
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Writer.Header().Set("Content-Type", "text/event-stream")
+		c.Writer.Header().Set("Cache-Control", "no-cache")
+		c.Writer.Header().Set("Connection", "keep-alive")
+	}
+	send := func(event string, data any) error {
+		start()
+		if err := sse.Encode(c.Writer, sse.Event{Event: event, Data: data}); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	result, err := s.mgr.SearchLog(ctx, c.Query("jobId"), q, func(m jobmanager.LogMatch) error {
+		return send("match", m)
+	})
+	if err != nil {
+		if !started {
+			// 搜索条件错误时还没有开始推送
+			c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+			return
+		}
+		if !errors.Is(err, context.Canceled) {
+			_ = send("error", gin.H{"message": err.Error()})
+		}
+		return
+	}
+	_ = send("done", result)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+func newLogTestServer(t *testing.T) (*Server, string, string) {
+	t.Helper()
+	logDir := t.TempDir()
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: logDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveTask(jobmanager.JobStatusShow{JobName: "api", Type: int(jobmanager.JobTypeScheduled), Spec: "@daily", BinPath: "echo"}); err != nil {
+		t.Fatal(err)
+	}
+	return New(m), logDir, m.JobList()[0].UUID
+}
+
+func TestHandleJobLogSearch(t *testing.T) {
+	s, logDir, jobId := newLogTestServer(t)
+	_ = os.WriteFile(filepath.Join(logDir, "api_log.txt"), []byte("boot\npanic: nil map\n"), 0o644)
+
+	rec := httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/job-log-search?jobId="+jobId+"&q=panic", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "event:match\n") || !strings.Contains(body, `"offset":5`) || !strings.Contains(body, "event:done\n") {
+		t.Fatalf("unexpected search stream:\n%v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/job-log-search?jobId="+jobId+"&q=(&regex=true", nil))
+	if !strings.Contains(rec.Body.String(), "正则表达式无效") || strings.Contains(rec.Header().Get("Content-Type"), "event-stream") {
+		t.Fatalf("invalid query should return a message: %v", rec.Body.String())
+	}
+}
+
+func TestHandleJobLogDownload(t *testing.T) {
+	s, logDir, jobId := newLogTestServer(t)
+	_ = os.WriteFile(filepath.Join(logDir, "api_log.txt"), []byte("0123456789"), 0o644)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/job-log-download?jobId="+jobId+"&file=api_log.txt", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	rec := httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "234" {
+		t.Fatalf("unexpected range response: %v %q", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), `filename=api_log.txt`) {
+		t.Fatalf("missing attachment header: %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/job-log-download?jobId="+jobId+"&file=../jobs.json", nil))
+	if !strings.Contains(rec.Body.String(), "日志文件不存在") {
+		t.Fatalf("files outside the job logs must not be served: %q", rec.Body.String())
+	}
+}
+
+func TestHandleJobLogStreamFollowsRotation(t *testing.T) {
+	s, logDir, jobId := newLogTestServer(t)
+	lp := filepath.Join(logDir, "api_log.txt")
+	_ = os.WriteFile(lp, []byte("before\n"), 0o644)
+	srv := httptest.NewServer(adminHandler(s))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/job-log-stream?jobId="+jobId, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	// next 返回下一个 message 事件的 ID 与内容
+	next := func() (string, string) {
+		var id, data string
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "id:"):
+				id = strings.TrimPrefix(line, "id:")
+			case strings.HasPrefix(line, "data:"):
+				data += strings.TrimPrefix(line, "data:") + "\n"
+			case line == "" && id != "":
+				return id, data
+			}
+		}
+	}
+	id, data := next()
+	if data != "before\n\n" || !strings.HasSuffix(id, ":7") {
+		t.Fatalf("unexpected first event %q %q", id, data)
+	}
+
+	_ = os.Rename(lp, filepath.Join(logDir, "api_log-2024-01-02T03-04-05.000.txt"))
+	_ = os.WriteFile(lp, []byte("after\n"), 0o644)
+	id2, data := next()
+	if data != "after\n\n" || !strings.HasSuffix(id2, ":6") || id2 == id {
+		t.Fatalf("stream should follow the new file: %q %q", id2, data)
+	}
+}
+
+func TestHandleLogsStreamMerged(t *testing.T) {
+	logDir := t.TempDir()
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: logDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"api", "worker", "other"} {
+		if err := m.SaveTask(jobmanager.JobStatusShow{JobName: name, Type: int(jobmanager.JobTypeScheduled), Spec: "@daily", BinPath: "echo"}); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.WriteFile(filepath.Join(logDir, name+"_log.txt"), []byte(name+" line\n"), 0o644)
+	}
+	s := New(m)
+
+	stream := func(lastEventId string) string {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/api/logs/stream?jobId=api&jobId=worker", nil).WithContext(ctx)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+		rec := httptest.NewRecorder()
+		adminHandler(s).ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	body := stream("")
+	if !strings.Contains(body, `"jobName":"api","data":"api line\n"`) || !strings.Contains(body, `"jobName":"worker","data":"worker line\n"`) || strings.Contains(body, "other line") {
+		t.Fatalf("unexpected merged stream:\n%s", body)
+	}
+	var lastId string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "id:") {
+			lastId = strings.TrimPrefix(line, "id:")
+		}
+	}
+	if strings.Count(lastId, "@") != 2 {
+		t.Fatalf("event id should carry a cursor per job: %q", lastId)
+	}
+
+	// 重连后每个任务从各自的位置继续
+	f, _ := os.OpenFile(filepath.Join(logDir, "worker_log.txt"), os.O_APPEND|os.O_WRONLY, 0o644)
+	_, _ = f.WriteString("worker more\n")
+	_ = f.Close()
+	body = stream(lastId)
+	if !strings.Contains(body, `"data":"worker more\n"`) || strings.Contains(body, "api line") || strings.Contains(body, "worker line") {
+		t.Fatalf("resume should only send new lines:\n%s", body)
+	}
+
+	rec := httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stream", nil))
+	if !strings.Contains(rec.Body.String(), "请通过") {
+		t.Fatalf("missing selector should return a message: %q", rec.Body.String())
+	}
+}
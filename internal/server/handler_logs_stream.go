@@ -0,0 +1,180 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// logStreamEvent 合并日志流中的一段日志
+type logStreamEvent struct {
+	JobID   string `json:"jobId"`
+	JobName string `json:"jobName"`
+	Data    string `json:"data,omitempty"`
+}
+
+// formatLogStreamID 合并日志流的事件 ID，记录每个任务的位置：<jobId>@<文件标识>:<偏移>，以逗号分隔
+func formatLogStreamID(sources []*logSource) string {
+	parts := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if cursor, ok := src.cursor(); ok {
+			parts = append(parts, src.jobId+"@"+cursor.String())
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseLogStreamID 解析 formatLogStreamID 的结果，无法解析的部分被忽略
+func parseLogStreamID(id string) map[string]jobmanager.LogCursor {
+	cursors := map[string]jobmanager.LogCursor{}
+	for _, part := range strings.Split(id, ",") {
+		i := strings.LastIndex(part, "@")
+		if i <= 0 {
+			continue
+		}
+		if cursor, ok := jobmanager.ParseLogCursor(part[i+1:]); ok {
+			cursors[part[:i]] = cursor
+		}
+	}
+	return cursors
+}
+
+// handleLogsStream 将多个任务的日志合并为一个 SSE 流。任务通过 jobId（可重复，UUID 或任务名）、
+// group 与 tag（可重复）选择；每个 message 事件的数据包含任务 ID 与名称，
+// 事件 ID 记录每个任务的位置，重连时各任务分别从原位置继续
+func (s *Server) handleLogsStream(c *gin.Context) {
+	sel := jobmanager.JobSelector{
+		IDs:   c.QueryArray("jobId"),
+		Group: c.Query("group"),
+		Tags:  c.QueryArray("tag"),
+	}
+	if sel.Empty() {
+		c.JSON(http.StatusOK, gin.H{"message": "请通过 jobId、group 或 tag 指定任务"})
+		return
+	}
+	jobs := s.mgr.JobListBySelector(sel)
+	if len(jobs) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "没有匹配的任务"})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+
+	cursors := parseLogStreamID(c.GetHeader("Last-Event-ID"))
+	var sources []*logSource
+	paths := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, j := range jobs {
+		lp, ok := getJobLogPath(j)
+		if !ok {
+			// 该任务的输出方式不写入日志文件
+			_ = sse.Encode(c.Writer, sse.Event{Event: "nolog", Data: logStreamEvent{JobID: j.UUID, JobName: j.JobName}})
+			continue
+		}
+		var from *jobmanager.LogCursor
+		if cursor, ok := cursors[j.UUID]; ok {
+			from = &cursor
+		}
+		sources = append(sources, newLogSource(j, lp, from))
+		paths[filepath.Clean(lp)] = true
+		dirs[filepath.Dir(lp)] = true
+	}
+	defer func() {
+		for _, src := range sources {
+			src.close()
+		}
+	}()
+
+	// send 轮流发送各任务的新内容，写入客户端失败时返回 false
+	send := func() bool {
+		for progress := true; progress; {
+			progress = false
+			for _, src := range sources {
+				out, truncated := src.next()
+				if truncated {
+					if err := sse.Encode(c.Writer, sse.Event{Event: "truncated", Data: logStreamEvent{JobID: src.jobId, JobName: src.jobName}}); err != nil {
+						return false
+					}
+				}
+				if out == nil {
+					continue
+				}
+				progress = true
+				err := sse.Encode(c.Writer, sse.Event{
+					Id:    formatLogStreamID(sources),
+					Event: "message",
+					Data:  logStreamEvent{JobID: src.jobId, JobName: src.jobName, Data: string(out)},
+				})
+				if err != nil {
+					slog.Error("sse encode error", "err", err)
+					return false
+				}
+			}
+		}
+		c.Writer.Flush()
+		return true
+	}
+	if !send() {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("fsnotify error", "err", err)
+		return
+	}
+	defer watcher.Close()
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			slog.Error("fsnotify add error", "dir", dir, "err", err)
+		}
+	}
+
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdownCtx.Done():
+			return
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !paths[filepath.Clean(event.Name)] {
+				continue
+			}
+			if !send() {
+				return
+			}
+		case <-ticker.C:
+			if !send() {
+				return
+			}
+			err := sse.Encode(c.Writer, sse.Event{
+				Event: "ping",
+				Data:  time.Now().Format(time.RFC3339),
+			})
+			if err != nil {
+				slog.Error("sse heartbeat error", "err", err)
+				return
+			}
+			c.Writer.Flush()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Error("watcher error", "err", err)
+		}
+	}
+}
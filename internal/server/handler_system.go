@@ -5,10 +5,9 @@ import (
 	"os"
 
 	"github.com/gin-gonic/gin"
-	"github.com/leancodebox/rooster/internal/jobmanager"
 )
 
-func handleHomePath(c *gin.Context) {
+func (s *Server) handleHomePath(c *gin.Context) {
 	h := ""
 	if v, err := os.UserHomeDir(); err == nil {
 		h = v
@@ -16,10 +15,32 @@ func handleHomePath(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"home": h})
 }
 
-func handleRunInfo(c *gin.Context) {
+func (s *Server) handleRunInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "success",
-		"start":   jobmanager.GetStartTime().Format("2006-01-02 15:04:05"),
-		"runTime": formatDuration(jobmanager.GetRunTime()),
+		"start":   s.mgr.GetStartTime().Format("2006-01-02 15:04:05"),
+		"runTime": formatDuration(s.mgr.GetRunTime()),
+	})
+}
+
+// handleLogSinks 返回 syslog 与 Loki 等日志转发目标的发送、丢弃与暂存行数
+func (s *Server) handleLogSinks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "success", "sinks": s.mgr.SinkStats()})
+}
+
+type NotifyTestReq struct {
+	Channel string `json:"channel"`
+}
+
+func (s *Server) handleNotifyTest(c *gin.Context) {
+	var params NotifyTestReq
+	_ = c.ShouldBind(&params)
+	err := s.mgr.TestNotify(params.Channel)
+	msg := "success"
+	if err != nil {
+		msg = err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": msg,
 	})
 }
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// terminalUpgrader 只接受同源的连接
+var terminalUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 32 * 1024,
+}
+
+// terminalMessage 终端连接中的文本消息，二进制消息为终端的输出（服务端发送）或输入（客户端发送）
+type terminalMessage struct {
+	Type  string `json:"type"`            // attached / exit / error，客户端发送 input / resize
+	Mode  string `json:"mode,omitempty"`  // read / write
+	Data  string `json:"data,omitempty"`  // input 的内容
+	Cols  uint16 `json:"cols,omitempty"`  // 终端列数
+	Rows  uint16 `json:"rows,omitempty"`  // 终端行数
+	Error string `json:"error,omitempty"` // 错误信息
+}
+
+// 写入 WebSocket 的超时与心跳间隔
+const (
+	terminalWriteTimeout = 10 * time.Second
+	terminalPingInterval = 30 * time.Second
+)
+
+// handleJobTerminal 通过 WebSocket 连接到 PTY 任务的终端。mode=write 时请求写入权限，
+// 同一时间只有一个写入者，其他连接为只读。连接后先发送 attached 消息与最近的输出
+func (s *Server) handleJobTerminal(c *gin.Context) {
+	write := c.Query("mode") == "write"
+	if write && !identityOf(c).Scope.Allows(ScopeOperator) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "权限不足，写入终端需要 operator 权限"})
+		return
+	}
+	client, scrollback, err := s.mgr.AttachTerminal(c.Query("jobId"), write)
+	if write {
+		// 写入终端可以执行任意命令，记录连接
+		e := auditEntry("job.terminal", "write", err)
+		e.JobID = c.Query("jobId")
+		s.record(c, e)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	defer client.Close()
+
+	conn, err := terminalUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.Error("websocket upgrade error", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	// 只有一个 goroutine 写入连接
+	var writeLock sync.Mutex
+	send := func(messageType int, data []byte) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(terminalWriteTimeout))
+		return conn.WriteMessage(messageType, data)
+	}
+	sendJSON := func(msg terminalMessage) error {
+		data, _ := json.Marshal(msg)
+		return send(websocket.TextMessage, data)
+	}
+
+	mode := "read"
+	if write {
+		mode = "write"
+	}
+	cols, rows := client.Size()
+	if err := sendJSON(terminalMessage{Type: "attached", Mode: mode, Cols: cols, Rows: rows}); err != nil {
+		return
+	}
+	if len(scrollback) > 0 {
+		if err := send(websocket.BinaryMessage, scrollback); err != nil {
+			return
+		}
+	}
+
+	// 读取客户端的输入，只读连接的输入被忽略
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if !write {
+				continue
+			}
+			switch messageType {
+			case websocket.BinaryMessage:
+				_, err = client.Write(data)
+			case websocket.TextMessage:
+				var msg terminalMessage
+				if json.Unmarshal(data, &msg) != nil {
+					continue
+				}
+				switch msg.Type {
+				case "input":
+					_, err = client.Write([]byte(msg.Data))
+				case "resize":
+					err = client.Resize(msg.Cols, msg.Rows)
+				}
+			}
+			if err != nil {
+				_ = sendJSON(terminalMessage{Type: "error", Error: err.Error()})
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(terminalPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdownCtx.Done():
+			return
+		case <-closed:
+			return
+		case chunk, ok := <-client.Output():
+			if !ok {
+				// 任务退出，或连接跟不上输出被断开
+				_ = sendJSON(terminalMessage{Type: "exit"})
+				writeLock.Lock()
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				writeLock.Unlock()
+				return
+			}
+			if err := send(websocket.BinaryMessage, chunk); err != nil {
+				return
+			}
+		case <-ticker.C:
+			writeLock.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(terminalWriteTimeout))
+			writeLock.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+//go:build !windows
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+func TestHandleJobTerminal(t *testing.T) {
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: t.TempDir(), Headless: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.SaveTask(jobmanager.JobStatusShow{
+		JobName: "repl", Type: int(jobmanager.JobTypeScheduled), Spec: "@daily", BinPath: "read line; echo got:$line",
+		Options: jobmanager.RunOptions{PTY: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobId := m.JobList()[0].UUID
+	s := New(m)
+	srv := httptest.NewServer(adminHandler(s))
+	defer srv.Close()
+
+	rec := httptest.NewRecorder()
+	adminHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/job-terminal?jobId="+jobId, nil))
+	if !strings.Contains(rec.Body.String(), "任务未运行") {
+		t.Fatalf("attach to a stopped job should return a message: %q", rec.Body.String())
+	}
+
+	if err := m.RunTask(jobId); err != nil {
+		t.Fatal(err)
+	}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/job-terminal?mode=write&jobId=" + jobId
+	var conn *websocket.Conn
+	for deadline := time.Now().Add(30 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if conn, _, err = websocket.DefaultDialer.Dial(url, nil); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dial: %v", err)
+		}
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+
+	var attached terminalMessage
+	if err := conn.ReadJSON(&attached); err != nil || attached.Type != "attached" || attached.Mode != "write" || attached.Cols == 0 {
+		t.Fatalf("unexpected attached message: %+v %v", attached, err)
+	}
+	_ = conn.WriteJSON(terminalMessage{Type: "resize", Cols: 100, Rows: 40})
+	_ = conn.WriteMessage(websocket.BinaryMessage, []byte("hello\n"))
+
+	// 读取输出直到任务退出
+	var out strings.Builder
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v (output %q)", err, out.String())
+		}
+		if messageType == websocket.BinaryMessage {
+			out.Write(data)
+			continue
+		}
+		var msg terminalMessage
+		_ = json.Unmarshal(data, &msg)
+		if msg.Type == "error" {
+			t.Fatalf("unexpected error message: %+v", msg)
+		}
+		if msg.Type == "exit" {
+			break
+		}
+	}
+	if !strings.Contains(out.String(), "got:hello") {
+		t.Fatalf("unexpected terminal output: %q", out.String())
+	}
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// v2 API 的路径前缀。v2 使用真实的 HTTP 状态码，失败时返回带稳定错误码的 v2ErrorBody
+const apiV2Prefix = "/api/v2"
+
+// v2 API 的错误码
+const (
+	codeNotFound           = "not_found"
+	codeInvalidBody        = "invalid_body"
+	codeValidationFailed   = "validation_failed"
+	codeJobNotFound        = "job_not_found"
+	codeJobNameAmbiguous   = "job_name_ambiguous"
+	codeJobEnabled         = "job_enabled"
+	codeJobBusy            = "job_busy"
+	codeJobTypeImmutable   = "job_type_immutable"
+	codeUnsupported        = "unsupported"
+	codeUnknownAction      = "unknown_action"
+	codePreconditionFailed = "precondition_failed"
+	codeUnauthorized       = "unauthorized"
+	codeForbidden          = "forbidden"
+	codeInternal           = "internal_error"
+)
+
+// V2Error v2 API 的错误，Code 是稳定的错误码，Message 是可读的描述
+type V2Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"` // 如校验失败时的字段错误
+}
+
+type v2ErrorBody struct {
+	Error V2Error `json:"error"`
+}
+
+type v2JobList struct {
+	Jobs []jobmanager.JobStatusShow `json:"jobs"`
+}
+
+type v2RunList struct {
+	Runs []jobmanager.RunLog `json:"runs"`
+}
+
+// v2Fail 以 status 与错误码结束请求
+func v2Fail(c *gin.Context, status int, code, message string, details any) {
+	c.AbortWithStatusJSON(status, v2ErrorBody{Error: V2Error{Code: code, Message: message, Details: details}})
+}
+
+// v2FailErr 按任务操作的错误类别选择状态码与错误码
+func v2FailErr(c *gin.Context, err error) {
+	var validationErr *jobmanager.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		v2Fail(c, http.StatusUnprocessableEntity, codeValidationFailed, err.Error(), validationErr.Result)
+	case errors.Is(err, jobmanager.ErrJobNotFound):
+		v2Fail(c, http.StatusNotFound, codeJobNotFound, err.Error(), nil)
+	case errors.Is(err, jobmanager.ErrJobEnabled):
+		v2Fail(c, http.StatusConflict, codeJobEnabled, err.Error(), nil)
+	case errors.Is(err, jobmanager.ErrJobBusy):
+		v2Fail(c, http.StatusConflict, codeJobBusy, err.Error(), nil)
+	case errors.Is(err, jobmanager.ErrJobTypeImmutable):
+		v2Fail(c, http.StatusUnprocessableEntity, codeJobTypeImmutable, err.Error(), nil)
+	case errors.Is(err, jobmanager.ErrUnsupported):
+		v2Fail(c, http.StatusUnprocessableEntity, codeUnsupported, err.Error(), nil)
+	default:
+		v2Fail(c, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+	}
+}
+
+// abortAuth 结束未通过认证或授权的请求，v2 API 返回 v2ErrorBody，其他 API 保持 {"message"}
+func abortAuth(c *gin.Context, status int, message string) {
+	if strings.HasPrefix(c.Request.URL.Path, apiV2Prefix+"/") {
+		code := codeForbidden
+		if status == http.StatusUnauthorized {
+			code = codeUnauthorized
+		}
+		v2Fail(c, status, code, message, nil)
+		return
+	}
+	c.AbortWithStatusJSON(status, gin.H{"message": message})
+}
+
+// specETag 任务配置的强 ETag，配置的任何字段变化（包括开启状态与隐藏的凭据）都会改变
+func specETag(spec jobmanager.JobSpec) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// findJob 按 UUID 或任务名查找任务，返回任务与其 ETag。任务名对应多个任务时需要使用 UUID
+func (s *Server) findJob(c *gin.Context, id string) (jobmanager.JobStatusShow, string, bool) {
+	var matched []jobmanager.JobSpec
+	for _, spec := range s.mgr.JobSpecs() {
+		if spec.UUID == id {
+			matched = []jobmanager.JobSpec{spec}
+			break
+		}
+		if spec.JobName == id {
+			matched = append(matched, spec)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		v2Fail(c, http.StatusNotFound, codeJobNotFound, "任务不存在: "+id, nil)
+		return jobmanager.JobStatusShow{}, "", false
+	case 1:
+	default:
+		v2Fail(c, http.StatusConflict, codeJobNameAmbiguous, "存在多个名为 "+id+" 的任务，请使用 UUID", nil)
+		return jobmanager.JobStatusShow{}, "", false
+	}
+	for _, job := range s.mgr.JobList() {
+		if job.UUID == matched[0].UUID {
+			return job, specETag(matched[0]), true
+		}
+	}
+	v2Fail(c, http.StatusNotFound, codeJobNotFound, "任务不存在: "+id, nil)
+	return jobmanager.JobStatusShow{}, "", false
+}
+
+// checkIfMatch 请求携带 If-Match 时要求与任务当前的 ETag 一致，防止覆盖其他人的修改
+func checkIfMatch(c *gin.Context, etag string) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	v2Fail(c, http.StatusPreconditionFailed, codePreconditionFailed, "任务已被修改，请重新获取后再提交", gin.H{"etag": etag})
+	return false
+}
+
+// respondJob 返回任务的最新状态与 ETag
+func (s *Server) respondJob(c *gin.Context, status int, id string) {
+	job, etag, ok := s.findJob(c, id)
+	if !ok {
+		return
+	}
+	c.Header("ETag", etag)
+	c.JSON(status, job.Redacted())
+}
+
+func (s *Server) v2ListJobs(c *gin.Context) {
+	jobs := redactJobs(s.mgr.JobListBySelector(jobmanager.JobSelector{
+		Group: c.Query("group"),
+		Tags:  c.QueryArray("tag"),
+	}))
+	if jobs == nil {
+		jobs = []jobmanager.JobStatusShow{}
+	}
+	c.JSON(http.StatusOK, v2JobList{Jobs: jobs})
+}
+
+func (s *Server) v2GetJob(c *gin.Context) {
+	job, etag, ok := s.findJob(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, job.Redacted())
+}
+
+// bindJob 读取请求体中的任务配置，忽略其中的 uuid
+func bindJob(c *gin.Context) (jobmanager.JobStatusShow, bool) {
+	var job jobmanager.JobStatusShow
+	if err := c.ShouldBindJSON(&job); err != nil {
+		v2Fail(c, http.StatusBadRequest, codeInvalidBody, "请求体格式错误: "+err.Error(), nil)
+		return job, false
+	}
+	job.UUID = ""
+	return job, true
+}
+
+func (s *Server) v2CreateJob(c *gin.Context) {
+	job, ok := bindJob(c)
+	if !ok {
+		return
+	}
+	var id string
+	if err := s.audited(c, "job.create", "", "", func() (err error) {
+		id, err = s.mgr.CreateTask(job)
+		return err
+	}); err != nil {
+		v2FailErr(c, err)
+		return
+	}
+	c.Header("Location", apiV2Prefix+"/jobs/"+id)
+	s.respondJob(c, http.StatusCreated, id)
+}
+
+func (s *Server) v2UpdateJob(c *gin.Context) {
+	job, ok := bindJob(c)
+	if !ok {
+		return
+	}
+	s.editLock.Lock()
+	defer s.editLock.Unlock()
+	current, etag, ok := s.findJob(c, c.Param("id"))
+	if !ok || !checkIfMatch(c, etag) {
+		return
+	}
+	job.UUID = current.UUID
+	if err := s.audited(c, "job.update", current.UUID, "", func() error { return s.mgr.SaveTask(job) }); err != nil {
+		v2FailErr(c, err)
+		return
+	}
+	s.respondJob(c, http.StatusOK, current.UUID)
+}
+
+func (s *Server) v2DeleteJob(c *gin.Context) {
+	s.editLock.Lock()
+	defer s.editLock.Unlock()
+	current, etag, ok := s.findJob(c, c.Param("id"))
+	if !ok || !checkIfMatch(c, etag) {
+		return
+	}
+	if err := s.audited(c, "job.remove", current.UUID, "", func() error { return s.mgr.RemoveTask(current) }); err != nil {
+		v2FailErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// v2JobActions v2 路径中的操作名与对应的任务操作、审计动作
+var v2JobActions = map[string]struct{ action, audit string }{
+	"start":      {jobmanager.BulkStart, "job.start"},
+	"stop":       {jobmanager.BulkStop, "job.stop"},
+	"enable":     {jobmanager.BulkEnable, "job.enable"},
+	"disable":    {jobmanager.BulkDisable, "job.disable"},
+	"run":        {jobmanager.BulkRun, "job.run"},
+	"rotate-log": {jobmanager.BulkRotate, "job.rotate-log"},
+}
+
+// v2JobAction 处理 POST /jobs/{id}:{action}。run 在后台执行，返回 202，其他操作返回 200，
+// 都返回任务的最新状态
+func (s *Server) v2JobAction(c *gin.Context) {
+	id, name, found := cutLast(c.Param("id"), ":")
+	act, known := v2JobActions[name]
+	if !found || !known {
+		v2Fail(c, http.StatusNotFound, codeUnknownAction, "未知的操作: "+name, nil)
+		return
+	}
+	current, _, ok := s.findJob(c, id)
+	if !ok {
+		return
+	}
+	if err := s.audited(c, act.audit, current.UUID, "", func() error { return s.mgr.JobAction(act.action, current.UUID) }); err != nil {
+		v2FailErr(c, err)
+		return
+	}
+	status := http.StatusOK
+	if act.action == jobmanager.BulkRun {
+		status = http.StatusAccepted
+	}
+	s.respondJob(c, status, current.UUID)
+}
+
+// cutLast 在最后一个 sep 处切分 s，任务名中可以包含 sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func (s *Server) v2JobRuns(c *gin.Context) {
+	current, _, ok := s.findJob(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	runs, err := s.mgr.ListRunLogs(current.UUID)
+	if err != nil {
+		v2FailErr(c, err)
+		return
+	}
+	if runs == nil {
+		runs = []jobmanager.RunLog{}
+	}
+	c.JSON(http.StatusOK, v2RunList{Runs: runs})
+}
+
+// handleOpenAPI 返回 v2 API 的 OpenAPI 文档
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPIDocument())
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+func TestV2Jobs(t *testing.T) {
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(m)
+	h := adminHandler(s)
+	do := func(method, url, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	expect := func(rec *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		if rec.Code != status {
+			t.Fatalf("expected %v, got %v: %v", status, rec.Code, rec.Body.String())
+		}
+		if code == "" {
+			return
+		}
+		var body v2ErrorBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != code {
+			t.Fatalf("expected error code %v, got %v", code, rec.Body.String())
+		}
+	}
+
+	expect(do(http.MethodPost, "/api/v2/jobs", `{"jobName":"nightly","type":2,"spec":"bad","binPath":"echo a"}`, nil),
+		http.StatusUnprocessableEntity, codeValidationFailed)
+	expect(do(http.MethodPost, "/api/v2/jobs", `{"jobName":`, nil), http.StatusBadRequest, codeInvalidBody)
+
+	rec := do(http.MethodPost, "/api/v2/jobs", `{"jobName":"nightly","type":2,"spec":"@daily","binPath":"echo a"}`, nil)
+	expect(rec, http.StatusCreated, "")
+	var created jobmanager.JobStatusShow
+	_ = json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.UUID == "" || rec.Header().Get("Location") != "/api/v2/jobs/"+created.UUID {
+		t.Fatalf("unexpected create response %v %v", rec.Header(), rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+
+	// 按任务名获取，未变化时返回 304
+	rec = do(http.MethodGet, "/api/v2/jobs/nightly", "", nil)
+	expect(rec, http.StatusOK, "")
+	if rec.Header().Get("ETag") != etag {
+		t.Fatalf("etag should be stable: %v %v", etag, rec.Header().Get("ETag"))
+	}
+	expect(do(http.MethodGet, "/api/v2/jobs/nightly", "", map[string]string{"If-None-Match": etag}), http.StatusNotModified, "")
+	expect(do(http.MethodGet, "/api/v2/jobs/missing", "", nil), http.StatusNotFound, codeJobNotFound)
+
+	update := `{"jobName":"nightly","type":2,"spec":"@daily","binPath":"echo b"}`
+	rec = do(http.MethodPut, "/api/v2/jobs/"+created.UUID, update, map[string]string{"If-Match": etag})
+	expect(rec, http.StatusOK, "")
+	newETag := rec.Header().Get("ETag")
+	if newETag == etag {
+		t.Fatalf("etag should change after update")
+	}
+	// 基于旧版本的修改被拒绝
+	expect(do(http.MethodPut, "/api/v2/jobs/"+created.UUID, update, map[string]string{"If-Match": etag}),
+		http.StatusPreconditionFailed, codePreconditionFailed)
+	expect(do(http.MethodPut, "/api/v2/jobs/"+created.UUID, `{"jobName":"nightly","type":1,"binPath":"echo b"}`, nil),
+		http.StatusUnprocessableEntity, codeJobTypeImmutable)
+
+	expect(do(http.MethodPost, "/api/v2/jobs/"+created.UUID+":enable", "", nil), http.StatusOK, "")
+	expect(do(http.MethodPost, "/api/v2/jobs/"+created.UUID+":start", "", nil), http.StatusUnprocessableEntity, codeUnsupported)
+	expect(do(http.MethodPost, "/api/v2/jobs/"+created.UUID+":explode", "", nil), http.StatusNotFound, codeUnknownAction)
+	expect(do(http.MethodDelete, "/api/v2/jobs/"+created.UUID, "", nil), http.StatusConflict, codeJobEnabled)
+	expect(do(http.MethodPost, "/api/v2/jobs/nightly:disable", "", nil), http.StatusOK, "")
+	expect(do(http.MethodDelete, "/api/v2/jobs/"+created.UUID, "", nil), http.StatusNoContent, "")
+	expect(do(http.MethodGet, "/api/v2/jobs/"+created.UUID, "", nil), http.StatusNotFound, codeJobNotFound)
+
+	// 认证失败同样返回结构化的错误
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/jobs", nil)
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	expect(rec, http.StatusUnauthorized, codeUnauthorized)
+	token, _ := s.Auth().CreateToken("viewer", ScopeRead)
+	expect(do(http.MethodPost, "/api/v2/jobs", `{}`, map[string]string{"Authorization": "Bearer " + token}),
+		http.StatusForbidden, codeForbidden)
+}
+
+func TestV2OpenAPI(t *testing.T) {
+	m, err := jobmanager.New(jobmanager.Options{Store: jobmanager.NewMemoryStore(jobmanager.JobConfig{}), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	New(m).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("openapi.json should be public: %v", rec.Code)
+	}
+	var doc struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for path, method := range map[string]string{"/jobs": "post", "/jobs/{id}": "put", "/jobs/{id}:start": "post", "/jobs/{id}/runs": "get"} {
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Fatalf("missing %v %v in %v", method, path, doc.Paths)
+		}
+	}
+	for _, name := range []string{"JobStatusShow", "RunOptions", "V2ErrorBody"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Fatalf("missing schema %v", name)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// v2Route v2 API 的一个操作。路由注册与 OpenAPI 文档都由同一张表生成，两者不会不一致
+type v2Route struct {
+	method  string
+	path    string // OpenAPI 形式的路径，如 /jobs/{id}:start
+	summary string
+	scope   Scope
+	query   []string        // 查询参数，tag 可重复
+	ifMatch bool            // 支持 If-Match 并发检查
+	body    any             // 请求体类型，nil 表示没有请求体
+	status  int             // 成功时的状态码
+	resp    any             // 成功时的响应体类型，nil 表示没有响应体
+	errors  []int           // 除认证失败外可能返回的状态码
+	handler gin.HandlerFunc // 任务操作共用 POST /jobs/:id
+}
+
+// v2Routes v2 API 的全部操作
+func (s *Server) v2Routes() []v2Route {
+	job := jobmanager.JobStatusShow{}
+	routes := []v2Route{
+		{method: http.MethodGet, path: "/jobs", summary: "列出任务，可按 group 与 tag（可重复，需全部包含）筛选", scope: ScopeRead,
+			query: []string{"group", "tag"}, status: http.StatusOK, resp: v2JobList{}, handler: s.v2ListJobs},
+		{method: http.MethodPost, path: "/jobs", summary: "创建任务，忽略请求体中的 uuid", scope: ScopeOperator,
+			body: job, status: http.StatusCreated, resp: job, errors: []int{400, 422}, handler: s.v2CreateJob},
+		{method: http.MethodGet, path: "/jobs/{id}", summary: "获取任务，id 为 UUID 或任务名", scope: ScopeRead,
+			status: http.StatusOK, resp: job, errors: []int{404, 409}, handler: s.v2GetJob},
+		{method: http.MethodPut, path: "/jobs/{id}", summary: "修改任务，开启中的任务需要先关闭", scope: ScopeOperator, ifMatch: true,
+			body: job, status: http.StatusOK, resp: job, errors: []int{400, 404, 409, 412, 422}, handler: s.v2UpdateJob},
+		{method: http.MethodDelete, path: "/jobs/{id}", summary: "删除任务，开启中的任务需要先关闭", scope: ScopeOperator, ifMatch: true,
+			status: http.StatusNoContent, errors: []int{404, 409, 412}, handler: s.v2DeleteJob},
+		{method: http.MethodGet, path: "/jobs/{id}/runs", summary: "列出任务的单次运行日志，最新的在前", scope: ScopeRead,
+			status: http.StatusOK, resp: v2RunList{}, errors: []int{404, 409}, handler: s.v2JobRuns},
+	}
+	actions := []struct{ name, summary string }{
+		{"start", "启动常驻任务"},
+		{"stop", "停止常驻任务"},
+		{"enable", "开启任务：常驻任务启动，定时任务注册"},
+		{"disable", "关闭任务：常驻任务停止，定时任务注销"},
+		{"run", "在后台立即运行一次定时任务"},
+		{"rotate-log", "立即轮转任务的日志文件"},
+	}
+	for _, a := range actions {
+		status := http.StatusOK
+		if a.name == "run" {
+			status = http.StatusAccepted
+		}
+		routes = append(routes, v2Route{method: http.MethodPost, path: "/jobs/{id}:" + a.name, summary: a.summary, scope: ScopeOperator,
+			status: status, resp: job, errors: []int{404, 409, 422}, handler: s.v2JobAction})
+	}
+	return routes
+}
+
+// ginPath 把 OpenAPI 路径转换为 gin 路由，/jobs/{id}:start 等操作都注册为 /jobs/:id
+func (r v2Route) ginPath() string {
+	p := r.path
+	if i := strings.Index(p, "}:"); i >= 0 {
+		p = p[:i+1]
+	}
+	return strings.NewReplacer("{", ":", "}", "").Replace(p)
+}
+
+// registerV2 在 group 上注册 v2 API，group 需已完成认证
+func (s *Server) registerV2(group *gin.RouterGroup) {
+	registered := map[string]bool{}
+	for _, r := range s.v2Routes() {
+		key := r.method + " " + r.ginPath()
+		if registered[key] {
+			continue
+		}
+		registered[key] = true
+		handlers := []gin.HandlerFunc{r.handler}
+		if r.scope != ScopeRead {
+			handlers = append([]gin.HandlerFunc{requireScope(r.scope)}, handlers...)
+		}
+		group.Handle(r.method, r.ginPath(), handlers...)
+	}
+}
+
+// openAPIDocument 由 v2Routes 与响应类型生成 OpenAPI 3.0 文档
+func (s *Server) openAPIDocument() gin.H {
+	g := &schemaGen{schemas: gin.H{}}
+	errorRef := g.schema(reflect.TypeOf(v2ErrorBody{}))
+	paths := gin.H{}
+	for _, r := range s.v2Routes() {
+		op := gin.H{
+			"summary":         r.summary,
+			"operationId":     operationID(r),
+			"x-rooster-scope": string(r.scope),
+			"security":        []gin.H{{"bearerAuth": []string{}}, {"sessionCookie": []string{}}},
+			"responses":       gin.H{},
+		}
+		var params []gin.H
+		if strings.Contains(r.path, "{id}") {
+			params = append(params, gin.H{"name": "id", "in": "path", "required": true, "description": "任务的 UUID 或任务名",
+				"schema": gin.H{"type": "string"}})
+		}
+		for _, q := range r.query {
+			param := gin.H{"name": q, "in": "query", "schema": gin.H{"type": "string"}}
+			if q == "tag" {
+				param["schema"] = gin.H{"type": "array", "items": gin.H{"type": "string"}}
+				param["explode"] = true
+			}
+			params = append(params, param)
+		}
+		if r.ifMatch {
+			params = append(params, gin.H{"name": "If-Match", "in": "header", "description": "任务的 ETag，与当前不一致时返回 412",
+				"schema": gin.H{"type": "string"}})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if r.body != nil {
+			op["requestBody"] = gin.H{"required": true, "content": gin.H{
+				"application/json": gin.H{"schema": g.schema(reflect.TypeOf(r.body))},
+			}}
+		}
+
+		responses := op["responses"].(gin.H)
+		success := gin.H{"description": http.StatusText(r.status)}
+		if r.resp != nil {
+			success["content"] = gin.H{"application/json": gin.H{"schema": g.schema(reflect.TypeOf(r.resp))}}
+			if _, ok := r.resp.(jobmanager.JobStatusShow); ok {
+				success["headers"] = gin.H{"ETag": gin.H{"description": "任务配置的版本，修改与删除时通过 If-Match 提交",
+					"schema": gin.H{"type": "string"}}}
+			}
+		}
+		responses[strconv.Itoa(r.status)] = success
+		for _, status := range append([]int{401, 403}, r.errors...) {
+			responses[strconv.Itoa(status)] = gin.H{"description": http.StatusText(status),
+				"content": gin.H{"application/json": gin.H{"schema": errorRef}}}
+		}
+
+		item, _ := paths[r.path].(gin.H)
+		if item == nil {
+			item = gin.H{}
+			paths[r.path] = item
+		}
+		item[strings.ToLower(r.method)] = op
+	}
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "rooster API",
+			"version":     "2",
+			"description": "失败时返回 {\"error\": {\"code\", \"message\", \"details\"}}，code 为稳定的错误码",
+		},
+		"servers": []gin.H{{"url": apiV2Prefix}},
+		"paths":   paths,
+		"components": gin.H{
+			"schemas": g.schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth":    gin.H{"type": "http", "scheme": "bearer"},
+				"sessionCookie": gin.H{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+			},
+		},
+	}
+}
+
+// operationID 如 listJobs、getJob、jobStart
+func operationID(r v2Route) string {
+	if _, action, ok := strings.Cut(r.path, "}:"); ok {
+		parts := strings.Split(action, "-")
+		for i := range parts {
+			parts[i] = upperFirst(parts[i])
+		}
+		return "job" + strings.Join(parts, "")
+	}
+	verb := map[string]string{http.MethodPost: "create", http.MethodPut: "update", http.MethodDelete: "delete"}[r.method]
+	if verb == "" {
+		verb = "get"
+	}
+	switch {
+	case r.path == "/jobs/{id}/runs":
+		return "listJobRuns"
+	case r.path == "/jobs" && r.method == http.MethodGet:
+		return "listJobs"
+	}
+	return verb + "Job"
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// schemaGen 按 json 标签把 Go 类型转换为 JSON Schema，具名结构体放入 components
+type schemaGen struct {
+	schemas gin.H
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (g *schemaGen) schema(t reflect.Type) gin.H {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case durationType:
+		return gin.H{"type": "integer", "format": "int64", "description": "纳秒"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return gin.H{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := upperFirst(t.Name())
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = gin.H{} // 先占位，处理递归引用
+			g.schemas[name] = g.object(t)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	}
+	return gin.H{}
+}
+
+func (g *schemaGen) object(t reflect.Type) gin.H {
+	props := gin.H{}
+	g.fields(t, props)
+	return gin.H{"type": "object", "properties": props}
+}
+
+// fields 收集结构体的 json 字段，匿名嵌入的结构体字段提升到外层
+func (g *schemaGen) fields(t reflect.Type, props gin.H) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, props)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
@@ -2,24 +2,60 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/leancodebox/rooster/assets"
+	"github.com/leancodebox/rooster/internal/audit"
 	"github.com/leancodebox/rooster/internal/jobmanager"
 )
 
-var srv *http.Server
-var serverPort int
-var shutdownCtx context.Context
-var shutdownCancel context.CancelFunc
+// Server 为一个 Manager 提供 dashboard 与 HTTP API，同一进程中可以为多个 Manager 分别创建
+type Server struct {
+	mgr            *jobmanager.Manager
+	auth           *Auth
+	audit          *audit.Log
+	srv            *http.Server
+	port           int
+	url            string
+	err            error
+	editLock       sync.Mutex // v2 API 检查 If-Match 与修改任务之间不允许其他修改
+	shutdownCtx    context.Context
+	shutdownCancel context.CancelFunc
+}
+
+// New 创建绑定到 mgr 的 Server。API 需要认证，令牌保存在 mgr 配置目录的 auth.json 中，
+// 配置不是保存在文件中时只生成内存中的管理员令牌，可以通过 Auth 获取
+func New(mgr *jobmanager.Manager) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	auth, err := LoadAuth(mgr.ConfigDir())
+	if err != nil {
+		// 无法读取令牌时不允许以任何令牌访问
+		slog.Error("读取认证文件失败", "err", err)
+		auth, _ = LoadAuth("")
+	}
+	return &Server{mgr: mgr, auth: auth, audit: openAudit(mgr), shutdownCtx: ctx, shutdownCancel: cancel}
+}
+
+// Auth 返回 Server 的令牌与登录会话
+func (s *Server) Auth() *Auth {
+	return s.auth
+}
+
+// defaultServer ServeRun 为 DefaultManager 创建的 Server
+var defaultServer *Server
 
 func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -30,25 +66,18 @@ func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
 	}
 }
 
-func ServeRun() *http.Server {
-	port := jobmanager.GetHttpConfig().Dashboard.Port
-	if port <= 0 {
-		return nil
-	}
-
-	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
-
+// Handler 返回包含 dashboard 与 API 路由的 http.Handler，可以挂载到自定义的 http.Server 上
+func (s *Server) Handler() http.Handler {
 	gin.DisableConsoleColor()
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
-	srv = &http.Server{
-		Handler:        r,
-		ReadTimeout:    10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
-	}
-	r.Use(GinCors)
+	r.Use(s.cors)
 	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, apiV2Prefix+"/") {
+			v2Fail(c, http.StatusNotFound, codeNotFound, "接口不存在", nil)
+			return
+		}
 		c.Redirect(http.StatusTemporaryRedirect, "/actor")
 	})
 
@@ -61,73 +90,247 @@ func ServeRun() *http.Server {
 	}
 
 	api := r.Group("api")
+	api.POST("/login", s.handleLogin)
+	api.POST("/logout", s.handleLogout)
+	api.GET("/v2/openapi.json", s.handleOpenAPI)
+
+	authed := api.Group("/")
+	authed.Use(s.authenticate)
+	operator := requireScope(ScopeOperator)
+	admin := requireScope(ScopeAdmin)
 
 	// Log handlers (No timeout)
-	api.GET("/job-log-stream", handleJobLogStream)
+	authed.GET("/job-log-stream", s.handleJobLogStream)
+	authed.GET("/job-log-search", s.handleJobLogSearch)
+	authed.GET("/job-log-download", s.handleJobLogDownload)
+	authed.GET("/logs/stream", s.handleLogsStream)
+	authed.GET("/job-terminal", s.handleJobTerminal)
+	authed.GET("/events", s.handleEvents)
 
 	// Standard handlers (With 10s timeout)
-	stdApi := api.Group("/")
+	stdApi := authed.Group("/")
 	stdApi.Use(timeoutMiddleware(10 * time.Second))
 	{
+		// Auth handlers
+		stdApi.GET("/session", s.handleSession)
+		stdApi.GET("/tokens", admin, s.handleTokenList)
+		stdApi.POST("/tokens", admin, s.handleTokenCreate)
+		stdApi.POST("/tokens/revoke", admin, s.handleTokenRevoke)
+		stdApi.GET("/audit", s.handleAudit)
+
 		// System handlers
-		stdApi.GET("/home-path", handleHomePath)
-		stdApi.GET("/run-info", handleRunInfo)
+		stdApi.GET("/home-path", s.handleHomePath)
+		stdApi.GET("/run-info", s.handleRunInfo)
+		stdApi.POST("/notify-test", operator, s.handleNotifyTest)
+		stdApi.GET("/log-sinks", s.handleLogSinks)
 
 		// Job handlers
-		stdApi.GET("/job-list", handleJobList)
-		stdApi.POST("/run-job-resident-task", handleRunJobResidentTask)
-		stdApi.POST("/stop-job-resident-task", handleStopJobResidentTask)
-		stdApi.POST("/open-close-task", handleOpenCloseTask)
-		stdApi.POST("/run-task", handleRunTask)
-		stdApi.POST("/save-task", handleSaveTask)
-		stdApi.POST("/remove-task", handleRemoveTask)
+		stdApi.GET("/job-list", s.handleJobList)
+		stdApi.GET("/job-labels", s.handleJobLabels)
+		stdApi.POST("/bulk-action", operator, s.handleBulkAction)
+		stdApi.POST("/run-job-resident-task", operator, s.handleRunJobResidentTask)
+		stdApi.POST("/stop-job-resident-task", operator, s.handleStopJobResidentTask)
+		stdApi.POST("/open-close-task", operator, s.handleOpenCloseTask)
+		stdApi.POST("/run-task", operator, s.handleRunTask)
+		stdApi.POST("/save-task", operator, s.handleSaveTask)
+		stdApi.POST("/validate-task", s.handleValidateTask)
+		stdApi.GET("/job-preview", s.handleJobPreview)
+		stdApi.POST("/job-preview", s.handlePreviewTask)
+		stdApi.POST("/remove-task", operator, s.handleRemoveTask)
+		stdApi.GET("/job-runs", s.handleJobRuns)
+		stdApi.GET("/job-run-log", s.handleJobRunLog)
+		stdApi.POST("/rotate-log", operator, s.handleRotateLog)
+		stdApi.GET("/job-log-files", s.handleJobLogFiles)
+		stdApi.GET("/job-log-read", s.handleJobLogRead)
+
+		// Bundle handlers
+		// 任务包、配置历史与差异包含未隐藏的凭据（通知渠道密码、Loki 请求头等），需要 operator 权限
+		stdApi.GET("/export", operator, s.handleExport)
+		stdApi.POST("/import", operator, s.handleImport)
+
+		// Config history handlers
+		stdApi.GET("/config-versions", operator, s.handleConfigVersions)
+		stdApi.GET("/config-diff", operator, s.handleConfigDiff)
+		stdApi.POST("/config-rollback", operator, s.handleConfigRollback)
+
+		// v2 API
+		s.registerV2(stdApi.Group("/v2"))
+	}
+	return r
+}
+
+// 默认监听地址，非严格端口模式下端口被占用时最多尝试的端口数
+const (
+	defaultBind    = "127.0.0.1"
+	maxPortRetries = 1000
+)
+
+// Start 按 dashboard 配置的地址与端口开始服务，未配置端口时不监听并返回 nil。
+// 端口被占用时依次尝试后续端口，strictPort 为 true 时直接返回错误。
+// 开始监听后把实际地址写入配置目录的 dashboard.json
+func (s *Server) Start() error {
+	cfg := s.mgr.GetHttpConfig().Dashboard
+	if cfg.Port <= 0 {
+		return nil
+	}
+	bind := cfg.Bind
+	if bind == "" {
+		bind = defaultBind
+	}
+	srv := &http.Server{
+		Handler:        s.Handler(),
+		ReadTimeout:    10 * time.Second,
+		MaxHeaderBytes: 1 << 20,
+	}
+	var certFile string
+	if cfg.TLS {
+		cert, file, err := loadCertificate(cfg, s.mgr.ConfigDir(), certHosts(bind))
+		if err != nil {
+			return fmt.Errorf("读取 TLS 证书失败: %w", err)
+		}
+		certFile = file
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
 	}
 
+	tries := maxPortRetries
+	if cfg.StrictPort {
+		tries = 1
+	}
 	var ln net.Listener
-	for i := 0; i < 1000; i++ {
-		tryPort := port + i
-		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%v", tryPort))
+	var lastErr error
+	for i := 0; i < tries; i++ {
+		addr := net.JoinHostPort(bind, strconv.Itoa(cfg.Port+i))
+		l, err := net.Listen("tcp", addr)
 		if err == nil {
 			ln = l
-			serverPort = tryPort
-			srv.Addr = fmt.Sprintf("127.0.0.1:%v", tryPort)
-			slog.Info(fmt.Sprintf("rooster 开启server服务 http://localhost:%v/actor", tryPort))
+			s.port = cfg.Port + i
+			srv.Addr = addr
 			break
 		}
+		lastErr = err
 	}
 	if ln == nil {
-		slog.Error("无法绑定端口", "start", port)
-		serverPort = 0
-		return nil
+		s.port = 0
+		if cfg.StrictPort {
+			return fmt.Errorf("无法监听 %v: %w", net.JoinHostPort(bind, strconv.Itoa(cfg.Port)), lastErr)
+		}
+		return fmt.Errorf("无法绑定端口，从 %v 开始尝试了 %v 个: %w", cfg.Port, tries, lastErr)
+	}
+
+	scheme := "http"
+	if cfg.TLS {
+		scheme = "https"
+		ln = tls.NewListener(ln, srv.TLSConfig)
+	}
+	host := bind
+	if ip := net.ParseIP(bind); ip != nil && ip.IsUnspecified() {
+		host = defaultBind
+	}
+	s.url = scheme + "://" + net.JoinHostPort(host, strconv.Itoa(s.port))
+	s.srv = srv
+	slog.Info(fmt.Sprintf("rooster 开启server服务 %v/actor", s.url))
+	if authPath := s.auth.Path(); authPath != "" {
+		slog.Info("dashboard 需要登录，管理员令牌保存在 " + authPath)
+	}
+	if ip := net.ParseIP(bind); !cfg.TLS && bind != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		slog.Warn("dashboard 监听在非本机地址且未开启 TLS，令牌与会话会以明文传输", "bind", bind)
+	}
+	if dir := s.mgr.ConfigDir(); dir != "" {
+		d := Discovery{URL: s.url, Bind: bind, Port: s.port, TLS: cfg.TLS, CertFile: certFile, PID: os.Getpid(), StartedAt: time.Now()}
+		if err := writeDiscovery(dir, d); err != nil {
+			slog.Error("写入 dashboard.json 失败", "err", err)
+		}
 	}
 	go func() {
 		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("Serve 失败", "err", err.Error())
 		}
 	}()
+	return nil
+}
 
-	return srv
+// Run 同 Start，启动失败时记录错误（可通过 Err 获取）并返回 nil，未配置端口时也返回 nil
+func (s *Server) Run() *http.Server {
+	s.err = s.Start()
+	if s.err != nil {
+		slog.Error(s.err.Error())
+		return nil
+	}
+	return s.srv
 }
 
-func ServeStop() {
-	if srv == nil {
+// Err Run 启动失败的原因
+func (s *Server) Err() error {
+	return s.err
+}
+
+// Stop 关闭 HTTP 服务并结束日志流等长连接，不会停止任务
+func (s *Server) Stop() {
+	// Cancel server context to notify handlers (e.g. log stream) to exit immediately
+	s.shutdownCancel()
+	if s.srv == nil {
 		return
 	}
 	slog.Info("Shutdown Server ...")
 
-	// Cancel global context to notify handlers (e.g. log stream) to exit immediately
-	if shutdownCancel != nil {
-		shutdownCancel()
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := s.srv.Shutdown(ctx); err != nil {
 		slog.Info("Server Shutdown:", "err", err.Error())
 	}
+	if dir := s.mgr.ConfigDir(); dir != "" {
+		removeDiscovery(dir)
+	}
+	s.port, s.url = 0, ""
+}
+
+// Port 实际监听的端口，未在监听时为 0
+func (s *Server) Port() int {
+	return s.port
+}
+
+// URL 实际监听的地址，如 https://127.0.0.1:9090，不含路径，未在监听时为空
+func (s *Server) URL() string {
+	return s.url
+}
+
+// ServeRun 为 DefaultManager 启动 dashboard
+func ServeRun() *http.Server {
+	if jobmanager.DefaultManager == nil {
+		return nil
+	}
+	defaultServer = New(jobmanager.DefaultManager)
+	return defaultServer.Run()
+}
+
+// ServeStop 关闭 ServeRun 启动的 dashboard 并停止所有任务
+func ServeStop() {
+	if defaultServer == nil || defaultServer.srv == nil {
+		return
+	}
+	defaultServer.Stop()
 	jobmanager.StopAll()
 }
 
 func GetPort() int {
-	return serverPort
+	if defaultServer == nil {
+		return 0
+	}
+	return defaultServer.Port()
+}
+
+// GetURL ServeRun 启动的 dashboard 的地址，不含路径
+func GetURL() string {
+	if defaultServer == nil {
+		return ""
+	}
+	return defaultServer.URL()
+}
+
+// ServeError ServeRun 启动失败的原因
+func ServeError() error {
+	if defaultServer == nil {
+		return nil
+	}
+	return defaultServer.Err()
 }
@@ -1,9 +1,19 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
 )
 
 func TestName(t *testing.T) {
@@ -20,3 +30,80 @@ func TestName(t *testing.T) {
 	fmt.Println(res)
 
 }
+
+func newServeTestServer(t *testing.T, dashboard jobmanager.DashboardConfig) (*Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	config := jobmanager.JobConfig{Config: jobmanager.BaseConfig{Dashboard: dashboard}}
+	data, _ := json.Marshal(config)
+	if err := os.WriteFile(filepath.Join(dir, "jobConfig.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := jobmanager.New(jobmanager.Options{ConfigPath: filepath.Join(dir, "jobConfig.json"), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(m), dir
+}
+
+func TestStartStrictPort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	s, _ := newServeTestServer(t, jobmanager.DashboardConfig{Port: port, StrictPort: true})
+	if err := s.Start(); err == nil {
+		s.Stop()
+		t.Fatalf("strict port should fail when the port is taken")
+	}
+
+	s, _ = newServeTestServer(t, jobmanager.DashboardConfig{Port: port})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if s.Port() == port || s.Port() == 0 {
+		t.Fatalf("should pick another port, got %v", s.Port())
+	}
+}
+
+func TestStartTLSWritesDiscovery(t *testing.T) {
+	s, dir := newServeTestServer(t, jobmanager.DashboardConfig{Port: 20000 + os.Getpid()%20000, TLS: true})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadDiscovery(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.URL != s.URL() || !strings.HasPrefix(d.URL, "https://127.0.0.1:") || !d.TLS || d.PID != os.Getpid() {
+		t.Fatalf("unexpected discovery %+v", d)
+	}
+
+	// 用自签名证书校验连接
+	pem, err := os.ReadFile(d.CertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	req, _ := http.NewRequest(http.MethodGet, d.URL+"/api/session", nil)
+	req.Header.Set("Authorization", "Bearer "+s.Auth().AdminToken())
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %v", resp.StatusCode)
+	}
+
+	s.Stop()
+	if _, err := ReadDiscovery(dir); !os.IsNotExist(err) {
+		t.Fatalf("discovery file should be removed on stop: %v", err)
+	}
+}
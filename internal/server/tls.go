@@ -0,0 +1,144 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leancodebox/rooster/internal/jobmanager"
+)
+
+// 自签名证书保存的目录与文件名、有效期，以及提前重新生成的时间
+const (
+	selfSignedDir      = "tls"
+	selfSignedCertName = "dashboard.crt"
+	selfSignedKeyName  = "dashboard.key"
+	selfSignedValidity = 365 * 24 * time.Hour
+	selfSignedRenew    = 30 * 24 * time.Hour
+)
+
+// loadCertificate 读取 dashboard 的证书，返回证书与证书文件路径。未配置证书路径时使用
+// configDir 中自动生成的自签名证书，证书缺失、即将过期或不包含 hosts 时重新生成
+func loadCertificate(cfg jobmanager.DashboardConfig, configDir string, hosts []string) (tls.Certificate, string, error) {
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		if cfg.TLSCert == "" || cfg.TLSKey == "" {
+			return tls.Certificate{}, "", errors.New("tlsCert 与 tlsKey 需要同时配置")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		return cert, cfg.TLSCert, err
+	}
+	if configDir == "" {
+		return tls.Certificate{}, "", errors.New("配置不是保存在文件中，无法生成自签名证书，请配置 tlsCert 与 tlsKey")
+	}
+	dir := filepath.Join(configDir, selfSignedDir)
+	certFile, keyFile := filepath.Join(dir, selfSignedCertName), filepath.Join(dir, selfSignedKeyName)
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && certCovers(cert, hosts) {
+		return cert, certFile, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return tls.Certificate{}, "", err
+	}
+	if err := writeSelfSignedCert(certFile, keyFile, hosts); err != nil {
+		return tls.Certificate{}, "", err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	return cert, certFile, err
+}
+
+// certCovers 证书在一段时间内不会过期，且对 hosts 都有效
+func certCovers(cert tls.Certificate, hosts []string) bool {
+	if len(cert.Certificate) == 0 {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || time.Now().Add(selfSignedRenew).After(leaf.NotAfter) {
+		return false
+	}
+	for _, h := range hosts {
+		if leaf.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeSelfSignedCert 生成对 hosts 有效的自签名证书，私钥的权限为 0600
+func writeSelfSignedCert(certFile, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "rooster dashboard", Organization: []string{"rooster"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+// certHosts 自签名证书需要包含的主机名与地址：本机、主机名、监听地址，监听所有网卡时包含各网卡的地址
+func certHosts(bind string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+	}
+	ip := net.ParseIP(bind)
+	switch {
+	case ip != nil && ip.IsUnspecified():
+		addrs, _ := net.InterfaceAddrs()
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	case bind != "":
+		hosts = append(hosts, bind)
+	}
+	seen := map[string]bool{}
+	unique := hosts[:0]
+	for _, h := range hosts {
+		if !seen[h] {
+			seen[h] = true
+			unique = append(unique, h)
+		}
+	}
+	return unique
+}
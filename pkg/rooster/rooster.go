@@ -0,0 +1,164 @@
+// Package rooster 以库的形式嵌入 rooster 的任务管理。
+//
+// 每个 Manager 拥有独立的配置存储、日志目录与定时器，同一进程中可以创建多个：
+//
+//	m, err := rooster.New(rooster.Options{ConfigPath: "/srv/app/jobs.yaml"})
+//	if err != nil {
+//		return err
+//	}
+//	m.Start()
+//	defer m.StopAll()
+//	http.Handle("/", rooster.Handler(m))
+package rooster
+
+import (
+	"net/http"
+
+	"github.com/leancodebox/rooster/internal/audit"
+	"github.com/leancodebox/rooster/internal/jobmanager"
+	"github.com/leancodebox/rooster/internal/notify"
+	"github.com/leancodebox/rooster/internal/server"
+)
+
+type (
+	Manager     = jobmanager.Manager
+	Options     = jobmanager.Options
+	ConfigStore = jobmanager.ConfigStore
+	FileStore   = jobmanager.FileStore
+	MemoryStore = jobmanager.MemoryStore
+
+	JobConfig       = jobmanager.JobConfig
+	BaseConfig      = jobmanager.BaseConfig
+	DashboardConfig = jobmanager.DashboardConfig
+	Job             = jobmanager.Job
+	JobSpec         = jobmanager.JobSpec
+	JobType         = jobmanager.JobType
+	RunOptions      = jobmanager.RunOptions
+	OutputType      = jobmanager.OutputType
+	RunStatus       = jobmanager.RunStatus
+	JobStatusShow   = jobmanager.JobStatusShow
+	JobSelector     = jobmanager.JobSelector
+	BulkResult      = jobmanager.BulkResult
+	JobPreview      = jobmanager.JobPreview
+	RunLog          = jobmanager.RunLog
+	LogLine         = jobmanager.LogLine
+	LogFile         = jobmanager.LogFile
+	LogChunk        = jobmanager.LogChunk
+	SyslogConfig    = jobmanager.SyslogConfig
+	LokiConfig      = jobmanager.LokiConfig
+	SinkStats       = jobmanager.SinkStats
+
+	Terminal       = jobmanager.Terminal
+	TerminalClient = jobmanager.TerminalClient
+
+	LogSearchQuery  = jobmanager.LogSearchQuery
+	LogMatch        = jobmanager.LogMatch
+	LogSearchResult = jobmanager.LogSearchResult
+
+	ValidationResult = jobmanager.ValidationResult
+	ValidationIssue  = jobmanager.ValidationIssue
+	ValidationError  = jobmanager.ValidationError
+
+	JobBundle     = jobmanager.JobBundle
+	ImportOptions = jobmanager.ImportOptions
+	ImportReport  = jobmanager.ImportReport
+	ConfigVersion = jobmanager.ConfigVersion
+
+	Event     = jobmanager.Event
+	EventType = jobmanager.EventType
+	EventBus  = jobmanager.EventBus
+
+	NotifyConfig  = jobmanager.NotifyConfig
+	NotifyRule    = jobmanager.NotifyRule
+	NotifyChannel = notify.Channel
+
+	// Server 为一个 Manager 提供 dashboard 与 HTTP API
+	Server = server.Server
+
+	// Auth dashboard 与 API 的令牌和登录会话
+	Auth      = server.Auth
+	Scope     = server.Scope
+	Identity  = server.Identity
+	TokenInfo = server.TokenInfo
+	Discovery = server.Discovery
+
+	AuditEntry  = audit.Entry
+	AuditChange = audit.Change
+
+	// V2Error REST API v2 失败时返回的错误
+	V2Error = server.V2Error
+)
+
+// 任务操作失败的类别，可通过 errors.Is 判断
+var (
+	ErrJobNotFound      = jobmanager.ErrJobNotFound
+	ErrJobEnabled       = jobmanager.ErrJobEnabled
+	ErrJobTypeImmutable = jobmanager.ErrJobTypeImmutable
+	ErrJobBusy          = jobmanager.ErrJobBusy
+	ErrUnsupported      = jobmanager.ErrUnsupported
+)
+
+const (
+	JobTypeResident  = jobmanager.JobTypeResident
+	JobTypeScheduled = jobmanager.JobTypeScheduled
+
+	OutputTypeStd     = jobmanager.OutputTypeStd
+	OutputTypeFile    = jobmanager.OutputTypeFile
+	OutputTypeBoth    = jobmanager.OutputTypeBoth
+	OutputTypeDiscard = jobmanager.OutputTypeDiscard
+
+	RotationSize  = jobmanager.RotationSize
+	RotationDaily = jobmanager.RotationDaily
+
+	LogFormatRaw  = jobmanager.LogFormatRaw
+	LogFormatText = jobmanager.LogFormatText
+	LogFormatJSON = jobmanager.LogFormatJSON
+
+	ConcurrencyAllow  = jobmanager.ConcurrencyAllow
+	ConcurrencyForbid = jobmanager.ConcurrencyForbid
+
+	Stop    = jobmanager.Stop
+	Running = jobmanager.Running
+
+	ScopeRead     = server.ScopeRead
+	ScopeOperator = server.ScopeOperator
+	ScopeAdmin    = server.ScopeAdmin
+)
+
+const (
+	EventJobStarted    = jobmanager.EventJobStarted
+	EventJobExited     = jobmanager.EventJobExited
+	EventJobRestarting = jobmanager.EventJobRestarting
+	EventJobGaveUp     = jobmanager.EventJobGaveUp
+	EventJobEnabled    = jobmanager.EventJobEnabled
+	EventJobDisabled   = jobmanager.EventJobDisabled
+	EventConfigChanged = jobmanager.EventConfigChanged
+	EventCronFired     = jobmanager.EventCronFired
+	EventCronSkipped   = jobmanager.EventCronSkipped
+
+	ExitReasonExited      = jobmanager.ExitReasonExited
+	ExitReasonFailed      = jobmanager.ExitReasonFailed
+	ExitReasonStopped     = jobmanager.ExitReasonStopped
+	ExitReasonStartFailed = jobmanager.ExitReasonStartFailed
+)
+
+// New 按选项创建一个独立的 Manager 并读取配置，调用 Start 后才会运行任务
+func New(opts Options) (*Manager, error) {
+	return jobmanager.New(opts)
+}
+
+// NewMemoryStore 创建以 config 为初始内容的内存配置存储
+func NewMemoryStore(config JobConfig) *MemoryStore {
+	return jobmanager.NewMemoryStore(config)
+}
+
+// NewServer 创建绑定到 m 的 Server，Run 按配置的 dashboard 端口监听
+func NewServer(m *Manager) *Server {
+	return server.New(m)
+}
+
+// Handler 返回 m 的 dashboard 与 API 路由，可以挂载到自定义的 http.Server 上。
+// API 需要认证，令牌保存在 m 配置目录的 auth.json 中；使用内存配置存储时通过 NewServer(m).Auth() 获取令牌
+func Handler(m *Manager) http.Handler {
+	return server.New(m).Handler()
+}
@@ -0,0 +1,43 @@
+package rooster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerServesItsOwnManager(t *testing.T) {
+	newManager := func(name string) *Manager {
+		store := NewMemoryStore(JobConfig{TaskList: []*Job{{JobSpec: JobSpec{
+			UUID: name, JobName: name, Type: JobTypeScheduled, Spec: "* * * * *", BinPath: "echo " + name,
+		}}}})
+		m, err := New(Options{Store: store, LogDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("new %v: %v", name, err)
+		}
+		m.Start()
+		t.Cleanup(m.StopAll)
+		return m
+	}
+
+	for _, name := range []string{"alpha", "beta"} {
+		s := NewServer(newManager(name))
+		req := httptest.NewRequest(http.MethodGet, "/api/job-list", nil)
+		req.Header.Set("Authorization", "Bearer "+s.Auth().AdminToken())
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %v", rec.Code)
+		}
+		var resp struct {
+			Message []JobStatusShow `json:"message"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(resp.Message) != 1 || resp.Message[0].JobName != name {
+			t.Fatalf("%v: unexpected jobs %+v", name, resp.Message)
+		}
+	}
+}